@@ -28,7 +28,7 @@ func main() {
 	var errRun error
 	switch cfg.Transport {
 	case "http", "streamable-http":
-		logger.Printf("starting MCP HTTP transport on %s%s", cfg.HTTPAddr, cfg.HTTPPath)
+		logger.Printf("starting MCP HTTP transport on %s%s", cfg.HTTPAddr, cfg.HTTPBasePath+cfg.HTTPPath)
 		errRun = server.RunHTTP(ctx)
 	default:
 		logger.Printf("starting MCP stdio transport")
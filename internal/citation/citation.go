@@ -5,19 +5,30 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/akrisanov/readeck-mcp/internal/readeck"
 )
 
-func Generate(bookmark readeck.Bookmark, highlight *readeck.Highlight, quote string, style readeck.CitationStyle, accessedAt time.Time) readeck.Citation {
+type Options struct {
+	CompactJSON bool
+	// TitleCase applies headline capitalization to the cited title.
+	TitleCase bool
+}
+
+func Generate(bookmark readeck.Bookmark, highlight *readeck.Highlight, quote string, style readeck.CitationStyle, accessedAt time.Time, opts Options) readeck.Citation {
 	if style == "" {
 		style = readeck.StyleMarkdown
 	}
 	if accessedAt.IsZero() {
 		accessedAt = time.Now().UTC()
 	}
+	if opts.TitleCase {
+		bookmark.Title = TitleCase(bookmark.Title)
+	}
 
 	metadata := readeck.CitationMetadata{
 		Title:       bookmark.Title,
@@ -37,7 +48,7 @@ func Generate(bookmark readeck.Bookmark, highlight *readeck.Highlight, quote str
 	case readeck.StyleCSLJSON:
 		csl := toCSLJSON(bookmark, accessedAt)
 		citation.CSLJSON = csl
-		citation.Text = toJSONString(csl)
+		citation.Text = toJSONString(csl, opts.CompactJSON)
 	case readeck.StyleBibTeX:
 		bib := toBibTeX(bookmark, accessedAt)
 		citation.BibTeX = bib
@@ -48,6 +59,8 @@ func Generate(bookmark readeck.Bookmark, highlight *readeck.Highlight, quote str
 		citation.Text = formatMLA(bookmark, accessedAt)
 	case readeck.StyleChicago:
 		citation.Text = formatChicago(bookmark, accessedAt)
+	case readeck.StyleIEEE:
+		citation.Text = formatIEEE(bookmark, accessedAt)
 	default:
 		citation.Style = readeck.StyleMarkdown
 		citation.Text = formatMarkdown(bookmark, highlight, quote, accessedAt)
@@ -56,6 +69,25 @@ func Generate(bookmark readeck.Bookmark, highlight *readeck.Highlight, quote str
 	return citation
 }
 
+var styleLabels = map[readeck.CitationStyle]string{
+	readeck.StyleAPA:      "APA",
+	readeck.StyleMLA:      "MLA",
+	readeck.StyleChicago:  "Chicago",
+	readeck.StyleBibTeX:   "BibTeX",
+	readeck.StyleCSLJSON:  "CSL-JSON",
+	readeck.StyleMarkdown: "Markdown",
+	readeck.StyleIEEE:     "IEEE",
+}
+
+func Label(style readeck.CitationStyle) string {
+	if label, ok := styleLabels[style]; ok {
+		return label
+	}
+	return string(style)
+}
+
+var markdownEmphasisEscaper = strings.NewReplacer("*", `\*`, "_", `\_`)
+
 func formatMarkdown(bookmark readeck.Bookmark, highlight *readeck.Highlight, quote string, accessedAt time.Time) string {
 	var b strings.Builder
 	author := authorOrSite(bookmark)
@@ -69,6 +101,12 @@ func formatMarkdown(bookmark readeck.Bookmark, highlight *readeck.Highlight, quo
 	b.WriteString("](")
 	b.WriteString(bookmark.URL)
 	b.WriteString(")")
+	// Without an author the site name already leads the entry.
+	if site := strings.TrimSpace(bookmark.SiteName); site != "" && site != author {
+		b.WriteString(", *")
+		b.WriteString(markdownEmphasisEscaper.Replace(site))
+		b.WriteString("*")
+	}
 	if date != "" {
 		b.WriteString(" (")
 		b.WriteString(date)
@@ -109,7 +147,7 @@ func formatMLA(bookmark readeck.Bookmark, accessedAt time.Time) string {
 	author := authorOrSite(bookmark)
 	title := nonEmpty(bookmark.Title, bookmark.URL)
 	site := nonEmpty(bookmark.SiteName, "")
-	date := nonEmpty(bookmark.PublishedAt, "n.d.")
+	date := publishedOrND(bookmark)
 	if author == "" {
 		return fmt.Sprintf("\"%s.\" %s, %s, %s. Accessed %s.", title, site, date, bookmark.URL, accessedAt.Format("2 Jan 2006"))
 	}
@@ -119,11 +157,58 @@ func formatMLA(bookmark readeck.Bookmark, accessedAt time.Time) string {
 func formatChicago(bookmark readeck.Bookmark, accessedAt time.Time) string {
 	author := authorOrSite(bookmark)
 	title := nonEmpty(bookmark.Title, bookmark.URL)
-	date := nonEmpty(bookmark.PublishedAt, "n.d.")
+	date := publishedOrND(bookmark)
 	if author == "" {
 		return fmt.Sprintf("\"%s.\" Accessed %s. %s.", title, accessedAt.Format("January 2, 2006"), bookmark.URL)
 	}
-	return fmt.Sprintf("%s. \"%s.\" %s. Accessed %s. %s.", author, title, date, accessedAt.Format("January 2, 2006"), bookmark.URL)
+	return fmt.Sprintf("%s. \"%s.\" %s. Accessed %s. %s.", author, title, strings.TrimSuffix(date, "."), accessedAt.Format("January 2, 2006"), bookmark.URL)
+}
+
+// formatIEEE renders an IEEE online reference. Bibliography prefixes the
+// [n] reference number.
+func formatIEEE(bookmark readeck.Bookmark, accessedAt time.Time) string {
+	var b strings.Builder
+	if author := strings.TrimSpace(bookmark.Author); author != "" {
+		b.WriteString(ieeeAuthor(author))
+		b.WriteString(", ")
+	}
+	var tail []string
+	if site := strings.TrimSpace(bookmark.SiteName); site != "" {
+		tail = append(tail, site)
+	}
+	if t, ok := parseDate(bookmark.PublishedAt); ok {
+		tail = append(tail, ieeeDate(t))
+	}
+	title := nonEmpty(bookmark.Title, bookmark.URL)
+	if len(tail) == 0 {
+		fmt.Fprintf(&b, "\"%s.\"", title)
+	} else {
+		fmt.Fprintf(&b, "\"%s,\" %s.", title, strings.Join(tail, ", "))
+	}
+	fmt.Fprintf(&b, " Accessed: %s. [Online]. Available: %s", ieeeDate(accessedAt), bookmark.URL)
+	return b.String()
+}
+
+// ieeeAuthor abbreviates given names to initials: "Jane Q. Doe" becomes
+// "J. Q. Doe".
+func ieeeAuthor(author string) string {
+	name := parseAuthor(author)
+	if name["family"] == "" {
+		return name["literal"]
+	}
+	given := strings.Fields(name["given"])
+	initials := make([]string, 0, len(given)+1)
+	for _, part := range given {
+		r, _ := utf8.DecodeRuneInString(part)
+		initials = append(initials, string(r)+".")
+	}
+	return strings.Join(append(initials, name["family"]), " ")
+}
+
+var ieeeMonths = [...]string{"Jan.", "Feb.", "Mar.", "Apr.", "May", "Jun.", "Jul.", "Aug.", "Sep.", "Oct.", "Nov.", "Dec."}
+
+func ieeeDate(t time.Time) string {
+	return fmt.Sprintf("%s %d, %d", ieeeMonths[t.Month()-1], t.Day(), t.Year())
 }
 
 func toCSLJSON(bookmark readeck.Bookmark, accessedAt time.Time) map[string]any {
@@ -197,13 +282,20 @@ func parseAuthor(author string) map[string]string {
 	return map[string]string{"given": given, "family": family}
 }
 
+var dateLayouts = []string{
+	time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02",
+	time.RFC1123, time.RFC1123Z, "January 2, 2006", "Jan 2, 2006", "2 January 2006", "2 Jan 2006",
+}
+
+// parseDate accepts the absolute date formats upstream is known to store.
+// Anything else, including relative text such as "2 days ago", is rejected
+// so formatters fall back to n.d. instead of printing it.
 func parseDate(raw string) (time.Time, bool) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
 		return time.Time{}, false
 	}
-	layouts := []string{time.RFC3339, "2006-01-02", time.RFC1123, time.RFC1123Z}
-	for _, layout := range layouts {
+	for _, layout := range dateLayouts {
 		if t, err := time.Parse(layout, raw); err == nil {
 			return t, true
 		}
@@ -216,8 +308,14 @@ func dateParts(t time.Time) map[string]any {
 	return map[string]any{"date-parts": [][]int{{ut.Year(), int(ut.Month()), ut.Day()}}}
 }
 
-func toJSONString(v any) string {
-	b, err := json.MarshalIndent(v, "", "  ")
+func toJSONString(v any, compact bool) string {
+	var b []byte
+	var err error
+	if compact {
+		b, err = json.Marshal(v)
+	} else {
+		b, err = json.MarshalIndent(v, "", "  ")
+	}
 	if err != nil {
 		return "{}"
 	}
@@ -232,7 +330,7 @@ func authorOrSite(bookmark readeck.Bookmark) string {
 }
 
 func publishedOrND(bookmark readeck.Bookmark) string {
-	if strings.TrimSpace(bookmark.PublishedAt) != "" {
+	if _, ok := parseDate(bookmark.PublishedAt); ok {
 		return strings.TrimSpace(bookmark.PublishedAt)
 	}
 	return "n.d."
@@ -250,3 +348,88 @@ func escapeBib(v string) string {
 	v = strings.ReplaceAll(v, "}", "\\}")
 	return v
 }
+
+// Bibliography renders bookmarks as one reference list and reports how many
+// duplicates (by normalized URL) were collapsed; the first occurrence wins.
+// APA, MLA and Chicago entries are alphabetized by author family name
+// (falling back to site, then title); IEEE entries keep the given order and
+// are numbered [1], [2], ...; Markdown entries form an ordered list in the
+// given order; BibTeX entries are concatenated and CSL-JSON items are
+// returned as one JSON array.
+func Bibliography(bookmarks []readeck.Bookmark, style readeck.CitationStyle, accessedAt time.Time, opts Options) (string, int) {
+	if accessedAt.IsZero() {
+		accessedAt = time.Now().UTC()
+	}
+	bookmarks, duplicates := dedupeByURL(bookmarks)
+	return formatBibliography(bookmarks, style, accessedAt, opts), duplicates
+}
+
+func dedupeByURL(bookmarks []readeck.Bookmark) ([]readeck.Bookmark, int) {
+	seen := map[string]struct{}{}
+	out := make([]readeck.Bookmark, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		key := readeck.NormalizeURL(bookmark.URL)
+		if key == "" {
+			key = "id:" + bookmark.ID
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, bookmark)
+	}
+	return out, len(bookmarks) - len(out)
+}
+
+func formatBibliography(bookmarks []readeck.Bookmark, style readeck.CitationStyle, accessedAt time.Time, opts Options) string {
+	switch style {
+	case readeck.StyleCSLJSON:
+		items := make([]map[string]any, 0, len(bookmarks))
+		for _, bookmark := range bookmarks {
+			items = append(items, toCSLJSON(bookmark, accessedAt))
+		}
+		return toJSONString(items, opts.CompactJSON)
+	case readeck.StyleBibTeX:
+		entries := make([]string, 0, len(bookmarks))
+		for _, bookmark := range bookmarks {
+			entries = append(entries, toBibTeX(bookmark, accessedAt))
+		}
+		return strings.Join(entries, "\n")
+	case readeck.StyleAPA, readeck.StyleMLA, readeck.StyleChicago:
+		sorted := append([]readeck.Bookmark(nil), bookmarks...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return bibliographyKey(sorted[i]) < bibliographyKey(sorted[j])
+		})
+		var b strings.Builder
+		for _, bookmark := range sorted {
+			b.WriteString(Generate(bookmark, nil, "", style, accessedAt, opts).Text)
+			b.WriteByte('\n')
+		}
+		return b.String()
+	case readeck.StyleIEEE:
+		var b strings.Builder
+		for i, bookmark := range bookmarks {
+			fmt.Fprintf(&b, "[%d] %s\n", i+1, formatIEEE(bookmark, accessedAt))
+		}
+		return b.String()
+	default:
+		var b strings.Builder
+		for i, bookmark := range bookmarks {
+			entry, _, _ := strings.Cut(formatMarkdown(bookmark, nil, "", accessedAt), "\n")
+			fmt.Fprintf(&b, "%d. %s\n", i+1, entry)
+		}
+		return b.String()
+	}
+}
+
+func bibliographyKey(bookmark readeck.Bookmark) string {
+	title := strings.ToLower(nonEmpty(bookmark.Title, bookmark.URL))
+	if author := strings.TrimSpace(bookmark.Author); author != "" {
+		name := parseAuthor(author)
+		return strings.ToLower(nonEmpty(name["family"], name["literal"])) + "\x00" + title
+	}
+	if site := strings.TrimSpace(bookmark.SiteName); site != "" {
+		return strings.ToLower(site) + "\x00" + title
+	}
+	return title
+}
@@ -0,0 +1,115 @@
+package citation
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+var accessed = time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+var unsortedBookmarks = []readeck.Bookmark{
+	{ID: "1", Title: "Zeta", URL: "https://example.com/z", Author: "Carol Young"},
+	{ID: "2", Title: "Alpha", URL: "https://example.com/a", Author: "Alice Zimmer", SiteName: "Blog", PublishedAt: "2024-03-15"},
+	{ID: "3", Title: "Beta", URL: "https://example.com/b", SiteName: "Acme"},
+}
+
+func TestBibliographyIEEENumbersInGivenOrder(t *testing.T) {
+	got, _ := Bibliography(unsortedBookmarks, readeck.StyleIEEE, accessed, Options{})
+	want := `[1] C. Young, "Zeta." Accessed: Jan. 2, 2025. [Online]. Available: https://example.com/z
+[2] A. Zimmer, "Alpha," Blog, Mar. 15, 2024. Accessed: Jan. 2, 2025. [Online]. Available: https://example.com/a
+[3] "Beta," Acme. Accessed: Jan. 2, 2025. [Online]. Available: https://example.com/b
+`
+	if got != want {
+		t.Fatalf("IEEE bibliography:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBibliographyAlphabetizesAuthorDateStyles(t *testing.T) {
+	for _, style := range []readeck.CitationStyle{readeck.StyleAPA, readeck.StyleMLA, readeck.StyleChicago} {
+		got, _ := Bibliography(unsortedBookmarks, style, accessed, Options{})
+		lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("%s: %d entries, want 3:\n%s", style, len(lines), got)
+		}
+		for i, prefix := range []string{"Acme", "Carol Young", "Alice Zimmer"} {
+			if !strings.HasPrefix(lines[i], prefix) {
+				t.Fatalf("%s entry %d = %q, want it to start with %q", style, i+1, lines[i], prefix)
+			}
+		}
+	}
+}
+
+func TestBibliographyCollapsesDuplicateURLs(t *testing.T) {
+	bookmarks := append(unsortedBookmarks, readeck.Bookmark{ID: "4", Title: "Again", URL: "https://example.com/z/"})
+	got, duplicates := Bibliography(bookmarks, readeck.StyleIEEE, accessed, Options{})
+	if duplicates != 1 || strings.Contains(got, "Again") {
+		t.Fatalf("duplicates = %d:\n%s", duplicates, got)
+	}
+}
+
+func TestGenerateIEEEHasNoReferenceNumber(t *testing.T) {
+	got := Generate(unsortedBookmarks[0], nil, "", readeck.StyleIEEE, accessed, Options{})
+	if got.Style != readeck.StyleIEEE || strings.HasPrefix(got.Text, "[") {
+		t.Fatalf("citation = %+v", got)
+	}
+}
+
+func TestCSLJSONCompactOption(t *testing.T) {
+	pretty := Generate(unsortedBookmarks[1], nil, "", readeck.StyleCSLJSON, accessed, Options{})
+	compact := Generate(unsortedBookmarks[1], nil, "", readeck.StyleCSLJSON, accessed, Options{CompactJSON: true})
+	if !strings.Contains(pretty.Text, "\n  ") || strings.Contains(compact.Text, "\n") {
+		t.Fatalf("pretty = %q\ncompact = %q", pretty.Text, compact.Text)
+	}
+}
+
+func TestParseDateRejectsRelativeAndMalformedDates(t *testing.T) {
+	for _, raw := range []string{"2024-03-15", "2024-03-15T10:00:00Z", "2024-03-15 10:00:00", "March 15, 2024", "15 Mar 2024"} {
+		if got, ok := parseDate(raw); !ok || got.Year() != 2024 || got.Month() != time.March || got.Day() != 15 {
+			t.Errorf("parseDate(%q) = %v, %v", raw, got, ok)
+		}
+	}
+	for _, raw := range []string{"", "2 days ago", "yesterday", "2024-13-45", "last week"} {
+		if _, ok := parseDate(raw); ok {
+			t.Errorf("parseDate(%q) accepted", raw)
+		}
+	}
+}
+
+func TestCitationsDoNotLeakUnparseableDates(t *testing.T) {
+	for _, raw := range []string{"2 days ago", "sometime in spring"} {
+		bookmark := readeck.Bookmark{ID: "1", Title: "Relative", URL: "https://example.com/r", Author: "Dana Moss", SiteName: "Blog", PublishedAt: raw}
+		for _, style := range []readeck.CitationStyle{readeck.StyleAPA, readeck.StyleMLA, readeck.StyleChicago, readeck.StyleIEEE, readeck.StyleMarkdown} {
+			got := Generate(bookmark, nil, "", style, accessed, Options{}).Text
+			if strings.Contains(got, raw) || strings.Contains(got, "n.d..") {
+				t.Errorf("%s leaks %q: %s", style, raw, got)
+			}
+			if style != readeck.StyleIEEE && !strings.Contains(got, "n.d.") {
+				t.Errorf("%s has no n.d. fallback: %s", style, got)
+			}
+		}
+	}
+}
+
+func TestMarkdownItalicizesSiteOnlyWhenPresent(t *testing.T) {
+	tests := []struct {
+		bookmark readeck.Bookmark
+		want     string
+		italic   bool
+	}{
+		{readeck.Bookmark{Title: "Post", URL: "https://example.com/p", Author: "Ann Lee", SiteName: "The *Daily* Blog"}, `[Post](https://example.com/p), *The \*Daily\* Blog*`, true},
+		{readeck.Bookmark{Title: "Post", URL: "https://example.com/p", Author: "Ann Lee", SiteName: "  "}, "[Post](https://example.com/p) (", false},
+		{readeck.Bookmark{Title: "Post", URL: "https://example.com/p", SiteName: "Acme"}, "Acme. [Post](https://example.com/p) (", false},
+	}
+	for _, tt := range tests {
+		got := Generate(tt.bookmark, nil, "", readeck.StyleMarkdown, accessed, Options{}).Text
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("site %q: citation = %q, want it to contain %q", tt.bookmark.SiteName, got, tt.want)
+		}
+		if italic := strings.Contains(got, ", *"); italic != tt.italic {
+			t.Errorf("site %q: italic = %v in %q", tt.bookmark.SiteName, italic, got)
+		}
+	}
+}
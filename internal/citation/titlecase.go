@@ -0,0 +1,76 @@
+package citation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minorWords stay lowercase in headline-style titles unless they open or
+// close the title or follow a colon.
+var minorWords = map[string]struct{}{
+	"a": {}, "an": {}, "the": {},
+	"and": {}, "but": {}, "or": {}, "nor": {}, "for": {}, "so": {}, "yet": {},
+	"as": {}, "at": {}, "by": {}, "in": {}, "of": {}, "off": {}, "on": {}, "per": {},
+	"to": {}, "up": {}, "via": {}, "vs": {}, "from": {}, "into": {}, "onto": {},
+	"over": {}, "with": {},
+}
+
+// TitleCase applies headline capitalization to s. Words that already carry
+// capitals past their first letter (acronyms, product names) are left as
+// they are.
+func TitleCase(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		forceUpper := i == 0 || i == len(words)-1 || strings.HasSuffix(words[i-1], ":")
+		words[i] = titleCaseWord(word, forceUpper)
+	}
+	return strings.Join(words, " ")
+}
+
+func titleCaseWord(word string, forceUpper bool) string {
+	start := strings.IndexFunc(word, isWordRune)
+	if start < 0 {
+		return word
+	}
+	end := strings.LastIndexFunc(word, isWordRune)
+	_, size := utf8.DecodeRuneInString(word[end:])
+	end += size
+	core := word[start:end]
+	if hasInnerUpper(core) {
+		return word
+	}
+	if _, ok := minorWords[strings.ToLower(core)]; ok && !forceUpper {
+		return word[:start] + strings.ToLower(core) + word[end:]
+	}
+	parts := strings.Split(core, "-")
+	for i, part := range parts {
+		if _, ok := minorWords[strings.ToLower(part)]; ok && i > 0 && i < len(parts)-1 {
+			parts[i] = strings.ToLower(part)
+			continue
+		}
+		parts[i] = upperFirst(part)
+	}
+	return word[:start] + strings.Join(parts, "-") + word[end:]
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func hasInnerUpper(s string) bool {
+	for i, r := range s {
+		if i > 0 && unicode.IsUpper(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func upperFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,39 @@
+package citation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+func TestTitleCase(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"the art of computer programming", "The Art of Computer Programming"},
+		{"what the web is made of", "What the Web Is Made Of"},
+		{"go concurrency: a guide to channels", "Go Concurrency: A Guide to Channels"},
+		{"notes on iOS and the JSON API", "Notes on iOS and the JSON API"},
+		{"state-of-the-art caching", "State-of-the-Art Caching"},
+		{"  why “simple” wins (in practice)  ", "Why “Simple” Wins (in Practice)"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := TitleCase(tt.in); got != tt.want {
+			t.Errorf("TitleCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateTitleCaseIsOptIn(t *testing.T) {
+	bookmark := readeck.Bookmark{ID: "1", Title: "a tour of go", URL: "https://example.com/t", Author: "Ann Lee"}
+	if got := Generate(bookmark, nil, "", readeck.StyleMLA, accessed, Options{}); !strings.Contains(got.Text, `"a tour of go."`) {
+		t.Fatalf("title changed without the option: %s", got.Text)
+	}
+	got := Generate(bookmark, nil, "", readeck.StyleMLA, accessed, Options{TitleCase: true})
+	if !strings.Contains(got.Text, `"A Tour of Go."`) {
+		t.Fatalf("title not title-cased: %s", got.Text)
+	}
+	if bookmark.Title != "a tour of go" {
+		t.Fatalf("stored bookmark title changed to %q", bookmark.Title)
+	}
+}
@@ -2,42 +2,124 @@ package config
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/netip"
 	"net/url"
 	"os"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 )
 
 type Config struct {
-	APIToken       string
-	Timeout        time.Duration
-	UserAgent      string
-	VerifyTLS      bool
-	MaxPageSize    int
-	APIBaseURL     string
-	ServerName     string
-	ServerVersion  string
-	Protocol       string
-	Transport      string
-	HTTPAddr       string
-	HTTPPath       string
-	HTTPAuthToken  string
-	AllowedOrigins []string
+	APIToken           string
+	Timeout            time.Duration
+	UserAgent          string
+	VerifyTLS          bool
+	MaxPageSize        int
+	DefaultSearchLimit int
+	DefaultListLimit   int
+	APIBaseURL         string
+	ServerName         string
+	ServerVersion      string
+	Protocol           string
+	Protocols          []string
+	Transport          string
+	HTTPAddr           string
+	HTTPPath           string
+	HTTPBasePath       string
+	HTTPAuthToken      string
+	HTTPBasicUser      string
+	HTTPBasicPass      string
+	HTTPTokens         map[string]string
+	HTTPTLSCert        string
+	HTTPTLSKey         string
+	AllowedOrigins     []string
+	DeadlineSlack      time.Duration
+	RenderCacheSize    int
+	SubscriptionPoll   time.Duration
+	ContentEndpoints   []string
+	CompactJSON        bool
+	MaxConcurrency     int
+	Keepalive          time.Duration
+	HTTPReadTimeout    time.Duration
+	HTTPWriteTimeout   time.Duration
+	HTTPIdleTimeout    time.Duration
+	HTTPRateLimitRPS   float64
+	HTTPMaxBodyBytes   int64
+	HTTPTrustedProxies []netip.Prefix
+	StdioFraming       string
+	StrictInit         bool
+	DefaultArchived    string
+	FieldMap           map[string][]string
+	RateLimitRPS       float64
+	HighlightScanCap   int
+	MaxLabelLength     int
+	FrontmatterFields  []string
+	SortMap            map[string]string
+	BoolFormat         string
+	TextParagraphs     bool
+	Debug              bool
 }
 
 const (
-	defaultTimeoutSeconds = 20
-	defaultUserAgent      = "readeck-mcp/0.1"
-	defaultMaxPageSize    = 100
-	defaultTransport      = "stdio"
-	defaultHTTPAddr       = "127.0.0.1:8080"
-	defaultHTTPPath       = "/mcp"
+	defaultTimeoutSeconds   = 20
+	defaultUserAgent        = "readeck-mcp/0.1"
+	defaultMaxPageSize      = 100
+	defaultSearchLimit      = 20
+	defaultListLimit        = 200
+	defaultMaxConcurrency   = 4
+	defaultTransport        = "stdio"
+	defaultStdioFraming     = "content-length"
+	defaultArchived         = "exclude"
+	defaultBoolFormat       = "truefalse"
+	defaultHTTPAddr         = "127.0.0.1:8080"
+	defaultHTTPPath         = "/mcp"
+	defaultDeadlineSlack    = 2000
+	defaultRenderCacheSize  = 128
+	defaultSubscriptionPoll = 60
+	defaultHTTPReadTimeout  = 30
+	defaultHTTPIdleTimeout  = 120
+	defaultHTTPMaxBodyBytes = 1 << 20
+	defaultHighlightScanCap = 2000
+	defaultMaxLabelLength   = 100
+	defaultAPIPath          = "/api"
 )
 
+// MaxListLimit caps list page sizes (labels, highlights, collections).
+const MaxListLimit = 500
+
+// supportedProtocols lists MCP protocol revisions newest first.
+var supportedProtocols = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// sortModes lists the search sort values READECK_SORT_MAP may remap.
+var sortModes = []string{"relevance", "updated_desc", "created_desc", "published_desc", "label_match"}
+
+// frontmatterFields lists the content.md frontmatter keys, in default order.
+var frontmatterFields = []string{
+	"title", "url", "author", "site_name", "published_at", "created_at", "updated_at", "readeck_id",
+	"archived", "word_count", "reading_minutes", "read_progress", "highlight_count", "labels",
+}
+
+// mappedFields lists, per entity, the fields the mappers look up through
+// READECK_FIELD_MAP.
+var mappedFields = map[string][]string{
+	"bookmark": {
+		"id", "title", "url", "author", "site_name", "published_at", "created_at", "updated_at",
+		"is_archived", "is_favorite", "labels", "collection", "word_count", "reading_minutes",
+		"read_progress", "icon_url", "content_text", "content_html",
+	},
+	"collection": {"id", "name"},
+	"highlight":  {"id", "bookmark_id", "text", "note", "color", "created_at", "updated_at"},
+	"label":      {"id", "name", "color"},
+}
+
 func Load() (Config, error) {
 	baseRaw := strings.TrimSpace(os.Getenv("READECK_BASE_URL"))
 	if baseRaw == "" {
@@ -75,17 +157,112 @@ func Load() (Config, error) {
 	if maxPageSize <= 0 {
 		return Config{}, errors.New("READECK_MAX_PAGE_SIZE must be > 0")
 	}
+	searchLimit, err := readIntEnv("READECK_DEFAULT_SEARCH_LIMIT", min(defaultSearchLimit, maxPageSize))
+	if err != nil {
+		return Config{}, err
+	}
+	if searchLimit <= 0 || searchLimit > maxPageSize {
+		return Config{}, errors.New("READECK_DEFAULT_SEARCH_LIMIT must be between 1 and READECK_MAX_PAGE_SIZE")
+	}
+	listLimit, err := readIntEnv("READECK_DEFAULT_LIST_LIMIT", defaultListLimit)
+	if err != nil {
+		return Config{}, err
+	}
+	if listLimit <= 0 || listLimit > MaxListLimit {
+		return Config{}, fmt.Errorf("READECK_DEFAULT_LIST_LIMIT must be between 1 and %d", MaxListLimit)
+	}
+
+	highlightScanCap, err := readIntEnv("READECK_HIGHLIGHT_SCAN_CAP", defaultHighlightScanCap)
+	if err != nil {
+		return Config{}, err
+	}
+	if highlightScanCap <= 0 {
+		return Config{}, errors.New("READECK_HIGHLIGHT_SCAN_CAP must be > 0")
+	}
+
+	maxLabelLength, err := readIntEnv("READECK_MAX_LABEL_LENGTH", defaultMaxLabelLength)
+	if err != nil {
+		return Config{}, err
+	}
+	if maxLabelLength <= 0 {
+		return Config{}, errors.New("READECK_MAX_LABEL_LENGTH must be > 0")
+	}
+
+	maxConcurrency, err := readIntEnv("READECK_MAX_CONCURRENCY", defaultMaxConcurrency)
+	if err != nil {
+		return Config{}, err
+	}
+	if maxConcurrency <= 0 {
+		return Config{}, errors.New("READECK_MAX_CONCURRENCY must be > 0")
+	}
+
+	rateLimitRPS, err := readFloatEnv("READECK_RATE_LIMIT_RPS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	if rateLimitRPS < 0 {
+		return Config{}, errors.New("READECK_RATE_LIMIT_RPS must be >= 0")
+	}
 
 	verifyTLS, err := readBoolEnv("READECK_VERIFY_TLS", true)
 	if err != nil {
 		return Config{}, err
 	}
 
+	textParagraphs, err := readBoolEnv("READECK_TEXT_PARAGRAPHS", false)
+	if err != nil {
+		return Config{}, err
+	}
+	compactJSON, err := readBoolEnv("MCP_COMPACT_JSON", false)
+	if err != nil {
+		return Config{}, err
+	}
+
 	userAgent := strings.TrimSpace(os.Getenv("READECK_USER_AGENT"))
 	if userAgent == "" {
 		userAgent = defaultUserAgent
 	}
 
+	defaultArchivedMode := strings.ToLower(strings.TrimSpace(os.Getenv("READECK_DEFAULT_ARCHIVED")))
+	if defaultArchivedMode == "" {
+		defaultArchivedMode = defaultArchived
+	}
+	switch defaultArchivedMode {
+	case "exclude", "include", "only":
+	default:
+		return Config{}, errors.New("READECK_DEFAULT_ARCHIVED must be one of: exclude, include, only")
+	}
+
+	boolFormat := strings.ToLower(strings.TrimSpace(os.Getenv("READECK_BOOL_FORMAT")))
+	if boolFormat == "" {
+		boolFormat = defaultBoolFormat
+	}
+	switch boolFormat {
+	case "truefalse", "onezero", "yesno":
+	default:
+		return Config{}, errors.New("READECK_BOOL_FORMAT must be one of: truefalse, onezero, yesno")
+	}
+
+	contentEndpoints, err := parseContentEndpoints(os.Getenv("READECK_CONTENT_ENDPOINTS"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	fieldMap, err := parseFieldMap(os.Getenv("READECK_FIELD_MAP"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	frontmatter, err := parseFrontmatterFields(os.Getenv("READECK_FRONTMATTER_FIELDS"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	sortMap, err := parseSortMap(os.Getenv("READECK_SORT_MAP"))
+	if err != nil {
+		return Config{}, err
+	}
+
 	transport := strings.ToLower(strings.TrimSpace(os.Getenv("MCP_TRANSPORT")))
 	if transport == "" {
 		transport = defaultTransport
@@ -96,6 +273,26 @@ func Load() (Config, error) {
 		return Config{}, errors.New("MCP_TRANSPORT must be one of: stdio, http, streamable-http")
 	}
 
+	stdioFraming := strings.ToLower(strings.TrimSpace(os.Getenv("MCP_STDIO_FRAMING")))
+	if stdioFraming == "" {
+		stdioFraming = defaultStdioFraming
+	}
+	switch stdioFraming {
+	case "content-length", "ndjson":
+	default:
+		return Config{}, errors.New("MCP_STDIO_FRAMING must be one of: content-length, ndjson")
+	}
+
+	strictInit, err := readBoolEnv("MCP_STRICT_INIT", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	debug, err := readBoolEnv("MCP_DEBUG", false)
+	if err != nil {
+		return Config{}, err
+	}
+
 	httpAddr := strings.TrimSpace(os.Getenv("MCP_HTTP_ADDR"))
 	if httpAddr == "" {
 		httpAddr = defaultHTTPAddr
@@ -108,32 +305,232 @@ func Load() (Config, error) {
 	if !strings.HasPrefix(httpPath, "/") {
 		httpPath = "/" + httpPath
 	}
+	httpBasePath := strings.TrimSpace(os.Getenv("MCP_HTTP_BASE_PATH"))
+	if httpBasePath != "" && (!strings.HasPrefix(httpBasePath, "/") || strings.ContainsAny(httpBasePath, "?# ")) {
+		return Config{}, errors.New("MCP_HTTP_BASE_PATH must be a path starting with /")
+	}
+	httpBasePath = strings.TrimRight(httpBasePath, "/")
+
+	deadlineSlackMS, err := readIntEnv("MCP_DEADLINE_SLACK_MS", defaultDeadlineSlack)
+	if err != nil {
+		return Config{}, err
+	}
+	if deadlineSlackMS < 0 {
+		return Config{}, errors.New("MCP_DEADLINE_SLACK_MS must be >= 0")
+	}
+
+	renderCacheSize, err := readIntEnv("MCP_RENDER_CACHE_SIZE", defaultRenderCacheSize)
+	if err != nil {
+		return Config{}, err
+	}
+	if renderCacheSize < 0 {
+		return Config{}, errors.New("MCP_RENDER_CACHE_SIZE must be >= 0")
+	}
+
+	subscriptionPollSeconds, err := readIntEnv("MCP_SUBSCRIPTION_POLL_SECONDS", defaultSubscriptionPoll)
+	if err != nil {
+		return Config{}, err
+	}
+	if subscriptionPollSeconds <= 0 {
+		return Config{}, errors.New("MCP_SUBSCRIPTION_POLL_SECONDS must be > 0")
+	}
+
+	keepaliveSeconds, err := readIntEnv("MCP_KEEPALIVE_SECONDS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	if keepaliveSeconds < 0 {
+		return Config{}, errors.New("MCP_KEEPALIVE_SECONDS must be >= 0")
+	}
+
+	httpReadTimeout, err := readIntEnv("MCP_HTTP_READ_TIMEOUT_SECONDS", defaultHTTPReadTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+	if httpReadTimeout < 0 {
+		return Config{}, errors.New("MCP_HTTP_READ_TIMEOUT_SECONDS must be >= 0")
+	}
+	// A tool call can make several upstream requests, so by default the
+	// response may take a few upstream timeouts to write.
+	httpWriteTimeout, err := readIntEnv("MCP_HTTP_WRITE_TIMEOUT_SECONDS", max(60, 3*timeoutSeconds))
+	if err != nil {
+		return Config{}, err
+	}
+	if httpWriteTimeout < 0 {
+		return Config{}, errors.New("MCP_HTTP_WRITE_TIMEOUT_SECONDS must be >= 0")
+	}
+	httpIdleTimeout, err := readIntEnv("MCP_HTTP_IDLE_TIMEOUT_SECONDS", defaultHTTPIdleTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+	if httpIdleTimeout < 0 {
+		return Config{}, errors.New("MCP_HTTP_IDLE_TIMEOUT_SECONDS must be >= 0")
+	}
+	httpMaxBodyBytes, err := readIntEnv("MCP_HTTP_MAX_BODY_BYTES", defaultHTTPMaxBodyBytes)
+	if err != nil {
+		return Config{}, err
+	}
+	if httpMaxBodyBytes <= 0 {
+		return Config{}, errors.New("MCP_HTTP_MAX_BODY_BYTES must be > 0")
+	}
+	httpRateLimitRPS, err := readFloatEnv("MCP_HTTP_RATE_LIMIT_RPS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	if httpRateLimitRPS < 0 {
+		return Config{}, errors.New("MCP_HTTP_RATE_LIMIT_RPS must be >= 0")
+	}
+	httpTrustedProxies, err := parseTrustedProxies(os.Getenv("MCP_HTTP_TRUSTED_PROXIES"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	apiPath := strings.TrimSpace(os.Getenv("READECK_API_PATH"))
+	if apiPath == "" {
+		apiPath = defaultAPIPath
+	}
+	if !strings.HasPrefix(apiPath, "/") || strings.ContainsAny(apiPath, "?# ") {
+		return Config{}, errors.New("READECK_API_PATH must be a path starting with /")
+	}
+	apiPath = strings.TrimRight(apiPath, "/")
 
 	httpAuthToken := strings.TrimSpace(os.Getenv("MCP_HTTP_AUTH_TOKEN"))
+	httpBasicUser := strings.TrimSpace(os.Getenv("MCP_HTTP_BASIC_USER"))
+	httpBasicPass := os.Getenv("MCP_HTTP_BASIC_PASS")
+	if (httpBasicUser == "") != (httpBasicPass == "") {
+		return Config{}, errors.New("MCP_HTTP_BASIC_USER and MCP_HTTP_BASIC_PASS must be set together")
+	}
+	httpTokens, err := parseHTTPTokens(os.Getenv("MCP_HTTP_TOKENS"))
+	if err != nil {
+		return Config{}, err
+	}
+	httpTLSCert := strings.TrimSpace(os.Getenv("MCP_HTTP_TLS_CERT"))
+	httpTLSKey := strings.TrimSpace(os.Getenv("MCP_HTTP_TLS_KEY"))
+	if (httpTLSCert == "") != (httpTLSKey == "") {
+		return Config{}, errors.New("MCP_HTTP_TLS_CERT and MCP_HTTP_TLS_KEY must be set together")
+	}
 	allowedOrigins := parseCSV(os.Getenv("MCP_ALLOWED_ORIGINS"))
 
-	baseURL.Path = strings.TrimRight(baseURL.Path, "/")
-	apiBase := strings.TrimRight(baseURL.String(), "/") + "/api"
+	// Only the path decides whether the API path is already there; a query
+	// or fragment on the base URL has no place in API requests.
+	basePath := strings.TrimRight(path.Clean("/"+baseURL.Path), "/")
+	if !strings.HasSuffix(basePath, apiPath) {
+		basePath += apiPath
+	}
+	baseURL.Path = basePath
+	baseURL.RawPath = ""
+	baseURL.RawQuery = ""
+	baseURL.ForceQuery = false
+	baseURL.Fragment = ""
+	baseURL.RawFragment = ""
+	apiBase := baseURL.String()
 
 	cfg := Config{
-		APIToken:       token,
-		Timeout:        time.Duration(timeoutSeconds) * time.Second,
-		UserAgent:      userAgent,
-		VerifyTLS:      verifyTLS,
-		MaxPageSize:    maxPageSize,
-		APIBaseURL:     apiBase,
-		ServerName:     "readeck-mcp",
-		ServerVersion:  "0.1.0",
-		Protocol:       "2025-06-18",
-		Transport:      transport,
-		HTTPAddr:       httpAddr,
-		HTTPPath:       httpPath,
-		HTTPAuthToken:  httpAuthToken,
-		AllowedOrigins: allowedOrigins,
+		APIToken:           token,
+		Timeout:            time.Duration(timeoutSeconds) * time.Second,
+		UserAgent:          userAgent,
+		VerifyTLS:          verifyTLS,
+		MaxPageSize:        maxPageSize,
+		DefaultSearchLimit: searchLimit,
+		DefaultListLimit:   listLimit,
+		APIBaseURL:         apiBase,
+		ServerName:         "readeck-mcp",
+		ServerVersion:      "0.1.0",
+		Protocol:           supportedProtocols[0],
+		Protocols:          supportedProtocols,
+		Transport:          transport,
+		HTTPAddr:           httpAddr,
+		HTTPPath:           httpPath,
+		HTTPBasePath:       httpBasePath,
+		HTTPAuthToken:      httpAuthToken,
+		HTTPBasicUser:      httpBasicUser,
+		HTTPBasicPass:      httpBasicPass,
+		HTTPTokens:         httpTokens,
+		HTTPTLSCert:        httpTLSCert,
+		HTTPTLSKey:         httpTLSKey,
+		AllowedOrigins:     allowedOrigins,
+		DeadlineSlack:      time.Duration(deadlineSlackMS) * time.Millisecond,
+		RenderCacheSize:    renderCacheSize,
+		SubscriptionPoll:   time.Duration(subscriptionPollSeconds) * time.Second,
+		ContentEndpoints:   contentEndpoints,
+		CompactJSON:        compactJSON,
+		MaxConcurrency:     maxConcurrency,
+		Keepalive:          time.Duration(keepaliveSeconds) * time.Second,
+		HTTPReadTimeout:    time.Duration(httpReadTimeout) * time.Second,
+		HTTPWriteTimeout:   time.Duration(httpWriteTimeout) * time.Second,
+		HTTPIdleTimeout:    time.Duration(httpIdleTimeout) * time.Second,
+		HTTPRateLimitRPS:   httpRateLimitRPS,
+		HTTPMaxBodyBytes:   int64(httpMaxBodyBytes),
+		HTTPTrustedProxies: httpTrustedProxies,
+		StdioFraming:       stdioFraming,
+		StrictInit:         strictInit,
+		DefaultArchived:    defaultArchivedMode,
+		FieldMap:           fieldMap,
+		RateLimitRPS:       rateLimitRPS,
+		HighlightScanCap:   highlightScanCap,
+		MaxLabelLength:     maxLabelLength,
+		FrontmatterFields:  frontmatter,
+		SortMap:            sortMap,
+		BoolFormat:         boolFormat,
+		TextParagraphs:     textParagraphs,
+		Debug:              debug,
 	}
 	return cfg, nil
 }
 
+// Redacted returns the effective configuration without secrets: the API
+// token and HTTP auth token are reduced to whether they are set, and the
+// base URL to its host.
+func (c Config) Redacted() map[string]any {
+	host := ""
+	if u, err := url.Parse(c.APIBaseURL); err == nil {
+		host = u.Host
+	}
+	return map[string]any{
+		"base_url_host":        host,
+		"api_token_set":        c.APIToken != "",
+		"timeout_seconds":      c.Timeout.Seconds(),
+		"user_agent":           c.UserAgent,
+		"verify_tls":           c.VerifyTLS,
+		"max_page_size":        c.MaxPageSize,
+		"default_search_limit": c.DefaultSearchLimit,
+		"default_list_limit":   c.DefaultListLimit,
+		"server_version":       c.ServerVersion,
+		"protocols":            c.Protocols,
+		"transport":            c.Transport,
+		"http_addr":            c.HTTPAddr,
+		"http_path":            c.HTTPPath,
+		"http_base_path":       c.HTTPBasePath,
+		"http_auth_enabled":    c.HTTPAuthToken != "",
+		"http_basic_auth":      c.HTTPBasicUser != "",
+		"http_scoped_tokens":   len(c.HTTPTokens),
+		"allowed_origins":      c.AllowedOrigins,
+		"stdio_framing":        c.StdioFraming,
+		"strict_init":          c.StrictInit,
+		"compact_json":         c.CompactJSON,
+		"deadline_slack_ms":    c.DeadlineSlack.Milliseconds(),
+		"render_cache_size":    c.RenderCacheSize,
+		"subscription_poll_s":  c.SubscriptionPoll.Seconds(),
+		"keepalive_s":          c.Keepalive.Seconds(),
+		"http_tls":             c.HTTPTLSCert != "",
+		"http_read_timeout_s":  c.HTTPReadTimeout.Seconds(),
+		"http_write_timeout_s": c.HTTPWriteTimeout.Seconds(),
+		"http_idle_timeout_s":  c.HTTPIdleTimeout.Seconds(),
+		"http_rate_limit_rps":  c.HTTPRateLimitRPS,
+		"http_max_body_bytes":  c.HTTPMaxBodyBytes,
+		"http_trusted_proxies": len(c.HTTPTrustedProxies),
+		"rate_limit_rps":       c.RateLimitRPS,
+		"highlight_scan_cap":   c.HighlightScanCap,
+		"max_label_length":     c.MaxLabelLength,
+		"frontmatter_fields":   c.FrontmatterFields,
+		"sort_map":             c.SortMap,
+		"bool_format":          c.BoolFormat,
+		"text_paragraphs":      c.TextParagraphs,
+		"default_archived":     c.DefaultArchived,
+		"field_map":            c.FieldMap,
+	}
+}
+
 func NewHTTPClient(cfg Config) *http.Client {
 	transport := http.DefaultTransport.(*http.Transport).Clone()
 	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: !cfg.VerifyTLS}
@@ -155,6 +552,18 @@ func readIntEnv(key string, fallback int) (int, error) {
 	return v, nil
 }
 
+func readFloatEnv(key string, fallback float64) (float64, error) {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return fallback, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%s must be a number", key)
+	}
+	return v, nil
+}
+
 func readBoolEnv(key string, fallback bool) (bool, error) {
 	raw := strings.TrimSpace(os.Getenv(key))
 	if raw == "" {
@@ -181,6 +590,47 @@ func validateScheme(u *url.URL) error {
 	return errors.New("READECK_BASE_URL must use https unless pointing to localhost")
 }
 
+// parseHTTPTokens parses token:scope pairs such as "abc:read,def:write".
+// The scope is taken after the last colon so tokens may contain colons.
+func parseHTTPTokens(raw string) (map[string]string, error) {
+	entries := parseCSV(raw)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	tokens := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		idx := strings.LastIndex(entry, ":")
+		if idx <= 0 {
+			return nil, errors.New("MCP_HTTP_TOKENS must be a list of token:read or token:write")
+		}
+		token, scope := entry[:idx], strings.ToLower(entry[idx+1:])
+		if scope != "read" && scope != "write" {
+			return nil, errors.New("MCP_HTTP_TOKENS must be a list of token:read or token:write")
+		}
+		tokens[token] = scope
+	}
+	return tokens, nil
+}
+
+// parseTrustedProxies accepts a comma-separated list of IPs and CIDR ranges.
+// Bare IPs become single-address prefixes.
+func parseTrustedProxies(raw string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, entry := range parseCSV(raw) {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			out = append(out, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, errors.New("MCP_HTTP_TRUSTED_PROXIES must be a list of IPs or CIDR ranges")
+		}
+		addr = addr.Unmap()
+		out = append(out, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return out, nil
+}
+
 func parseCSV(raw string) []string {
 	if strings.TrimSpace(raw) == "" {
 		return nil
@@ -196,3 +646,124 @@ func parseCSV(raw string) []string {
 	}
 	return out
 }
+
+func parseContentEndpoints(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	parts := parseCSV(raw)
+	if len(parts) == 0 {
+		return nil, errors.New("READECK_CONTENT_ENDPOINTS must list at least one path")
+	}
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		clean := strings.Trim(p, "/")
+		if clean == "" || strings.ContainsAny(clean, "?# ") {
+			return nil, fmt.Errorf("READECK_CONTENT_ENDPOINTS has invalid path %q", p)
+		}
+		out = append(out, "/"+clean)
+	}
+	return out, nil
+}
+
+// parseFrontmatterFields returns nil when unset so the renderer falls back to
+// the full default set.
+func parseFrontmatterFields(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	seen := map[string]struct{}{}
+	out := []string{}
+	for _, field := range parseCSV(strings.ToLower(raw)) {
+		if !slices.Contains(frontmatterFields, field) {
+			return nil, fmt.Errorf("READECK_FRONTMATTER_FIELDS has unknown field %q; known fields: %s", field, strings.Join(frontmatterFields, ", "))
+		}
+		if _, ok := seen[field]; ok {
+			continue
+		}
+		seen[field] = struct{}{}
+		out = append(out, field)
+	}
+	if len(out) == 0 {
+		return nil, errors.New("READECK_FRONTMATTER_FIELDS must list at least one field")
+	}
+	return out, nil
+}
+
+// parseSortMap reads sort=token pairs (created_desc=-created). An empty token
+// stops that sort from being forwarded upstream.
+func parseSortMap(raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	out := map[string]string{}
+	for _, pair := range parseCSV(raw) {
+		mode, token, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("READECK_SORT_MAP entry %q must look like sort=token", pair)
+		}
+		mode = strings.ToLower(strings.TrimSpace(mode))
+		if !slices.Contains(sortModes, mode) {
+			return nil, fmt.Errorf("READECK_SORT_MAP sort %q must be one of: %s", mode, strings.Join(sortModes, ", "))
+		}
+		out[mode] = strings.TrimSpace(token)
+	}
+	return out, nil
+}
+
+// parseFieldMap accepts either a JSON object ({"bookmark.content_text":
+// ["summary_text"]}) or CSV pairs (bookmark.content_text=summary_text).
+func parseFieldMap(raw string) (map[string][]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	out := map[string][]string{}
+	if strings.HasPrefix(raw, "{") {
+		var decoded map[string][]string
+		if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+			return nil, errors.New("READECK_FIELD_MAP must be a JSON object of string arrays")
+		}
+		for field, keys := range decoded {
+			for _, key := range keys {
+				if err := addFieldMapping(out, field, key); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return out, nil
+	}
+
+	for _, pair := range parseCSV(raw) {
+		field, key, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("READECK_FIELD_MAP entry %q must look like entity.field=key", pair)
+		}
+		if err := addFieldMapping(out, field, key); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func addFieldMapping(out map[string][]string, field, key string) error {
+	field = strings.TrimSpace(field)
+	key = strings.TrimSpace(key)
+	entity, name, ok := strings.Cut(field, ".")
+	if !ok || name == "" {
+		return fmt.Errorf("READECK_FIELD_MAP field %q must look like entity.field", field)
+	}
+	known, ok := mappedFields[entity]
+	if !ok {
+		return fmt.Errorf("READECK_FIELD_MAP field %q entity must be one of: bookmark, collection, highlight, label", field)
+	}
+	if !slices.Contains(known, name) {
+		return fmt.Errorf("READECK_FIELD_MAP has unknown field %q; known %s fields: %s", field, entity, strings.Join(known, ", "))
+	}
+	if key == "" {
+		return fmt.Errorf("READECK_FIELD_MAP field %q has an empty key", field)
+	}
+	out[field] = append(out[field], key)
+	return nil
+}
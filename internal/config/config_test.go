@@ -0,0 +1,240 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// setEnv applies the minimum valid environment plus extra name/value pairs.
+func setEnv(t *testing.T, env ...string) {
+	t.Helper()
+	t.Setenv("READECK_BASE_URL", "https://readeck.example.com")
+	t.Setenv("READECK_API_TOKEN", "token")
+	for i := 0; i+1 < len(env); i += 2 {
+		t.Setenv(env[i], env[i+1])
+	}
+}
+
+func mustLoad(t *testing.T, env ...string) Config {
+	t.Helper()
+	setEnv(t, env...)
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return cfg
+}
+
+func loadError(t *testing.T, env ...string) string {
+	t.Helper()
+	setEnv(t, env...)
+	_, err := Load()
+	if err == nil {
+		t.Fatalf("Load with %v succeeded, want an error", env)
+	}
+	return err.Error()
+}
+
+func TestAPIBaseURL(t *testing.T) {
+	tests := []struct {
+		base, apiPath, want string
+	}{
+		{"https://r.example.com", "", "https://r.example.com/api"},
+		{"https://r.example.com/", "", "https://r.example.com/api"},
+		{"https://r.example.com/api", "", "https://r.example.com/api"},
+		{"https://r.example.com/api/", "", "https://r.example.com/api"},
+		{"https://r.example.com/api?x=1", "", "https://r.example.com/api"},
+		{"https://r.example.com/api#frag", "", "https://r.example.com/api"},
+		{"https://r.example.com/readeck?x=1", "", "https://r.example.com/readeck/api"},
+		{"https://r.example.com/myapi", "", "https://r.example.com/myapi/api"},
+		{"https://r.example.com", "/v1/readeck/", "https://r.example.com/v1/readeck"},
+		{"https://r.example.com/proxy/v1/readeck", "/v1/readeck", "https://r.example.com/proxy/v1/readeck"},
+	}
+	for _, tt := range tests {
+		cfg := mustLoad(t, "READECK_BASE_URL", tt.base, "READECK_API_PATH", tt.apiPath)
+		if cfg.APIBaseURL != tt.want {
+			t.Errorf("base %q path %q: APIBaseURL = %q, want %q", tt.base, tt.apiPath, cfg.APIBaseURL, tt.want)
+		}
+	}
+}
+
+func TestAPIPathMustStartWithSlash(t *testing.T) {
+	if msg := loadError(t, "READECK_API_PATH", "api"); !strings.Contains(msg, "READECK_API_PATH") {
+		t.Fatalf("error = %q", msg)
+	}
+}
+
+func TestFieldMapAcceptsJSONAndCSV(t *testing.T) {
+	for _, raw := range []string{
+		`{"bookmark.content_text":["summary_text","body"],"label.name":["title"]}`,
+		"bookmark.content_text=summary_text,bookmark.content_text=body,label.name=title",
+	} {
+		cfg := mustLoad(t, "READECK_FIELD_MAP", raw)
+		if got := cfg.FieldMap["bookmark.content_text"]; len(got) != 2 || got[0] != "summary_text" || got[1] != "body" {
+			t.Fatalf("%s: bookmark.content_text = %q", raw, got)
+		}
+		if got := cfg.FieldMap["label.name"]; len(got) != 1 || got[0] != "title" {
+			t.Fatalf("%s: label.name = %q", raw, got)
+		}
+	}
+}
+
+func TestFieldMapRejectsUnknownFields(t *testing.T) {
+	tests := []struct{ raw, want string }{
+		{"bookmark.summary=summary_text", `unknown field "bookmark.summary"`},
+		{`{"highlight.title":["name"]}`, `unknown field "highlight.title"`},
+		{"article.title=name", "entity must be one of"},
+		{"bookmark.title=", "empty key"},
+		{"bookmark.title", "entity.field=key"},
+	}
+	for _, tt := range tests {
+		if msg := loadError(t, "READECK_FIELD_MAP", tt.raw); !strings.Contains(msg, tt.want) {
+			t.Errorf("%s: error = %q, want it to mention %q", tt.raw, msg, tt.want)
+		}
+	}
+}
+
+func TestContentEndpoints(t *testing.T) {
+	cfg := mustLoad(t, "READECK_CONTENT_ENDPOINTS", "text, /article/")
+	if got := cfg.ContentEndpoints; len(got) != 2 || got[0] != "/text" || got[1] != "/article" {
+		t.Fatalf("ContentEndpoints = %q", got)
+	}
+	for _, raw := range []string{",", "text?x=1", "/"} {
+		if msg := loadError(t, "READECK_CONTENT_ENDPOINTS", raw); !strings.Contains(msg, "READECK_CONTENT_ENDPOINTS") {
+			t.Errorf("%q: error = %q", raw, msg)
+		}
+	}
+}
+
+func TestDefaultArchived(t *testing.T) {
+	if cfg := mustLoad(t, "READECK_DEFAULT_ARCHIVED", ""); cfg.DefaultArchived != "exclude" {
+		t.Fatalf("DefaultArchived = %q, want exclude", cfg.DefaultArchived)
+	}
+	if cfg := mustLoad(t, "READECK_DEFAULT_ARCHIVED", " Include "); cfg.DefaultArchived != "include" {
+		t.Fatalf("DefaultArchived = %q, want include", cfg.DefaultArchived)
+	}
+	if msg := loadError(t, "READECK_DEFAULT_ARCHIVED", "all"); !strings.Contains(msg, "READECK_DEFAULT_ARCHIVED") {
+		t.Fatalf("error = %q", msg)
+	}
+}
+
+func TestHighlightScanCap(t *testing.T) {
+	if cfg := mustLoad(t, "READECK_HIGHLIGHT_SCAN_CAP", ""); cfg.HighlightScanCap != defaultHighlightScanCap {
+		t.Fatalf("HighlightScanCap = %d, want %d", cfg.HighlightScanCap, defaultHighlightScanCap)
+	}
+	if msg := loadError(t, "READECK_HIGHLIGHT_SCAN_CAP", "0"); !strings.Contains(msg, "READECK_HIGHLIGHT_SCAN_CAP") {
+		t.Fatalf("error = %q", msg)
+	}
+}
+
+func TestFrontmatterFields(t *testing.T) {
+	if cfg := mustLoad(t, "READECK_FRONTMATTER_FIELDS", ""); cfg.FrontmatterFields != nil {
+		t.Fatalf("FrontmatterFields = %q, want nil for the default set", cfg.FrontmatterFields)
+	}
+	cfg := mustLoad(t, "READECK_FRONTMATTER_FIELDS", "URL, title,url")
+	if got := cfg.FrontmatterFields; len(got) != 2 || got[0] != "url" || got[1] != "title" {
+		t.Fatalf("FrontmatterFields = %q", got)
+	}
+	if msg := loadError(t, "READECK_FRONTMATTER_FIELDS", "title,summary"); !strings.Contains(msg, `unknown field "summary"`) {
+		t.Fatalf("error = %q", msg)
+	}
+	if msg := loadError(t, "READECK_FRONTMATTER_FIELDS", " , "); !strings.Contains(msg, "at least one field") {
+		t.Fatalf("error = %q", msg)
+	}
+}
+
+func TestSortMap(t *testing.T) {
+	cfg := mustLoad(t, "READECK_SORT_MAP", "Updated_Desc = -updated, created_desc=")
+	if len(cfg.SortMap) != 2 || cfg.SortMap["updated_desc"] != "-updated" || cfg.SortMap["created_desc"] != "" {
+		t.Fatalf("SortMap = %v", cfg.SortMap)
+	}
+	for _, raw := range []string{"newest=-created", "updated_desc"} {
+		if msg := loadError(t, "READECK_SORT_MAP", raw); !strings.Contains(msg, "READECK_SORT_MAP") {
+			t.Errorf("%q: error = %q", raw, msg)
+		}
+	}
+}
+
+func TestBoolFormat(t *testing.T) {
+	if cfg := mustLoad(t, "READECK_BOOL_FORMAT", " OneZero "); cfg.BoolFormat != "onezero" {
+		t.Fatalf("BoolFormat = %q", cfg.BoolFormat)
+	}
+	if msg := loadError(t, "READECK_BOOL_FORMAT", "10"); !strings.Contains(msg, "READECK_BOOL_FORMAT") {
+		t.Fatalf("error = %q", msg)
+	}
+}
+
+func TestHTTPTimeoutsMustBeNonNegative(t *testing.T) {
+	for _, name := range []string{"MCP_HTTP_READ_TIMEOUT_SECONDS", "MCP_HTTP_WRITE_TIMEOUT_SECONDS", "MCP_HTTP_IDLE_TIMEOUT_SECONDS"} {
+		if msg := loadError(t, name, "-1"); !strings.Contains(msg, name) {
+			t.Errorf("%s: error = %q", name, msg)
+		}
+		t.Setenv(name, "")
+	}
+}
+
+func TestTLSCertAndKeyGoTogether(t *testing.T) {
+	if msg := loadError(t, "MCP_HTTP_TLS_CERT", "cert.pem", "MCP_HTTP_TLS_KEY", ""); !strings.Contains(msg, "MCP_HTTP_TLS_CERT and MCP_HTTP_TLS_KEY") {
+		t.Fatalf("error = %q", msg)
+	}
+	if msg := loadError(t, "MCP_HTTP_TLS_CERT", "", "MCP_HTTP_TLS_KEY", "key.pem"); !strings.Contains(msg, "MCP_HTTP_TLS_CERT and MCP_HTTP_TLS_KEY") {
+		t.Fatalf("error = %q", msg)
+	}
+}
+
+func TestBasicAuthUserAndPasswordGoTogether(t *testing.T) {
+	if msg := loadError(t, "MCP_HTTP_BASIC_USER", "alice", "MCP_HTTP_BASIC_PASS", ""); !strings.Contains(msg, "MCP_HTTP_BASIC_USER and MCP_HTTP_BASIC_PASS") {
+		t.Fatalf("error = %q", msg)
+	}
+}
+
+func TestHTTPMaxBodyBytes(t *testing.T) {
+	if cfg := mustLoad(t, "MCP_HTTP_MAX_BODY_BYTES", ""); cfg.HTTPMaxBodyBytes != 1<<20 {
+		t.Fatalf("HTTPMaxBodyBytes = %d, want 1MB", cfg.HTTPMaxBodyBytes)
+	}
+	if msg := loadError(t, "MCP_HTTP_MAX_BODY_BYTES", "0"); !strings.Contains(msg, "MCP_HTTP_MAX_BODY_BYTES") {
+		t.Fatalf("error = %q", msg)
+	}
+}
+
+func TestHTTPTokens(t *testing.T) {
+	cfg := mustLoad(t, "MCP_HTTP_TOKENS", "a:b:read, tokenB:Write")
+	if len(cfg.HTTPTokens) != 2 || cfg.HTTPTokens["a:b"] != "read" || cfg.HTTPTokens["tokenB"] != "write" {
+		t.Fatalf("HTTPTokens = %v", cfg.HTTPTokens)
+	}
+	for _, raw := range []string{"token", ":read", "token:admin"} {
+		if msg := loadError(t, "MCP_HTTP_TOKENS", raw); !strings.Contains(msg, "MCP_HTTP_TOKENS") {
+			t.Errorf("%q: error = %q", raw, msg)
+		}
+	}
+}
+
+func TestHTTPBasePath(t *testing.T) {
+	if cfg := mustLoad(t, "MCP_HTTP_BASE_PATH", "/proxy/"); cfg.HTTPBasePath != "/proxy" {
+		t.Fatalf("HTTPBasePath = %q", cfg.HTTPBasePath)
+	}
+	for _, raw := range []string{"proxy", "/proxy?x=1"} {
+		if msg := loadError(t, "MCP_HTTP_BASE_PATH", raw); !strings.Contains(msg, "MCP_HTTP_BASE_PATH") {
+			t.Errorf("%q: error = %q", raw, msg)
+		}
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	cfg := mustLoad(t, "READECK_DEFAULT_SEARCH_LIMIT", "", "READECK_DEFAULT_LIST_LIMIT", "")
+	if cfg.DefaultSearchLimit != 20 || cfg.DefaultListLimit != 200 {
+		t.Fatalf("defaults = %d/%d, want 20/200", cfg.DefaultSearchLimit, cfg.DefaultListLimit)
+	}
+	if cfg := mustLoad(t, "READECK_MAX_PAGE_SIZE", "10", "READECK_DEFAULT_SEARCH_LIMIT", ""); cfg.DefaultSearchLimit != 10 {
+		t.Fatalf("DefaultSearchLimit = %d, want it capped at the page size", cfg.DefaultSearchLimit)
+	}
+	if msg := loadError(t, "READECK_MAX_PAGE_SIZE", "10", "READECK_DEFAULT_SEARCH_LIMIT", "11"); !strings.Contains(msg, "READECK_DEFAULT_SEARCH_LIMIT") {
+		t.Fatalf("error = %q", msg)
+	}
+	t.Setenv("READECK_DEFAULT_SEARCH_LIMIT", "")
+	for _, raw := range []string{"0", "501"} {
+		if msg := loadError(t, "READECK_DEFAULT_LIST_LIMIT", raw); !strings.Contains(msg, "READECK_DEFAULT_LIST_LIMIT") {
+			t.Errorf("%q: error = %q", raw, msg)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+	"github.com/akrisanov/readeck-mcp/internal/render"
+)
+
+// maxContentResults caps how many search results readeck.search fetches
+// content for when include_content is set.
+const maxContentResults = 20
+
+type summaryWithContent struct {
+	readeck.BookmarkSummary
+	ContentText string `json:"content_text,omitempty"`
+}
+
+// searchWithContent runs a search and fetches each result's content
+// concurrently; the client's concurrency limit bounds the upstream load.
+// Items whose content fails to load are returned without it.
+func (s *Server) searchWithContent(ctx context.Context, opts readeck.SearchOptions) (map[string]any, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = s.cfg.DefaultSearchLimit
+	}
+	opts.Limit = min(opts.Limit, maxContentResults)
+	result, err := s.client.Search(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]summaryWithContent, len(result.Items))
+	var wg sync.WaitGroup
+	for i, summary := range result.Items {
+		items[i].BookmarkSummary = summary
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			text, html, err := s.client.GetContent(ctx, summary.ID)
+			if err != nil {
+				s.logger.Printf("search content id=%s: %v", summary.ID, err)
+				return
+			}
+			items[i].ContentText = render.ContentText(readeck.Bookmark{ContentText: text, ContentHTML: html}, s.textOptions())
+		}()
+	}
+	wg.Wait()
+
+	out := map[string]any{"items": items}
+	if result.NextCursor != "" {
+		out["next_cursor"] = result.NextCursor
+	}
+	if result.PrevCursor != "" {
+		out["prev_cursor"] = result.PrevCursor
+	}
+	if result.Partial {
+		out["partial"] = true
+	}
+	return out, nil
+}
+
+// sentenceEnders are the runes a complete article plausibly ends with.
+const sentenceEnders = ".!?…\"'”’)]»*:"
+
+// checkContent fetches a bookmark's content and flags it as truncated when it
+// stops mid-sentence or is much shorter than the upstream word count or the
+// snippet suggest.
+func (s *Server) checkContent(ctx context.Context, id string) (map[string]any, error) {
+	bookmark, err := s.client.GetBookmark(ctx, id, readeck.IncludeOptions{Content: true})
+	if err != nil {
+		return nil, err
+	}
+	text := render.BookmarkContentText(bookmark)
+	chars, words := render.Stats(text)
+
+	reasons := []string{}
+	switch {
+	case text == "":
+		reasons = append(reasons, "no content")
+	case !endsSentence(text):
+		reasons = append(reasons, "ends mid-sentence")
+	}
+	if bookmark.WordCount > 0 && words < bookmark.WordCount/2 {
+		reasons = append(reasons, "fewer than half of the reported word count")
+	}
+	if snippet := strings.TrimSuffix(bookmark.Snippet, "..."); text != "" && len([]rune(snippet)) > chars {
+		reasons = append(reasons, "shorter than the snippet")
+	}
+
+	return map[string]any{
+		"id":              bookmark.ID,
+		"char_count":      chars,
+		"word_count":      words,
+		"looks_truncated": len(reasons) > 0,
+		"reasons":         reasons,
+	}, nil
+}
+
+func endsSentence(text string) bool {
+	text = strings.TrimRightFunc(text, unicode.IsSpace)
+	if text == "" {
+		return false
+	}
+	last := []rune(text)[len([]rune(text))-1]
+	return strings.ContainsRune(sentenceEnders, last)
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestContentCheckFlagsTruncatedContent(t *testing.T) {
+	up := upstream{
+		bookmarks: map[string]map[string]any{
+			"complete": {"id": "complete", "title": "Complete"},
+			"midway":   {"id": "midway", "title": "Midway"},
+			"short":    {"id": "short", "title": "Short", "word_count": 100},
+			"snippet":  {"id": "snippet", "title": "Snippet", "excerpt": "A much longer excerpt than the scraped body turned out to be."},
+			"empty":    {"id": "empty", "title": "Empty"},
+		},
+		content: map[string]string{
+			"complete": "<p>A full sentence. Another one!</p>",
+			"midway":   "<p>This article stops in the mid</p>",
+			"short":    "<p>Only a few words here.</p>",
+			"snippet":  "<p>Too short.</p>",
+		},
+	}
+	s := newTestServer(t, up.handler(t))
+
+	tests := []struct {
+		id        string
+		chars     float64
+		words     float64
+		truncated bool
+		reasons   []string
+	}{
+		{"complete", 29, 5, false, nil},
+		{"midway", 29, 6, true, []string{"ends mid-sentence"}},
+		{"short", 22, 5, true, []string{"fewer than half of the reported word count"}},
+		{"snippet", 10, 2, true, []string{"shorter than the snippet"}},
+		{"empty", 0, 0, true, []string{"no content"}},
+	}
+	for _, tt := range tests {
+		out := mustCallTool(t, s, "readeck.content.check", `{"id":"`+tt.id+`"}`)
+		var reasons []string
+		for _, r := range out["reasons"].([]any) {
+			reasons = append(reasons, r.(string))
+		}
+		if out["char_count"] != tt.chars || out["word_count"] != tt.words {
+			t.Errorf("%s: chars = %v words = %v", tt.id, out["char_count"], out["word_count"])
+		}
+		if out["looks_truncated"] != tt.truncated || !slices.Equal(reasons, tt.reasons) {
+			t.Errorf("%s: looks_truncated = %v reasons = %q", tt.id, out["looks_truncated"], reasons)
+		}
+	}
+}
+
+func TestSearchIncludesContentOnlyWhenRequested(t *testing.T) {
+	var contentHits, inflight, peak atomic.Int32
+	var searchLimit atomic.Value
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bookmarks" {
+			searchLimit.Store(r.URL.Query().Get("limit"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			items := make([]any, 0, limit)
+			for i := range limit {
+				items = append(items, map[string]any{"id": "b" + strconv.Itoa(i), "title": "T"})
+			}
+			writeJSON(t, w, map[string]any{"items": items})
+			return
+		}
+		id, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/bookmarks/"), "/")
+		contentHits.Add(1)
+		if id == "b1" {
+			http.NotFound(w, r)
+			return
+		}
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		writeJSON(t, w, map[string]any{"content_html": "<p>Body of " + id + "</p>"})
+	}, "READECK_MAX_CONCURRENCY", "2")
+
+	out := mustCallTool(t, s, "readeck.search", `{"limit":3}`)
+	for _, item := range out["items"].([]any) {
+		if _, ok := item.(map[string]any)["content_text"]; ok {
+			t.Fatalf("content_text without include_content: %v", item)
+		}
+	}
+	if n := contentHits.Load(); n != 0 {
+		t.Fatalf("content fetched %d times without include_content", n)
+	}
+
+	out = mustCallTool(t, s, "readeck.search", `{"limit":50,"include_content":true}`)
+	if got := searchLimit.Load(); got != strconv.Itoa(maxContentResults) {
+		t.Fatalf("search limit = %v, want it capped at %d", got, maxContentResults)
+	}
+	items := out["items"].([]any)
+	if len(items) != maxContentResults {
+		t.Fatalf("items = %d, want %d", len(items), maxContentResults)
+	}
+	for _, raw := range items {
+		item := raw.(map[string]any)
+		text, ok := item["content_text"]
+		if item["id"] == "b1" {
+			if ok {
+				t.Fatalf("failed item carries content_text: %v", item)
+			}
+			continue
+		}
+		if text != "Body of "+item["id"].(string) {
+			t.Fatalf("item %v content_text = %v", item["id"], text)
+		}
+	}
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("peak concurrent content fetches = %d, want at most 2", p)
+	}
+}
@@ -0,0 +1,376 @@
+package mcp
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akrisanov/readeck-mcp/internal/citation"
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+const (
+	feedPageSize      = 200
+	maxFeedHighlights = 5000
+	maxFeedBookmarks  = 5000
+	defaultFeedLimit  = 100
+	maxCitedResults   = 50
+)
+
+// exportAnki renders highlights as front<TAB>back rows: the quote, then the
+// note or, when there is none, an APA citation of the source bookmark. Rows
+// whose source bookmark cannot be loaded are skipped and counted; partial
+// reports that the global feed stopped at maxFeedHighlights or that ctx was
+// about to expire before every citation was fetched.
+func (s *Server) exportAnki(ctx context.Context, bookmarkID string) (map[string]any, error) {
+	var highlights []readeck.Highlight
+	bookmarks := map[string]readeck.Bookmark{}
+	partial := false
+
+	if bookmarkID != "" {
+		bookmark, err := s.client.GetBookmark(ctx, bookmarkID, readeck.IncludeOptions{Highlights: true})
+		if err != nil {
+			return nil, err
+		}
+		bookmarks[bookmarkID] = bookmark
+		highlights = bookmark.Highlights
+	} else {
+		all, capped, err := s.allHighlights(ctx)
+		if err != nil {
+			return nil, err
+		}
+		highlights = all
+		partial = capped
+	}
+
+	accessedAt := time.Now().UTC()
+	failed := map[string]bool{}
+	var b strings.Builder
+	count := 0
+	skipped := 0
+	for _, h := range highlights {
+		front := ankiField(h.Text)
+		if front == "" {
+			continue
+		}
+		back := ankiField(h.Note)
+		if back == "" && readeck.NearDeadline(ctx, s.cfg.DeadlineSlack) {
+			partial = true
+			break
+		}
+		if back == "" {
+			id := h.BookmarkID
+			if id == "" {
+				id = bookmarkID
+			}
+			bookmark, ok := bookmarks[id]
+			if !ok && !failed[id] {
+				fetched, err := s.client.GetBookmark(ctx, id, readeck.IncludeOptions{})
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil, err
+					}
+					s.logger.Printf("anki export highlight=%s bookmark=%s: %v", h.ID, id, err)
+					failed[id] = true
+				} else {
+					bookmark, ok = fetched, true
+					bookmarks[id] = bookmark
+				}
+			}
+			if !ok {
+				skipped++
+				continue
+			}
+			back = ankiField(citation.Generate(bookmark, &h, "", readeck.StyleAPA, accessedAt, citation.Options{}).Text)
+		}
+		b.WriteString(front)
+		b.WriteByte('\t')
+		b.WriteString(back)
+		b.WriteByte('\n')
+		count++
+	}
+	return map[string]any{"tsv": b.String(), "count": count, "skipped": skipped, "partial": partial}, nil
+}
+
+// allHighlights walks the global highlight feed, stopping after
+// maxFeedHighlights entries or when ctx is about to expire, and reporting
+// whether it stopped early.
+func (s *Server) allHighlights(ctx context.Context) ([]readeck.Highlight, bool, error) {
+	var out []readeck.Highlight
+	offset := 0
+	for len(out) < maxFeedHighlights {
+		page, err := s.client.ListHighlights(ctx, "", feedPageSize, offset)
+		if err != nil {
+			return nil, false, err
+		}
+		out = append(out, page.Highlights...)
+		if len(page.Highlights) == 0 {
+			break
+		}
+		next, ok := parseNonNegativeInt(page.NextCursor)
+		if !ok {
+			if len(page.Highlights) < feedPageSize {
+				break
+			}
+			next = offset + len(page.Highlights)
+		}
+		if next <= offset {
+			break
+		}
+		if readeck.NearDeadline(ctx, s.cfg.DeadlineSlack) {
+			return out, true, nil
+		}
+		offset = next
+	}
+	if len(out) >= maxFeedHighlights {
+		return out[:maxFeedHighlights], true, nil
+	}
+	return out, false, nil
+}
+
+// highlightsSince returns highlights created or edited after since, oldest
+// first. next_since is the newest timestamp seen, ready for the next call.
+func (s *Server) highlightsSince(ctx context.Context, since time.Time) (map[string]any, error) {
+	all, partial, err := s.allHighlights(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type stamped struct {
+		highlight readeck.Highlight
+		at        time.Time
+	}
+	matched := make([]stamped, 0)
+	for _, h := range all {
+		at, ok := highlightChangedAt(h)
+		if !ok || !at.After(since) {
+			continue
+		}
+		matched = append(matched, stamped{highlight: h, at: at})
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].at.Before(matched[j].at)
+	})
+
+	out := make([]readeck.Highlight, 0, len(matched))
+	newest := since
+	for _, m := range matched {
+		out = append(out, m.highlight)
+		newest = m.at
+	}
+	return map[string]any{
+		"highlights": out,
+		"next_since": newest.Format(time.RFC3339Nano),
+		"partial":    partial,
+	}, nil
+}
+
+func highlightChangedAt(h readeck.Highlight) (time.Time, bool) {
+	return changedAt(h.CreatedAt, h.UpdatedAt)
+}
+
+// changedAt returns the later of the parseable timestamps.
+func changedAt(stamps ...string) (time.Time, bool) {
+	var latest time.Time
+	for _, raw := range stamps {
+		if t, err := parseHighlightTimestamp(raw); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// bookmarksChangedSince returns up to limit bookmarks created or updated after
+// since, oldest first, scanning archived ones too. When upstream sorts by
+// update time the scan stops at the first older bookmark; otherwise it walks
+// up to maxFeedBookmarks, warns that it could not stop early, and reports
+// partial if it hits that cap.
+func (s *Server) bookmarksChangedSince(ctx context.Context, since time.Time, limit int) (map[string]any, error) {
+	if limit <= 0 {
+		limit = defaultFeedLimit
+	}
+	opts := readeck.SearchOptions{
+		Archived:   readeck.ArchivedInclude,
+		Sort:       readeck.SortUpdatedDesc,
+		Limit:      feedPageSize,
+		NoSnippets: true,
+	}
+	sorted := s.client.SortsUpstream(opts.Sort)
+
+	type stamped struct {
+		bookmark readeck.BookmarkSummary
+		at       time.Time
+	}
+	var matched []stamped
+	scanned := 0
+	partial := false
+	for {
+		page, err := s.client.Search(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		older := false
+		for _, b := range page.Items {
+			at, ok := changedAt(b.CreatedAt, b.UpdatedAt)
+			if !ok || !at.After(since) {
+				older = true
+				continue
+			}
+			matched = append(matched, stamped{bookmark: b, at: at})
+		}
+		scanned += len(page.Items)
+		if (sorted && older) || page.NextCursor == "" || page.NextCursor == opts.Cursor || len(page.Items) == 0 {
+			break
+		}
+		if scanned >= maxFeedBookmarks || readeck.NearDeadline(ctx, s.cfg.DeadlineSlack) {
+			partial = true
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].at.Before(matched[j].at)
+	})
+	// next_since is exclusive, so a page never ends partway through bookmarks
+	// sharing a timestamp: the whole group is held back for the next call, or
+	// returned in full when it alone exceeds limit.
+	if len(matched) > limit {
+		cut := limit
+		for cut > 0 && matched[cut-1].at.Equal(matched[limit].at) {
+			cut--
+		}
+		if cut == 0 {
+			cut = limit
+			for cut < len(matched) && matched[cut].at.Equal(matched[limit].at) {
+				cut++
+			}
+		}
+		if cut < len(matched) {
+			partial = true
+		}
+		matched = matched[:cut]
+	}
+	out := make([]readeck.BookmarkSummary, 0, len(matched))
+	newest := since
+	for _, m := range matched {
+		out = append(out, m.bookmark)
+		newest = m.at
+	}
+	result := map[string]any{
+		"items":      out,
+		"next_since": newest.Format(time.RFC3339Nano),
+		"partial":    partial,
+	}
+	if !sorted {
+		result["warning"] = "upstream does not sort by update time, so the scan could not stop at the first unchanged bookmark; map updated_desc in READECK_SORT_MAP to enable that"
+	}
+	return result, nil
+}
+
+// ankiField flattens a value for a TSV cell. Anki's importer allows HTML, so
+// line breaks become <br> and tabs become spaces.
+func ankiField(v string) string {
+	v = strings.TrimSpace(v)
+	v = strings.ReplaceAll(v, "\r\n", "\n")
+	v = strings.ReplaceAll(v, "\r", "\n")
+	v = strings.ReplaceAll(v, "\n", "<br>")
+	return strings.ReplaceAll(v, "\t", " ")
+}
+
+var sampleCitationBookmark = readeck.Bookmark{
+	ID:          "sample",
+	URL:         "https://example.com/articles/sample",
+	Title:       "An Example Article",
+	SiteName:    "Example",
+	Author:      "Jane Doe",
+	PublishedAt: "2024-03-15",
+}
+
+var sampleAccessedAt = time.Date(2025, time.January, 2, 0, 0, 0, 0, time.UTC)
+
+// citeSearch cites each search result from its summary metadata, capping the
+// page at maxCitedResults.
+func (s *Server) citeSearch(ctx context.Context, opts readeck.SearchOptions, style readeck.CitationStyle, accessedAt time.Time) (map[string]any, error) {
+	if opts.Limit <= 0 || opts.Limit > maxCitedResults {
+		opts.Limit = maxCitedResults
+	}
+	opts.NoSnippets = true
+	result, err := s.client.Search(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	citations := make([]readeck.Citation, 0, len(result.Items))
+	for _, item := range result.Items {
+		bookmark := readeck.Bookmark{
+			ID:          item.ID,
+			URL:         item.URL,
+			Title:       item.Title,
+			PublishedAt: item.PublishedAt,
+		}
+		citations = append(citations, citation.Generate(bookmark, nil, "", style, accessedAt, citation.Options{CompactJSON: s.cfg.CompactJSON}))
+	}
+	out := map[string]any{"citations": citations}
+	if result.NextCursor != "" {
+		out["next_cursor"] = result.NextCursor
+	}
+	return out, nil
+}
+
+// bibliography resolves ids, or the first page of a search, to full bookmarks
+// so entries carry author and site, then formats them as one list.
+func (s *Server) bibliography(ctx context.Context, ids []string, opts readeck.SearchOptions, style readeck.CitationStyle, accessedAt time.Time) (map[string]any, error) {
+	if len(ids) == 0 {
+		if opts.Limit <= 0 || opts.Limit > maxCitedResults {
+			opts.Limit = maxCitedResults
+		}
+		opts.NoSnippets = true
+		result, err := s.client.Search(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			ids = append(ids, item.ID)
+		}
+	}
+
+	bookmarks := make([]readeck.Bookmark, 0, len(ids))
+	for _, id := range ids {
+		if strings.TrimSpace(id) == "" {
+			continue
+		}
+		bookmark, err := s.client.GetBookmark(ctx, id, readeck.IncludeOptions{})
+		if err != nil {
+			return nil, err
+		}
+		bookmarks = append(bookmarks, bookmark)
+	}
+	text, duplicates := citation.Bibliography(bookmarks, style, accessedAt, citation.Options{CompactJSON: s.cfg.CompactJSON})
+	return map[string]any{
+		"bibliography": text,
+		"count":        len(bookmarks) - duplicates,
+		"duplicates":   duplicates,
+	}, nil
+}
+
+func citationStyles(compact bool) []map[string]any {
+	out := make([]map[string]any, 0, len(readeck.CitationStyles))
+	for _, style := range readeck.CitationStyles {
+		sample := citation.Generate(sampleCitationBookmark, nil, "", style, sampleAccessedAt, citation.Options{CompactJSON: compact})
+		out = append(out, map[string]any{
+			"style":  style,
+			"label":  citation.Label(style),
+			"sample": sample.Text,
+		})
+	}
+	return out
+}
+
+func citationStyleNames() []string {
+	out := make([]string, 0, len(readeck.CitationStyles))
+	for _, style := range readeck.CitationStyles {
+		out = append(out, string(style))
+	}
+	return out
+}
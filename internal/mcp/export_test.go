@@ -0,0 +1,310 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+func changedBookmark(id, updated string) map[string]any {
+	return map[string]any{"id": id, "title": id, "url": "https://example.com/" + id, "created": "2020-01-01T00:00:00Z", "updated": updated}
+}
+
+func feedUpstream(t *testing.T, pages map[string]map[string]any, requested *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		*requested = append(*requested, cursor)
+		writeJSON(t, w, pages[cursor])
+	}
+}
+
+var changedPages = map[string]map[string]any{
+	"": {"items": []any{
+		changedBookmark("c", "2024-03-03T00:00:00Z"),
+		changedBookmark("b", "2024-03-02T00:00:00Z"),
+	}, "next_cursor": "p2"},
+	"p2": {"items": []any{
+		changedBookmark("a", "2024-03-01T00:00:00Z"),
+		changedBookmark("old", "2024-01-01T00:00:00Z"),
+	}, "next_cursor": "p3"},
+	"p3": {"items": []any{
+		changedBookmark("older", "2023-01-01T00:00:00Z"),
+	}},
+}
+
+func TestChangedSinceStopsAtOlderResultsWhenSortedUpstream(t *testing.T) {
+	var requested []string
+	s := newTestServer(t, feedUpstream(t, changedPages, &requested))
+
+	out := mustCallTool(t, s, "readeck.changed_since", `{"since":"2024-02-01T00:00:00Z"}`)
+	if got, want := itemIDs(t, out, "items"), []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("items = %v, want %v", got, want)
+	}
+	if !slices.Equal(requested, []string{"", "p2"}) {
+		t.Fatalf("pages requested = %q, want the scan to stop at p2", requested)
+	}
+	if out["next_since"] != "2024-03-03T00:00:00Z" || out["partial"] != false {
+		t.Fatalf("next_since = %v partial = %v", out["next_since"], out["partial"])
+	}
+	if _, ok := out["warning"]; ok {
+		t.Fatalf("unexpected warning: %v", out["warning"])
+	}
+}
+
+func TestChangedSinceWarnsWhenUnsortedUpstream(t *testing.T) {
+	var requested []string
+	s := newTestServer(t, feedUpstream(t, changedPages, &requested), "READECK_SORT_MAP", "updated_desc=")
+
+	out := mustCallTool(t, s, "readeck.changed_since", `{"since":"2024-02-01T00:00:00Z"}`)
+	if got, want := itemIDs(t, out, "items"), []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Fatalf("items = %v, want %v", got, want)
+	}
+	if len(requested) != 3 {
+		t.Fatalf("pages requested = %q, want all three", requested)
+	}
+	if out["warning"] == nil {
+		t.Fatal("missing warning about the unsorted scan")
+	}
+}
+
+func TestChangedSinceKeepsTimestampGroupsTogether(t *testing.T) {
+	pages := map[string]map[string]any{"": {"items": []any{
+		changedBookmark("t2a", "2024-03-02T00:00:00Z"),
+		changedBookmark("t2b", "2024-03-02T00:00:00Z"),
+		changedBookmark("t2c", "2024-03-02T00:00:00Z"),
+		changedBookmark("t1", "2024-03-01T00:00:00Z"),
+	}}}
+	var requested []string
+	s := newTestServer(t, feedUpstream(t, pages, &requested))
+
+	first := mustCallTool(t, s, "readeck.changed_since", `{"since":"2024-02-01T00:00:00Z","limit":2}`)
+	if got := itemIDs(t, first, "items"); !slices.Equal(got, []string{"t1"}) {
+		t.Fatalf("first page = %v, want [t1]", got)
+	}
+	if first["partial"] != true {
+		t.Fatalf("partial = %v, want true", first["partial"])
+	}
+
+	second := mustCallTool(t, s, "readeck.changed_since", `{"since":"`+first["next_since"].(string)+`","limit":2}`)
+	if got := itemIDs(t, second, "items"); !slices.Equal(got, []string{"t2a", "t2b", "t2c"}) {
+		t.Fatalf("second page = %v, want the whole t2 group", got)
+	}
+}
+
+func TestExportAnkiEscapesAndFallsBackToCitation(t *testing.T) {
+	up := upstream{
+		bookmarks: map[string]map[string]any{
+			"b1": {"id": "b1", "title": "Source", "url": "https://example.com/s", "author": "Jane Doe"},
+		},
+		highlights: map[string][]map[string]any{
+			"b1": {
+				{"id": "h1", "text": "tab\there\nnewline", "note": "my\tnote"},
+				{"id": "h2", "text": "no note"},
+			},
+		},
+	}
+	s := newTestServer(t, up.handler(t))
+
+	out := mustCallTool(t, s, "readeck.export.anki", `{"bookmark_id":"b1"}`)
+	rows := strings.Split(strings.TrimSuffix(out["tsv"].(string), "\n"), "\n")
+	if len(rows) != 2 || out["count"] != float64(2) {
+		t.Fatalf("rows = %q count = %v", rows, out["count"])
+	}
+	if rows[0] != "tab here<br>newline\tmy note" {
+		t.Fatalf("escaped row = %q", rows[0])
+	}
+	front, back, _ := strings.Cut(rows[1], "\t")
+	if front != "no note" || !strings.Contains(back, "Doe") || !strings.Contains(back, "https://example.com/s") {
+		t.Fatalf("citation fallback row = %q", rows[1])
+	}
+}
+
+func TestExportAnkiSkipsRowsWithoutSourceBookmark(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bookmarks/annotations":
+			if r.URL.Query().Get("offset") != "0" {
+				writeJSON(t, w, map[string]any{"items": []any{}})
+				return
+			}
+			writeJSON(t, w, map[string]any{"items": []any{
+				map[string]any{"id": "h1", "bookmark_id": "gone", "text": "deleted source"},
+				map[string]any{"id": "h2", "text": "no bookmark id"},
+				map[string]any{"id": "h3", "bookmark_id": "gone", "text": "with note", "note": "kept"},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	out := mustCallTool(t, s, "readeck.export.anki", `{}`)
+	if out["tsv"] != "with note\tkept\n" || out["count"] != float64(1) {
+		t.Fatalf("tsv = %q count = %v", out["tsv"], out["count"])
+	}
+	if out["skipped"] != float64(2) || out["partial"] != false {
+		t.Fatalf("skipped = %v partial = %v, want 2 and false", out["skipped"], out["partial"])
+	}
+}
+
+func TestExportAnkiReportsPartialFeed(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		items := make([]any, 0, feedPageSize)
+		for i := range feedPageSize {
+			items = append(items, map[string]any{"id": "h" + strconv.Itoa(i), "text": "quote", "note": "note"})
+		}
+		writeJSON(t, w, map[string]any{"items": items})
+	})
+
+	out := mustCallTool(t, s, "readeck.export.anki", `{}`)
+	if out["partial"] != true || out["count"] != float64(maxFeedHighlights) {
+		t.Fatalf("partial = %v count = %v", out["partial"], out["count"])
+	}
+}
+
+func TestBatchToolsReturnPartialNearDeadline(t *testing.T) {
+	calls := 0
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		cursor := r.URL.Query().Get("cursor")
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		switch r.URL.Path {
+		case "/api/bookmarks":
+			writeJSON(t, w, map[string]any{
+				"items":       []any{changedBookmark("b"+cursor, "2024-03-01T00:00:00Z")},
+				"next_cursor": cursor + "n",
+			})
+		case "/api/bookmarks/annotations":
+			items := make([]any, 0, feedPageSize)
+			for i := range feedPageSize {
+				items = append(items, map[string]any{"id": "h" + strconv.Itoa(offset+i), "text": "quote", "note": "note", "created": "2024-03-01T00:00:00Z"})
+			}
+			writeJSON(t, w, map[string]any{"items": items})
+		default:
+			http.NotFound(w, r)
+		}
+	}, "MCP_DEADLINE_SLACK_MS", "5000")
+
+	for _, tc := range []struct{ tool, args string }{
+		{"readeck.changed_since", `{"since":"2024-01-01T00:00:00Z"}`},
+		{"readeck.highlights.since", `{"since":"2024-01-01T00:00:00Z"}`},
+		{"readeck.export.anki", `{}`},
+	} {
+		calls = 0
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		result, err := s.executeTool(ctx, tc.tool, json.RawMessage(tc.args))
+		cancel()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.tool, err)
+		}
+		if partial := result.(map[string]any)["partial"]; partial != true {
+			t.Fatalf("%s partial = %v, want true", tc.tool, partial)
+		}
+		if calls != 1 {
+			t.Fatalf("%s made %d upstream calls, want 1", tc.tool, calls)
+		}
+	}
+}
+
+func TestCiteStylesCoversEveryStyle(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected upstream request %s", r.URL)
+	})
+
+	out := mustCallTool(t, s, "readeck.cite.styles", `{}`)
+	var got []string
+	for _, item := range out["styles"].([]any) {
+		style := item.(map[string]any)
+		if style["label"] == "" || style["sample"] == "" {
+			t.Errorf("style %v has an empty label or sample", style["style"])
+		}
+		got = append(got, style["style"].(string))
+	}
+	var want []string
+	for _, style := range readeck.CitationStyles {
+		want = append(want, string(style))
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("styles = %v, want %v", got, want)
+	}
+}
+
+func TestHighlightsSinceFetchesIncrementally(t *testing.T) {
+	items := []map[string]any{
+		{"id": "edited", "text": "q", "created": "2024-01-01T00:00:00Z", "updated": "2024-03-05T00:00:00Z"},
+		{"id": "new", "text": "q", "created": "2024-03-02T00:00:00Z"},
+		{"id": "old", "text": "q", "created": "2024-01-01T00:00:00Z"},
+		{"id": "undated", "text": "q"},
+	}
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		highlightFeed(t, items, nil)(w, r)
+	})
+
+	first := mustCallTool(t, s, "readeck.highlights.since", `{"since":"2024-02-01T00:00:00Z"}`)
+	if got := itemIDs(t, first, "highlights"); !slices.Equal(got, []string{"new", "edited"}) {
+		t.Fatalf("first sync = %v, want [new edited]", got)
+	}
+	if first["next_since"] != "2024-03-05T00:00:00Z" || first["partial"] != false {
+		t.Fatalf("next_since = %v partial = %v", first["next_since"], first["partial"])
+	}
+
+	items = append(items, map[string]any{"id": "later", "text": "q", "created": "2024-04-01T00:00:00Z"})
+	second := mustCallTool(t, s, "readeck.highlights.since", `{"since":"`+first["next_since"].(string)+`"}`)
+	if got := itemIDs(t, second, "highlights"); !slices.Equal(got, []string{"later"}) {
+		t.Fatalf("second sync = %v, want [later]", got)
+	}
+
+	third := mustCallTool(t, s, "readeck.highlights.since", `{"since":"`+second["next_since"].(string)+`"}`)
+	if got := itemIDs(t, third, "highlights"); len(got) != 0 || third["next_since"] != second["next_since"] {
+		t.Fatalf("third sync = %v next_since = %v", got, third["next_since"])
+	}
+}
+
+func TestCiteSearchCitesEachResult(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if limit, _ := strconv.Atoi(r.URL.Query().Get("limit")); limit > maxCitedResults {
+			t.Errorf("search limit = %d, want at most %d", limit, maxCitedResults)
+		}
+		writeJSON(t, w, map[string]any{"items": []any{
+			map[string]any{"id": "a", "title": "First Post", "url": "https://example.com/a"},
+			map[string]any{"id": "b", "title": "Second Post", "url": "https://example.com/b", "published": "2024-03-15"},
+			map[string]any{"id": "c", "title": "Third Post", "url": "https://example.com/c"},
+		}})
+	})
+
+	out := mustCallTool(t, s, "readeck.cite.search", `{"style":"mla","accessed_at":"2025-01-02T00:00:00Z"}`)
+	citations := out["citations"].([]any)
+	if len(citations) != 3 {
+		t.Fatalf("citations = %d, want one per result", len(citations))
+	}
+	for i, title := range []string{"First Post", "Second Post", "Third Post"} {
+		c := citations[i].(map[string]any)
+		if c["style"] != "mla" || !strings.Contains(c["text"].(string), `"`+title+`."`) {
+			t.Errorf("citation %d = %v", i, c)
+		}
+	}
+}
+
+func TestBibliographyCollapsesDuplicateURLs(t *testing.T) {
+	up := upstream{bookmarks: map[string]map[string]any{
+		"a":     {"id": "a", "title": "Alpha", "url": "https://example.com/a"},
+		"b":     {"id": "b", "title": "Beta", "url": "https://example.com/b"},
+		"again": {"id": "again", "title": "Alpha again", "url": "https://example.com/a/"},
+	}}
+	s := newTestServer(t, up.handler(t))
+
+	out := mustCallTool(t, s, "readeck.bibliography", `{"ids":["a","again","b"],"style":"ieee","accessed_at":"2025-01-02T00:00:00Z"}`)
+	text := out["bibliography"].(string)
+	if out["count"] != float64(2) || out["duplicates"] != float64(1) {
+		t.Fatalf("count = %v duplicates = %v", out["count"], out["duplicates"])
+	}
+	if !strings.HasPrefix(text, `[1] "Alpha."`) || !strings.Contains(text, "\n[2] \"Beta.\"") || strings.Contains(text, "again") {
+		t.Fatalf("bibliography:\n%s", text)
+	}
+}
@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// highlightFeed serves items as the global highlight feed, honouring limit
+// and offset, and counts the requests it answers.
+func highlightFeed(t *testing.T, items []map[string]any, requests *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/bookmarks/annotations" {
+			http.NotFound(w, r)
+			return
+		}
+		if requests != nil {
+			*requests++
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		start, end := min(offset, len(items)), min(offset+limit, len(items))
+		writeJSON(t, w, map[string]any{"items": items[start:end]})
+	}
+}
+
+func TestHighlightColorsAggregatesDistinctColors(t *testing.T) {
+	items := []map[string]any{
+		{"id": "h1", "color": "Yellow"},
+		{"id": "h2", "color": "purple"},
+		{"id": "h3"},
+		{"id": "h4", "color": " PURPLE "},
+		{"id": "h5", "color": "orange"},
+	}
+	s := newTestServer(t, highlightFeed(t, items, nil))
+
+	out := mustCallTool(t, s, "readeck.highlights.colors", `{}`)
+	var got []string
+	for _, c := range out["colors"].([]any) {
+		got = append(got, c.(string))
+	}
+	if want := []string{"yellow", "red", "blue", "green", "purple", "orange"}; !slices.Equal(got, want) {
+		t.Fatalf("colors = %v, want %v", got, want)
+	}
+}
+
+func TestHighlightColorsCapsTheScan(t *testing.T) {
+	items := make([]map[string]any, 0, 3*maxColorScan)
+	for i := range 3 * maxColorScan {
+		items = append(items, map[string]any{"id": fmt.Sprint("h", i), "color": fmt.Sprint("c", i)})
+	}
+	s := newTestServer(t, highlightFeed(t, items, nil))
+
+	out := mustCallTool(t, s, "readeck.highlights.colors", `{}`)
+	if n := len(out["colors"].([]any)); n != len(knownHighlightColors)+maxColorScan {
+		t.Fatalf("%d colors, want the scan capped at %d highlights", n, maxColorScan)
+	}
+}
+
+func TestHighlightsDigestTool(t *testing.T) {
+	up := articleUpstream()
+	up.highlights = map[string][]map[string]any{"b1": {
+		{"id": "h1", "text": "noted", "note": "a note"},
+		{"id": "h2", "text": "plain"},
+	}}
+	s := newTestServer(t, up.handler(t))
+
+	out := mustCallTool(t, s, "readeck.highlights.digest", `{"bookmark_id":"b1"}`)
+	md, _ := out["markdown"].(string)
+	if !strings.HasPrefix(md, "# Highlight digest: Article\n") || !strings.Contains(md, "> plain\n") || !strings.Contains(md, "- a note (on \"noted\")\n") {
+		t.Fatalf("markdown:\n%s", md)
+	}
+
+	_, err := callTool(t, s, "readeck.highlights.digest", `{}`)
+	assertInputError(t, err, "bookmark_id is required")
+}
+
+func TestDateFilteredHighlightsStopAtScanCap(t *testing.T) {
+	items := make([]map[string]any, 0, 1000)
+	for i := range 1000 {
+		created := "2023-06-01T00:00:00Z"
+		if i == 50 {
+			created = "2024-03-01T00:00:00Z"
+		}
+		items = append(items, map[string]any{"id": "h" + strconv.Itoa(i), "text": "quote", "created": created})
+	}
+	requests := 0
+	s := newTestServer(t, highlightFeed(t, items, &requests), "READECK_HIGHLIGHT_SCAN_CAP", "300")
+
+	out := mustCallTool(t, s, "readeck.highlights.list", `{"date_from":"2024-01-01","limit":10}`)
+	if out["partial"] != true {
+		t.Fatalf("partial = %v, want true", out["partial"])
+	}
+	if got := itemIDs(t, out, "highlights"); !slices.Equal(got, []string{"h50"}) {
+		t.Fatalf("highlights = %v, want [h50]", got)
+	}
+	if requests != 3 {
+		t.Fatalf("feed requests = %d, want 3 pages of 100 before the cap", requests)
+	}
+}
+
+func TestFilteredHighlightsCursorResumesTheScan(t *testing.T) {
+	items := make([]map[string]any, 0, 1000)
+	for i := range 1000 {
+		created := "2023-06-01T00:00:00Z"
+		if i == 50 || i == 60 || i == 450 || i == 900 {
+			created = "2024-03-01T00:00:00Z"
+		}
+		items = append(items, map[string]any{"id": "h" + strconv.Itoa(i), "text": "quote", "created": created})
+	}
+	s := newTestServer(t, highlightFeed(t, items, nil), "READECK_HIGHLIGHT_SCAN_CAP", "300")
+
+	var got, cursors []string
+	offset := "0"
+	for range 10 {
+		out := mustCallTool(t, s, "readeck.highlights.list", `{"date_from":"2024-01-01","limit":1,"offset":`+offset+`}`)
+		got = append(got, itemIDs(t, out, "highlights")...)
+		next, _ := out["next_cursor"].(string)
+		if next == "" {
+			break
+		}
+		if next == offset {
+			t.Fatalf("next_cursor %s did not advance", next)
+		}
+		cursors = append(cursors, next)
+		offset = next
+	}
+	if want := []string{"h50", "h60", "h450", "h900"}; !slices.Equal(got, want) {
+		t.Fatalf("highlights across pages = %v, want %v (cursors %v)", got, want, cursors)
+	}
+	if want := []string{"60", "360", "660", "960"}; !slices.Equal(cursors, want) {
+		t.Fatalf("cursors = %v, want %v", cursors, want)
+	}
+}
+
+func TestHighlightsListQueryMatchesTextAndNote(t *testing.T) {
+	items := []map[string]any{
+		{"id": "text", "text": "Goroutines are CHEAP", "created": "2024-03-01T00:00:00Z"},
+		{"id": "note", "text": "quote", "note": "cheap threads", "created": "2024-03-02T00:00:00Z"},
+		{"id": "old", "text": "cheap but old", "created": "2023-01-01T00:00:00Z"},
+		{"id": "miss", "text": "expensive", "note": "costly", "created": "2024-03-03T00:00:00Z"},
+	}
+	s := newTestServer(t, highlightFeed(t, items, nil))
+
+	out := mustCallTool(t, s, "readeck.highlights.list", `{"query":"cheap"}`)
+	if got := itemIDs(t, out, "highlights"); !slices.Equal(got, []string{"text", "note", "old"}) {
+		t.Fatalf("query only = %v", got)
+	}
+	out = mustCallTool(t, s, "readeck.highlights.list", `{"query":" Cheap ","date_from":"2024-01-01"}`)
+	if got := itemIDs(t, out, "highlights"); !slices.Equal(got, []string{"text", "note"}) {
+		t.Fatalf("query with date = %v", got)
+	}
+}
@@ -2,34 +2,118 @@ package mcp
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/akrisanov/readeck-mcp/internal/readeck"
-	"github.com/akrisanov/readeck-mcp/internal/render"
 )
 
-const maxHTTPBodySize = 1 << 20
+const sessionHeader = "Mcp-Session-Id"
+
+const (
+	// sessionIdleTTL drops sessions that have not been used for this long.
+	sessionIdleTTL = 30 * time.Minute
+	// maxSessions bounds the store; creating one more evicts the session
+	// that was used least recently.
+	maxSessions = 1000
+)
+
+// sessionStore tracks the session ids issued by initialize under
+// MCP_STRICT_INIT, with the time each was last used.
+type sessionStore struct {
+	mu      sync.Mutex
+	ids     map[string]time.Time
+	idleTTL time.Duration
+	max     int
+	now     func() time.Time
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{ids: map[string]time.Time{}, idleTTL: sessionIdleTTL, max: maxSessions, now: time.Now}
+}
+
+func (s *sessionStore) create() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for existing, seen := range s.ids {
+		if now.Sub(seen) > s.idleTTL {
+			delete(s.ids, existing)
+		}
+	}
+	for len(s.ids) >= s.max {
+		oldest, oldestSeen := "", now
+		for existing, seen := range s.ids {
+			if oldest == "" || seen.Before(oldestSeen) {
+				oldest, oldestSeen = existing, seen
+			}
+		}
+		delete(s.ids, oldest)
+	}
+	s.ids[id] = now
+	return id, nil
+}
+
+// has reports whether id is a live session and marks it as used.
+func (s *sessionStore) has(id string) bool {
+	if id == "" {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen, ok := s.ids[id]
+	if !ok {
+		return false
+	}
+	now := s.now()
+	if now.Sub(seen) > s.idleTTL {
+		delete(s.ids, id)
+		return false
+	}
+	s.ids[id] = now
+	return true
+}
+
+func (s *sessionStore) remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[id]; !ok {
+		return false
+	}
+	delete(s.ids, id)
+	return true
+}
 
 func (s *Server) RunHTTP(ctx context.Context) error {
 	mux := http.NewServeMux()
-	mux.HandleFunc(s.cfg.HTTPPath, s.handleHTTPMCP)
+	mux.Handle(s.httpRoute(s.cfg.HTTPPath), s.httpHandler())
 
-	httpServer := &http.Server{
-		Addr:              s.cfg.HTTPAddr,
-		Handler:           mux,
-		ReadHeaderTimeout: 10 * time.Second,
-	}
+	httpServer := s.newHTTPServer(mux)
 
 	errCh := make(chan error, 1)
 	go func() {
-		err := httpServer.ListenAndServe()
+		var err error
+		if s.cfg.HTTPTLSCert != "" {
+			err = httpServer.ListenAndServeTLS(s.cfg.HTTPTLSCert, s.cfg.HTTPTLSKey)
+		} else {
+			err = httpServer.ListenAndServe()
+		}
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- err
 			return
@@ -49,8 +133,33 @@ func (s *Server) RunHTTP(ctx context.Context) error {
 	}
 }
 
+func (s *Server) newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              s.cfg.HTTPAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       s.cfg.HTTPReadTimeout,
+		WriteTimeout:      s.cfg.HTTPWriteTimeout,
+		IdleTimeout:       s.cfg.HTTPIdleTimeout,
+		TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+}
+
+// httpRoute prefixes path with MCP_HTTP_BASE_PATH. Every route registered
+// in RunHTTP should go through it.
+func (s *Server) httpRoute(path string) string {
+	return s.cfg.HTTPBasePath + path
+}
+
 func (s *Server) handleHTTPMCP(w http.ResponseWriter, r *http.Request) {
-	if !s.isHTTPAuthorized(r) {
+	if !s.allowHTTPRequest(w, r) {
+		return
+	}
+	scope, ok := s.httpScope(r)
+	if !ok {
+		if s.cfg.HTTPBasicUser != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="readeck-mcp"`)
+		}
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -61,22 +170,34 @@ func (s *Server) handleHTTPMCP(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodPost:
-		s.handleHTTPPost(w, r)
-	case http.MethodGet, http.MethodDelete:
+		s.handleHTTPPost(w, r, scope)
+	case http.MethodDelete:
+		s.handleHTTPDelete(w, r)
+	case http.MethodGet:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
 
-func (s *Server) handleHTTPPost(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleHTTPPost(w http.ResponseWriter, r *http.Request, scope string) {
 	if !acceptsRPCResponse(r.Header.Get("Accept")) {
 		http.Error(w, "not acceptable", http.StatusNotAcceptable)
 		return
 	}
 
-	body, err := io.ReadAll(io.LimitReader(r.Body, maxHTTPBodySize))
+	limit := s.cfg.HTTPMaxBodyBytes
+	if r.ContentLength > limit {
+		writeBodyTooLarge(w, limit)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeBodyTooLarge(w, limit)
+			return
+		}
 		http.Error(w, "read request body", http.StatusBadRequest)
 		return
 	}
@@ -91,6 +212,10 @@ func (s *Server) handleHTTPPost(w http.ResponseWriter, r *http.Request) {
 
 	var req rpcRequest
 	if err := json.Unmarshal(body, &req); err != nil {
+		if json.Valid(body) {
+			writeHTTPRPCResponse(w, rpcResponse{JSONRPC: "2.0", ID: recoverRequestID(body), Error: &rpcError{Code: -32600, Message: "invalid request"}})
+			return
+		}
 		writeHTTPRPCResponse(w, rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: -32700, Message: "parse error"}})
 		return
 	}
@@ -117,10 +242,56 @@ func (s *Server) handleHTTPPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.cfg.StrictInit && !allowedBeforeInit(req.Method) && !s.sessions.has(r.Header.Get(sessionHeader)) {
+		writeHTTPRPCResponse(w, rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: -32002, Message: "server not initialized"}})
+		return
+	}
+
+	if scope == scopeRead && requiresWriteScope(req) {
+		writeHTTPRPCResponse(w, rpcResponse{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: -32003, Message: "forbidden: token has read-only scope"}})
+		return
+	}
+
+	// r.Context() ends when the client disconnects, cancelling its upstream
+	// requests; a coalesced GET is cancelled once no other caller waits on it.
 	resp := s.executeRPCOverHTTP(r.Context(), req)
+	if s.cfg.StrictInit && req.Method == "initialize" && resp.Error == nil {
+		sessionID, err := s.sessions.create()
+		if err != nil {
+			http.Error(w, "create session", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(sessionHeader, sessionID)
+	}
 	writeHTTPRPCResponse(w, resp)
 }
 
+// handleHTTPDelete ends the session named by Mcp-Session-Id. Sessions only
+// exist under MCP_STRICT_INIT; without it there is nothing to end.
+func (s *Server) handleHTTPDelete(w http.ResponseWriter, r *http.Request) {
+	if !s.cfg.StrictInit {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	id := r.Header.Get(sessionHeader)
+	if id == "" {
+		http.Error(w, "missing "+sessionHeader, http.StatusBadRequest)
+		return
+	}
+	if !s.sessions.remove(id) {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeBodyTooLarge answers oversized bodies with 413 rather than letting
+// a truncated body surface as a JSON-RPC parse error.
+func writeBodyTooLarge(w http.ResponseWriter, limit int64) {
+	w.Header().Set("Connection", "close")
+	http.Error(w, fmt.Sprintf("request body exceeds %d bytes", limit), http.StatusRequestEntityTooLarge)
+}
+
 func (s *Server) executeRPCOverHTTP(ctx context.Context, req rpcRequest) rpcResponse {
 	requestID := req.idString()
 	ctx = readeck.WithRequestID(ctx, requestID)
@@ -134,30 +305,11 @@ func (s *Server) executeRPCOverHTTP(ctx context.Context, req rpcRequest) rpcResp
 	resp := rpcResponse{JSONRPC: "2.0", ID: req.ID}
 	switch req.Method {
 	case "initialize":
-		resp.Result = map[string]any{
-			"protocolVersion": s.cfg.Protocol,
-			"capabilities": map[string]any{
-				"tools":     map[string]any{},
-				"resources": map[string]any{"subscribe": false},
-				"prompts":   map[string]any{},
-			},
-			"serverInfo": map[string]any{
-				"name":    s.cfg.ServerName,
-				"version": s.cfg.ServerVersion,
-			},
-		}
+		resp.Result = s.initializeResult(req.Params, false)
 	case "ping":
 		resp.Result = map[string]any{}
 	case "tools/list":
-		resp.Result = map[string]any{"tools": []map[string]any{
-			{"name": "readeck.search", "description": "Search and filter bookmarks.", "inputSchema": searchInputSchema()},
-			{"name": "readeck.get", "description": "Fetch one bookmark with optional content and highlights.", "inputSchema": getInputSchema()},
-			{"name": "readeck.archive", "description": "Archive or unarchive a bookmark.", "inputSchema": archiveInputSchema()},
-			{"name": "readeck.labels.list", "description": "List all labels.", "inputSchema": labelsListInputSchema()},
-			{"name": "readeck.labels.set", "description": "Replace labels on a bookmark.", "inputSchema": labelsSetInputSchema()},
-			{"name": "readeck.highlights.list", "description": "List annotations/highlights globally or per bookmark, with optional date filtering.", "inputSchema": highlightsListInputSchema()},
-			{"name": "readeck.cite", "description": "Generate citations for a bookmark in multiple styles.", "inputSchema": citeInputSchema()},
-		}}
+		resp.Result = map[string]any{"tools": s.tools()}
 	case "tools/call":
 		var params toolCallParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -177,17 +329,11 @@ func (s *Server) executeRPCOverHTTP(ctx context.Context, req rpcRequest) rpcResp
 			break
 		}
 		resp.Result = map[string]any{
-			"content":           []map[string]any{{"type": "text", "text": mustJSON(result)}},
+			"content":           []map[string]any{{"type": "text", "text": mustJSON(result, s.cfg.CompactJSON)}},
 			"structuredContent": result,
 		}
 	case "resources/list", "resources/templates/list":
-		resp.Result = map[string]any{"resources": []map[string]any{
-			{"uriTemplate": "readeck://bookmark/{id}", "name": "Bookmark metadata", "mimeType": "application/json"},
-			{"uriTemplate": "readeck://bookmark/{id}/content.md", "name": "Bookmark content markdown", "mimeType": "text/markdown"},
-			{"uriTemplate": "readeck://bookmark/{id}/content.txt", "name": "Bookmark content text", "mimeType": "text/plain"},
-			{"uriTemplate": "readeck://bookmark/{id}/highlights.json", "name": "Bookmark highlights JSON", "mimeType": "application/json"},
-			{"uriTemplate": "readeck://bookmark/{id}/highlights.md", "name": "Bookmark highlights markdown", "mimeType": "text/markdown"},
-		}}
+		resp.Result = map[string]any{"resources": resourceTemplates()}
 	case "resources/read":
 		var params struct {
 			URI string `json:"uri"`
@@ -196,118 +342,26 @@ func (s *Server) executeRPCOverHTTP(ctx context.Context, req rpcRequest) rpcResp
 			resp.Error = &rpcError{Code: -32602, Message: "invalid params"}
 			break
 		}
-		if strings.TrimSpace(params.URI) == "" {
-			resp.Error = &rpcError{Code: -32602, Message: "uri is required"}
-			break
-		}
-		parsed, err := parseReadeckURI(params.URI)
-		if err != nil {
-			resp.Error = &rpcError{Code: -32602, Message: "invalid resource uri"}
-			break
-		}
-		bookmark, err := s.client.GetBookmark(ctx, parsed.ID, readeck.IncludeOptions{
-			Content:    parsed.Kind == "content.md" || parsed.Kind == "content.txt",
-			Highlights: parsed.Kind == "highlights.json" || parsed.Kind == "highlights.md",
-			Labels:     true,
-		})
-		if err != nil {
-			mapped := mapToolError(err)
-			resp.Error = &rpcError{Code: -32000, Message: mapped.Message, Data: map[string]any{"error": mapped}}
+		result, rpcErr := s.readResource(ctx, params.URI)
+		if rpcErr != nil {
+			resp.Error = rpcErr
 			break
 		}
-
-		mime := "application/json"
-		text := ""
-		switch parsed.Kind {
-		case "metadata":
-			data := bookmark
-			data.ContentText = ""
-			data.ContentHTML = ""
-			data.Highlights = nil
-			text = mustJSON(data)
-		case "content.md":
-			mime = "text/markdown"
-			text = render.BookmarkContentMarkdown(bookmark, false)
-		case "content.txt":
-			mime = "text/plain"
-			text = render.BookmarkContentText(bookmark)
-		case "highlights.json":
-			text = mustJSON(map[string]any{"highlights": bookmark.Highlights})
-		case "highlights.md":
-			mime = "text/markdown"
-			text = render.HighlightsMarkdown(bookmark.Highlights)
-		default:
-			resp.Error = &rpcError{Code: -32602, Message: "unsupported resource uri"}
-		}
-		if resp.Error == nil {
-			resp.Result = map[string]any{"contents": []map[string]any{{"uri": params.URI, "mimeType": mime, "text": text}}}
-		}
+		resp.Result = result
 	case "prompts/list":
-		resp.Result = map[string]any{"prompts": []map[string]any{
-			{
-				"name":        "readeck.prompt.summarize",
-				"description": "Summarize a bookmark with optional focus mode.",
-				"arguments": []map[string]any{
-					{"name": "bookmark_id", "required": true},
-					{"name": "focus", "required": false},
-				},
-			},
-			{
-				"name":        "readeck.prompt.flashcards",
-				"description": "Create flashcards from bookmark content/highlights.",
-				"arguments": []map[string]any{
-					{"name": "bookmark_id", "required": true},
-					{"name": "num_cards", "required": false},
-					{"name": "card_type", "required": false},
-					{"name": "use_highlights", "required": false},
-				},
-			},
-		}}
+		resp.Result = map[string]any{"prompts": promptDefinitions()}
 	case "prompts/get":
-		var params struct {
-			Name      string         `json:"name"`
-			Arguments map[string]any `json:"arguments"`
-		}
+		var params promptGetParams
 		if err := json.Unmarshal(req.Params, &params); err != nil {
 			resp.Error = &rpcError{Code: -32602, Message: "invalid params"}
 			break
 		}
-		bookmarkID, _ := params.Arguments["bookmark_id"].(string)
-		if strings.TrimSpace(bookmarkID) == "" {
-			resp.Error = &rpcError{Code: -32602, Message: "bookmark_id is required"}
+		result, rpcErr := buildPrompt(params)
+		if rpcErr != nil {
+			resp.Error = rpcErr
 			break
 		}
-		switch params.Name {
-		case "readeck.prompt.summarize":
-			focus, _ := params.Arguments["focus"].(string)
-			if focus == "" {
-				focus = "key_ideas"
-			}
-			text := fmt.Sprintf("Summarize this article with focus on %s. Read:\n- readeck://bookmark/%s/content.md\n- readeck://bookmark/%s/highlights.md", focus, bookmarkID, bookmarkID)
-			resp.Result = promptResult("Summarize bookmark", text)
-		case "readeck.prompt.flashcards":
-			numCards := 10
-			if raw, ok := params.Arguments["num_cards"]; ok {
-				numCards = int(toFloat(raw, 10))
-			}
-			cardType, _ := params.Arguments["card_type"].(string)
-			if cardType == "" {
-				cardType = "qa"
-			}
-			useHighlights := true
-			if raw, ok := params.Arguments["use_highlights"]; ok {
-				if b, ok := raw.(bool); ok {
-					useHighlights = b
-				}
-			}
-			text := fmt.Sprintf("Generate %d %s flashcards from this article. Read:\n- readeck://bookmark/%s/content.md", numCards, cardType, bookmarkID)
-			if useHighlights {
-				text += fmt.Sprintf("\n- readeck://bookmark/%s/highlights.md", bookmarkID)
-			}
-			resp.Result = promptResult("Flashcards from bookmark", text)
-		default:
-			resp.Error = &rpcError{Code: -32602, Message: "unknown prompt"}
-		}
+		resp.Result = result
 	default:
 		resp.Error = &rpcError{Code: -32601, Message: "method not found"}
 	}
@@ -343,16 +397,58 @@ func acceptsRPCResponse(accept string) bool {
 	return false
 }
 
-func (s *Server) isHTTPAuthorized(r *http.Request) bool {
-	expected := s.cfg.HTTPAuthToken
-	if expected == "" {
-		return true
+const (
+	scopeRead  = "read"
+	scopeWrite = "write"
+)
+
+// writeTools are the tools that modify bookmarks upstream. Keep in step with
+// executeTool.
+var writeTools = map[string]bool{
+	"readeck.archive":    true,
+	"readeck.labels.set": true,
+}
+
+func requiresWriteScope(req rpcRequest) bool {
+	if req.Method != "tools/call" {
+		return false
 	}
-	provided, ok := bearerToken(r.Header.Get("Authorization"))
-	if !ok {
+	var params toolCallParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return false
 	}
-	return subtle.ConstantTimeCompare([]byte(provided), []byte(expected)) == 1
+	return writeTools[params.Name]
+}
+
+// httpScope authenticates r against every configured credential and returns
+// the scope it grants. MCP_HTTP_AUTH_TOKEN and Basic credentials grant write;
+// MCP_HTTP_TOKENS entries grant their own scope. With nothing configured
+// every request gets write.
+func (s *Server) httpScope(r *http.Request) (string, bool) {
+	token, basicUser := s.cfg.HTTPAuthToken, s.cfg.HTTPBasicUser
+	if token == "" && basicUser == "" && len(s.cfg.HTTPTokens) == 0 {
+		return scopeWrite, true
+	}
+	if provided, ok := bearerToken(r.Header.Get("Authorization")); ok {
+		if token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1 {
+			return scopeWrite, true
+		}
+		for candidate, scope := range s.cfg.HTTPTokens {
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(candidate)) == 1 {
+				return scope, true
+			}
+		}
+	}
+	if basicUser != "" {
+		if user, pass, ok := r.BasicAuth(); ok {
+			userOK := subtle.ConstantTimeCompare([]byte(user), []byte(basicUser))
+			passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(s.cfg.HTTPBasicPass))
+			if userOK&passOK == 1 {
+				return scopeWrite, true
+			}
+		}
+	}
+	return "", false
 }
 
 func (s *Server) isOriginAllowed(r *http.Request) bool {
@@ -364,13 +460,37 @@ func (s *Server) isOriginAllowed(r *http.Request) bool {
 		return false
 	}
 	for _, allowed := range s.cfg.AllowedOrigins {
-		if allowed == "*" || strings.EqualFold(origin, allowed) {
+		if allowed == "*" || strings.EqualFold(origin, allowed) || matchesWildcardOrigin(origin, allowed) {
 			return true
 		}
 	}
 	return false
 }
 
+// matchesWildcardOrigin matches patterns like https://*.example.com against
+// any subdomain of example.com with the same scheme and port. The apex
+// itself does not match.
+func matchesWildcardOrigin(origin, pattern string) bool {
+	scheme, rest, ok := strings.Cut(pattern, "://*.")
+	if !ok || rest == "" {
+		return false
+	}
+	want, err := url.Parse(scheme + "://" + rest)
+	if err != nil {
+		return false
+	}
+	got, err := url.Parse(origin)
+	if err != nil || got.Path != "" || got.User != nil {
+		return false
+	}
+	if !strings.EqualFold(got.Scheme, want.Scheme) || got.Port() != want.Port() {
+		return false
+	}
+	host, domain := strings.ToLower(got.Hostname()), strings.ToLower(want.Hostname())
+	sub, ok := strings.CutSuffix(host, "."+domain)
+	return ok && sub != "" && !strings.HasSuffix(sub, ".")
+}
+
 func bearerToken(header string) (string, bool) {
 	header = strings.TrimSpace(header)
 	if header == "" {
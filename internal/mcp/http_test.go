@@ -0,0 +1,399 @@
+package mcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// postHTTP sends body to the MCP endpoint. header holds name/value pairs.
+func postHTTP(t *testing.T, s *Server, body string, header ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, s.cfg.HTTPPath, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.httpHandler().ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeHTTPResponse(t *testing.T, rec *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %q", rec.Code, rec.Body.String())
+	}
+	var msg map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	return msg
+}
+
+func TestHTTPServerTimeouts(t *testing.T) {
+	s := newTestServer(t, nil,
+		"READECK_TIMEOUT_SECONDS", "30",
+		"MCP_HTTP_READ_TIMEOUT_SECONDS", "",
+		"MCP_HTTP_WRITE_TIMEOUT_SECONDS", "",
+		"MCP_HTTP_IDLE_TIMEOUT_SECONDS", "")
+	srv := s.newHTTPServer(http.NotFoundHandler())
+	if srv.ReadHeaderTimeout == 0 || srv.ReadTimeout != 30*time.Second || srv.WriteTimeout != 90*time.Second || srv.IdleTimeout != 120*time.Second {
+		t.Fatalf("default timeouts: header %v read %v write %v idle %v", srv.ReadHeaderTimeout, srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+
+	s = newTestServer(t, nil,
+		"MCP_HTTP_READ_TIMEOUT_SECONDS", "5",
+		"MCP_HTTP_WRITE_TIMEOUT_SECONDS", "0",
+		"MCP_HTTP_IDLE_TIMEOUT_SECONDS", "7")
+	srv = s.newHTTPServer(http.NotFoundHandler())
+	if srv.ReadTimeout != 5*time.Second || srv.WriteTimeout != 0 || srv.IdleTimeout != 7*time.Second {
+		t.Fatalf("configured timeouts: read %v write %v idle %v", srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+}
+
+// selfSignedCert writes a localhost certificate and key to dir and returns
+// their paths with a pool trusting the certificate.
+func selfSignedCert(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// runHTTP serves s on its configured address until the test ends.
+func runHTTP(t *testing.T, s *Server) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.RunHTTP(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("RunHTTP: %v", err)
+		}
+	})
+}
+
+func TestRunHTTPServesTLS(t *testing.T) {
+	certFile, keyFile, pool := selfSignedCert(t, t.TempDir())
+	addr := freeAddr(t)
+	s := newTestServer(t, nil, "MCP_HTTP_ADDR", addr, "MCP_HTTP_TLS_CERT", certFile, "MCP_HTTP_TLS_KEY", keyFile)
+
+	runHTTP(t, s)
+
+	post := func(cfg *tls.Config) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}, Timeout: 2 * time.Second}
+		req, _ := http.NewRequest(http.MethodPost, "https://"+addr+s.cfg.HTTPPath, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		return client.Do(req)
+	}
+
+	var resp *http.Response
+	var err error
+	for range 50 {
+		if resp, err = post(&tls.Config{RootCAs: pool}); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("TLS request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.TLS == nil {
+		t.Fatalf("status = %d, tls = %v", resp.StatusCode, resp.TLS != nil)
+	}
+
+	if _, err := post(&tls.Config{RootCAs: pool, MaxVersion: tls.VersionTLS11}); err == nil {
+		t.Fatal("TLS 1.1 handshake succeeded, want it refused")
+	}
+}
+
+func basicAuth(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func TestHTTPBasicAuth(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_HTTP_BASIC_USER", "alice", "MCP_HTTP_BASIC_PASS", "s3cret", "MCP_HTTP_AUTH_TOKEN", "bearer-token")
+	ping := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+
+	tests := []struct {
+		name, authorization string
+		want                int
+	}{
+		{"valid basic", basicAuth("alice", "s3cret"), http.StatusOK},
+		{"valid bearer", "Bearer bearer-token", http.StatusOK},
+		{"wrong password", basicAuth("alice", "guess"), http.StatusUnauthorized},
+		{"wrong user", basicAuth("bob", "s3cret"), http.StatusUnauthorized},
+		{"password as bearer", "Bearer s3cret", http.StatusUnauthorized},
+		{"missing", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		rec := postHTTP(t, s, ping, "Authorization", tt.authorization)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic ") {
+			t.Errorf("%s: WWW-Authenticate = %q", tt.name, rec.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestHTTPWithoutAuthConfigured(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_HTTP_BASIC_USER", "", "MCP_HTTP_BASIC_PASS", "", "MCP_HTTP_AUTH_TOKEN", "")
+	decodeHTTPResponse(t, postHTTP(t, s, `{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+}
+
+func TestWildcardOriginMatching(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_ALLOWED_ORIGINS", "https://*.example.com,https://example.org")
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://APP.Example.COM", true},
+		{"https://example.com", false},
+		{"https://evilexample.com", false},
+		{"https://app.example.com.evil.net", false},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://.example.com", false},
+		{"https://example.org", true},
+		{"https://sub.example.org", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set("Origin", tt.origin)
+		if got := s.isOriginAllowed(r); got != tt.want {
+			t.Errorf("origin %s: allowed = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+// pingOfSize returns a ping request padded with whitespace to exactly n bytes.
+func pingOfSize(t *testing.T, n int) string {
+	t.Helper()
+	body := `{"jsonrpc":"2.0","id":1,"method":"ping"}`
+	if n < len(body) {
+		t.Fatalf("size %d is below the %d-byte ping", n, len(body))
+	}
+	return body + strings.Repeat(" ", n-len(body))
+}
+
+func TestHTTPOversizeBodyIsRejected(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_HTTP_MAX_BODY_BYTES", "64")
+
+	rec := postHTTP(t, s, pingOfSize(t, 200))
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "exceeds 64 bytes") {
+		t.Fatalf("status = %d body %q", rec.Code, rec.Body.String())
+	}
+	decodeHTTPResponse(t, postHTTP(t, s, pingOfSize(t, 64)))
+}
+
+func TestHTTPBodyJustOverLimitWithoutLength(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_HTTP_MAX_BODY_BYTES", "64")
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, s.cfg.HTTPPath, io.NopCloser(strings.NewReader(body)))
+		req.ContentLength = -1
+		rec := httptest.NewRecorder()
+		s.httpHandler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(pingOfSize(t, 65)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("65-byte body: status = %d body %q, want 413", rec.Code, rec.Body.String())
+	}
+	decodeHTTPResponse(t, send(pingOfSize(t, 64)))
+}
+
+func TestHTTPErrorsEchoRecoverableIDs(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_STRICT_INIT", "false")
+	tests := []struct {
+		name, body string
+		code       float64
+		id         any
+	}{
+		{"parse error", `{"jsonrpc":"2.0","id":1,`, -32700, nil},
+		{"wrong method type", `{"jsonrpc":"2.0","id":7,"method":5}`, -32600, float64(7)},
+		{"missing method", `{"jsonrpc":"2.0","id":"abc"}`, -32600, "abc"},
+		{"unusable id", `{"jsonrpc":"2.0","id":{"x":1},"method":5}`, -32600, nil},
+		{"method not found", `{"jsonrpc":"2.0","id":9,"method":"nope"}`, -32601, float64(9)},
+	}
+	for _, tt := range tests {
+		msg := decodeHTTPResponse(t, postHTTP(t, s, tt.body))
+		if code := errorCode(msg); code != tt.code {
+			t.Errorf("%s: code = %v, want %v", tt.name, code, tt.code)
+		}
+		if id := msg["id"]; id != tt.id {
+			t.Errorf("%s: id = %v, want %v", tt.name, id, tt.id)
+		}
+	}
+}
+
+func TestHTTPScopedTokens(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, "MCP_STRICT_INIT", "false", "MCP_HTTP_AUTH_TOKEN", "admin", "MCP_HTTP_TOKENS", "reader:read,writer:WRITE")
+	call := func(tool, args string) string {
+		return `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"` + tool + `","arguments":` + args + `}}`
+	}
+	write := call("readeck.labels.set", `{"id":"b1","labels":["go"]}`)
+	read := call("readeck.cite.styles", `{}`)
+
+	tests := []struct {
+		token, body string
+		forbidden   bool
+	}{
+		{"reader", read, false},
+		{"reader", write, true},
+		{"reader", call("readeck.archive", `{"id":"b1","archived":true}`), true},
+		{"reader", `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, false},
+		{"writer", write, false},
+		{"admin", write, false},
+	}
+	for _, tt := range tests {
+		msg := decodeHTTPResponse(t, postHTTP(t, s, tt.body, "Authorization", "Bearer "+tt.token))
+		if forbidden := errorCode(msg) == -32003; forbidden != tt.forbidden {
+			t.Errorf("%s %s: response = %v", tt.token, tt.body, msg)
+		}
+	}
+
+	if rec := postHTTP(t, s, read, "Authorization", "Bearer stranger"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("unknown token: status = %d", rec.Code)
+	}
+}
+
+func TestRunHTTPMountsRoutesUnderBasePath(t *testing.T) {
+	addr := freeAddr(t)
+	s := newTestServer(t, nil, "MCP_HTTP_ADDR", addr, "MCP_HTTP_BASE_PATH", "/proxy/", "MCP_HTTP_PATH", "mcp")
+	runHTTP(t, s)
+
+	post := func(path string) (int, error) {
+		resp, err := http.Post("http://"+addr+path, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+	var status int
+	var err error
+	for range 50 {
+		if status, err = post("/proxy/mcp"); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil || status != http.StatusOK {
+		t.Fatalf("prefixed path: status = %d, err = %v", status, err)
+	}
+	if status, err := post("/mcp"); err != nil || status != http.StatusNotFound {
+		t.Fatalf("unprefixed path: status = %d, err = %v", status, err)
+	}
+}
+
+func TestHTTPClientDisconnectCancelsUpstream(t *testing.T) {
+	arrived := make(chan struct{}, 1)
+	cancelled := make(chan struct{})
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		select {
+		case <-r.Context().Done():
+			close(cancelled)
+		case <-time.After(5 * time.Second):
+		}
+	})
+	srv := httptest.NewServer(s.httpHandler())
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	body := request(1, "tools/call", `{"name":"readeck.get","arguments":{"id":"b1"}}`)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+s.cfg.HTTPPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	errCh := make(chan error, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		errCh <- err
+	}()
+
+	<-arrived
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("client err = %v, want context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request outlived the disconnected client")
+	}
+}
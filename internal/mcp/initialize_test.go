@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func errorCode(msg map[string]any) float64 {
+	errObj, _ := msg["error"].(map[string]any)
+	code, _ := errObj["code"].(float64)
+	return code
+}
+
+func TestStrictInitGatesStdio(t *testing.T) {
+	input := frame(request(1, "tools/list", "")) + frame(request(2, "ping", "")) +
+		frame(request(3, "initialize", `{"protocolVersion":"2025-06-18"}`)) + frame(request(4, "tools/list", ""))
+
+	gated := byID(runStdio(t, newTestServer(t, nil, "MCP_STRICT_INIT", "true"), input))
+	if errorCode(gated[1]) != -32002 {
+		t.Fatalf("tools/list before initialize = %v", gated[1])
+	}
+	if gated[2]["result"] == nil || gated[4]["result"] == nil {
+		t.Fatalf("ping = %v, tools/list after initialize = %v", gated[2], gated[4])
+	}
+
+	ungated := byID(runStdio(t, newTestServer(t, nil, "MCP_STRICT_INIT", "false"), input))
+	if ungated[1]["result"] == nil {
+		t.Fatalf("tools/list without the gate = %v", ungated[1])
+	}
+}
+
+func TestStrictInitGatesHTTPBySession(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_STRICT_INIT", "true")
+
+	if msg := decodeHTTPResponse(t, postHTTP(t, s, request(1, "tools/list", ""))); errorCode(msg) != -32002 {
+		t.Fatalf("tools/list without a session = %v", msg)
+	}
+	if msg := decodeHTTPResponse(t, postHTTP(t, s, request(2, "ping", ""))); msg["result"] == nil {
+		t.Fatalf("ping without a session = %v", msg)
+	}
+
+	rec := postHTTP(t, s, request(3, "initialize", `{"protocolVersion":"2025-06-18"}`))
+	session := rec.Header().Get(sessionHeader)
+	if session == "" {
+		t.Fatal("initialize did not issue a session id")
+	}
+	if msg := decodeHTTPResponse(t, postHTTP(t, s, request(4, "tools/list", ""), sessionHeader, session)); msg["result"] == nil {
+		t.Fatalf("tools/list with a session = %v", msg)
+	}
+	if msg := decodeHTTPResponse(t, postHTTP(t, s, request(5, "tools/list", ""), sessionHeader, "forged")); errorCode(msg) != -32002 {
+		t.Fatalf("tools/list with an unknown session = %v", msg)
+	}
+
+	if msg := decodeHTTPResponse(t, postHTTP(t, newTestServer(t, nil, "MCP_STRICT_INIT", "false"), request(1, "tools/list", ""))); msg["result"] == nil {
+		t.Fatalf("tools/list without the gate = %v", msg)
+	}
+}
+
+func TestInitializeNegotiatesProtocolVersion(t *testing.T) {
+	s := newTestServer(t, nil)
+	latest := s.cfg.Protocols[0]
+	tests := []struct{ requested, want string }{
+		{"2025-03-26", "2025-03-26"},
+		{"2024-11-05", "2024-11-05"},
+		{latest, latest},
+		{"1999-01-01", latest},
+		{"", latest},
+	}
+	for _, tt := range tests {
+		params := `{"protocolVersion":"` + tt.requested + `"}`
+		stdio := byID(runStdio(t, newTestServer(t, nil), frame(request(1, "initialize", params))))[1]
+		overHTTP := decodeHTTPResponse(t, postHTTP(t, s, request(1, "initialize", params)))
+		for transport, msg := range map[string]map[string]any{"stdio": stdio, "http": overHTTP} {
+			result, _ := msg["result"].(map[string]any)
+			if result["protocolVersion"] != tt.want {
+				t.Errorf("%s requested %q: protocolVersion = %v, want %s", transport, tt.requested, result["protocolVersion"], tt.want)
+			}
+		}
+	}
+}
+
+func TestProgressNotificationsFollowTheRequestToken(t *testing.T) {
+	items := make([]map[string]any, 0, 250)
+	for i := range 250 {
+		items = append(items, map[string]any{"id": fmt.Sprint("h", i), "text": "quote", "created": "2024-03-01T00:00:00Z"})
+	}
+	args := `"name":"readeck.highlights.list","arguments":{"date_from":"2024-01-01","limit":300}`
+
+	for _, tt := range []struct {
+		meta     string
+		progress bool
+	}{
+		{`,"_meta":{"progressToken":"tok"}`, true},
+		{`,"_meta":{"progressToken":null}`, false},
+		{``, false},
+	} {
+		s := newTestServer(t, highlightFeed(t, items, nil))
+		input := frame(request(1, "initialize", `{"capabilities":{}}`)) + frame(request(2, "tools/call", `{`+args+tt.meta+`}`))
+		notified := 0
+		for _, msg := range runStdio(t, s, input) {
+			if msg["method"] == "notifications/progress" {
+				params := msg["params"].(map[string]any)
+				if params["progressToken"] != "tok" || params["total"] != float64(300) {
+					t.Fatalf("progress params = %v", params)
+				}
+				notified++
+			}
+		}
+		if (notified > 0) != tt.progress {
+			t.Errorf("meta %q: %d progress notifications", tt.meta, notified)
+		}
+	}
+}
+
+func deleteHTTP(t *testing.T, s *Server, header ...string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodDelete, s.cfg.HTTPPath, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	s.httpHandler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestHTTPDeleteEndsTheSession(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_STRICT_INIT", "true")
+	session := postHTTP(t, s, request(1, "initialize", `{"protocolVersion":"2025-06-18"}`)).Header().Get(sessionHeader)
+
+	if code := deleteHTTP(t, s, sessionHeader, session); code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d, want 204", code)
+	}
+	if msg := decodeHTTPResponse(t, postHTTP(t, s, request(2, "tools/list", ""), sessionHeader, session)); errorCode(msg) != -32002 {
+		t.Fatalf("tools/list after DELETE = %v", msg)
+	}
+	if code := deleteHTTP(t, s, sessionHeader, session); code != http.StatusNotFound {
+		t.Fatalf("second DELETE = %d, want 404", code)
+	}
+	if code := deleteHTTP(t, s); code != http.StatusBadRequest {
+		t.Fatalf("DELETE without a session id = %d, want 400", code)
+	}
+	if code := deleteHTTP(t, newTestServer(t, nil, "MCP_STRICT_INIT", "false"), sessionHeader, session); code != http.StatusMethodNotAllowed {
+		t.Fatalf("DELETE without MCP_STRICT_INIT = %d, want 405", code)
+	}
+}
+
+func TestSessionsExpireWhenIdle(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newSessionStore()
+	store.now = func() time.Time { return now }
+
+	active, _ := store.create()
+	idle, _ := store.create()
+	now = now.Add(sessionIdleTTL - time.Minute)
+	if !store.has(active) {
+		t.Fatal("session expired before the idle TTL")
+	}
+	now = now.Add(2 * time.Minute)
+	if !store.has(active) {
+		t.Fatal("a session in use expired")
+	}
+	if store.has(idle) {
+		t.Fatal("idle session outlived the TTL")
+	}
+	if _, ok := store.ids[idle]; ok {
+		t.Fatal("expired session still stored")
+	}
+}
+
+func TestSessionStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := newSessionStore()
+	store.max = 2
+	store.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	first, _ := store.create()
+	second, _ := store.create()
+	store.has(first)
+	third, _ := store.create()
+	if len(store.ids) != 2 || !store.has(first) || !store.has(third) || store.has(second) {
+		t.Fatalf("sessions = %v, want the least recently used one evicted", store.ids)
+	}
+}
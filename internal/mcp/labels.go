@@ -0,0 +1,178 @@
+package mcp
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+	"github.com/akrisanov/readeck-mcp/internal/render"
+)
+
+const (
+	defaultLabelSuggestions = 5
+	defaultRelatedLimit     = 10
+	maxRelatedPages         = 10
+	maxLabelPages           = 20
+)
+
+type labelSuggestion struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+func (s *Server) suggestLabels(ctx context.Context, id string, max int) (map[string]any, error) {
+	if max <= 0 {
+		max = defaultLabelSuggestions
+	}
+	bookmark, err := s.client.GetBookmark(ctx, id, readeck.IncludeOptions{Content: true, Labels: true})
+	if err != nil {
+		return nil, err
+	}
+	universe, err := s.allLabels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[string]struct{}{}
+	for _, l := range bookmark.Labels {
+		applied[strings.ToLower(strings.TrimSpace(l.Name))] = struct{}{}
+	}
+	text := bookmark.Title + "\n" + render.BookmarkContentText(bookmark)
+	return map[string]any{"suggestions": rankLabels(text, universe, applied, max)}, nil
+}
+
+// relatedBookmarks finds other bookmarks sharing any of the source's labels,
+// ranked by the number of shared labels (the label_match sort). Search pages
+// are followed until limit candidates are collected; partial reports that
+// the scan stopped at maxRelatedPages or the upstream scan cap first.
+func (s *Server) relatedBookmarks(ctx context.Context, id string, limit int) (map[string]any, error) {
+	if limit <= 0 {
+		limit = defaultRelatedLimit
+	}
+	bookmark, err := s.client.GetBookmark(ctx, id, readeck.IncludeOptions{Labels: true})
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]string, 0, len(bookmark.Labels))
+	for _, l := range bookmark.Labels {
+		if name := strings.TrimSpace(l.Name); name != "" {
+			labels = append(labels, name)
+		}
+	}
+	items := []readeck.BookmarkSummary{}
+	if len(labels) == 0 {
+		return map[string]any{"id": bookmark.ID, "labels": labels, "items": items, "partial": false}, nil
+	}
+
+	opts := readeck.SearchOptions{
+		Labels:     labels,
+		LabelMode:  readeck.LabelMatchAny,
+		Sort:       readeck.SortLabelMatch,
+		Limit:      s.cfg.MaxPageSize,
+		NoSnippets: true,
+	}
+	partial := false
+	for page := 1; ; page++ {
+		result, err := s.client.Search(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range result.Items {
+			if item.ID != bookmark.ID {
+				items = append(items, item)
+			}
+		}
+		if result.Partial {
+			partial = true
+			break
+		}
+		if len(items) >= limit || result.NextCursor == "" || result.NextCursor == opts.Cursor {
+			break
+		}
+		if page >= maxRelatedPages {
+			partial = true
+			break
+		}
+		opts.Cursor = result.NextCursor
+	}
+
+	readeck.RankByLabels(items, labels)
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return map[string]any{"id": bookmark.ID, "labels": labels, "items": items, "partial": partial}, nil
+}
+
+func (s *Server) allLabels(ctx context.Context) ([]readeck.Label, error) {
+	var out []readeck.Label
+	cursor := ""
+	for page := 0; page < maxLabelPages; page++ {
+		result, err := s.client.ListLabels(ctx, 0, cursor)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, result.Labels...)
+		if result.NextCursor == "" || result.NextCursor == cursor {
+			break
+		}
+		cursor = result.NextCursor
+	}
+	return out, nil
+}
+
+// rankLabels counts whole-word, case-insensitive occurrences of each label in
+// text and returns the most frequent ones, skipping labels already applied.
+func rankLabels(text string, labels []readeck.Label, applied map[string]struct{}, max int) []labelSuggestion {
+	seen := map[string]struct{}{}
+	out := []labelSuggestion{}
+	for _, l := range labels {
+		name := strings.TrimSpace(l.Name)
+		key := strings.ToLower(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := applied[key]; ok {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		pattern := `(?i)(^|[^\pL\pN])` + regexp.QuoteMeta(name) + `($|[^\pL\pN])`
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			continue
+		}
+		if count := countMatches(re, text); count > 0 {
+			out = append(out, labelSuggestion{Label: name, Count: count})
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return strings.ToLower(out[i].Label) < strings.ToLower(out[j].Label)
+	})
+	if len(out) > max {
+		out = out[:max]
+	}
+	return out
+}
+
+// countMatches counts matches of a separator-delimited pattern, resuming at
+// the trailing separator so adjacent occurrences are both counted.
+func countMatches(re *regexp.Regexp, text string) int {
+	count := 0
+	for {
+		loc := re.FindStringSubmatchIndex(text)
+		if loc == nil {
+			return count
+		}
+		count++
+		text = text[loc[4]:]
+	}
+}
@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func bookmarkJSON(id, updated string, labels ...string) map[string]any {
+	return map[string]any{"id": id, "title": id, "url": "https://example.com/" + id, "updated": updated, "labels": labels}
+}
+
+func TestRelatedRanksAcrossSearchPages(t *testing.T) {
+	pages := map[string]map[string]any{
+		"": {"items": []any{
+			bookmarkJSON("src", "2024-01-09T00:00:00Z", "a", "b"),
+			bookmarkJSON("x", "2024-01-08T00:00:00Z", "a"),
+		}, "next_cursor": "p2"},
+		"p2": {"items": []any{
+			bookmarkJSON("y", "2024-01-07T00:00:00Z", "a", "b"),
+			bookmarkJSON("z", "2024-01-06T00:00:00Z", "c"),
+		}, "next_cursor": "p3"},
+		"p3": {"items": []any{
+			bookmarkJSON("w", "2024-01-05T00:00:00Z", "b"),
+		}},
+	}
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bookmarks/src":
+			writeJSON(t, w, bookmarkJSON("src", "2024-01-09T00:00:00Z", "a", "b"))
+		case "/api/bookmarks":
+			writeJSON(t, w, pages[r.URL.Query().Get("cursor")])
+		default:
+			http.NotFound(w, r)
+		}
+	}, "READECK_MAX_PAGE_SIZE", "2", "READECK_DEFAULT_SEARCH_LIMIT", "2")
+
+	out := mustCallTool(t, s, "readeck.related", `{"id":"src","limit":3}`)
+	if got, want := itemIDs(t, out, "items"), []string{"y", "x", "w"}; !slices.Equal(got, want) {
+		t.Fatalf("items = %v, want %v", got, want)
+	}
+	if out["partial"] != false {
+		t.Fatalf("partial = %v, want false", out["partial"])
+	}
+}
+
+func TestRelatedReportsPartialAtPageCap(t *testing.T) {
+	calls := 0
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bookmarks/src" {
+			writeJSON(t, w, bookmarkJSON("src", "2024-01-09T00:00:00Z", "a"))
+			return
+		}
+		calls++
+		writeJSON(t, w, map[string]any{
+			"items":       []any{bookmarkJSON("m", "2024-01-01T00:00:00Z", "a")},
+			"next_cursor": r.URL.Query().Get("cursor") + "n",
+		})
+	}, "READECK_MAX_PAGE_SIZE", "1", "READECK_DEFAULT_SEARCH_LIMIT", "1")
+
+	out := mustCallTool(t, s, "readeck.related", `{"id":"src","limit":50}`)
+	if out["partial"] != true {
+		t.Fatalf("partial = %v, want true", out["partial"])
+	}
+	if calls != maxRelatedPages {
+		t.Fatalf("search pages = %d, want %d", calls, maxRelatedPages)
+	}
+	if n := len(itemIDs(t, out, "items")); n != maxRelatedPages {
+		t.Fatalf("items = %d, want %d", n, maxRelatedPages)
+	}
+}
+
+func TestRelatedWithoutLabels(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/bookmarks/src" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		writeJSON(t, w, bookmarkJSON("src", "2024-01-09T00:00:00Z"))
+	})
+	out := mustCallTool(t, s, "readeck.related", `{"id":"src"}`)
+	if n := len(itemIDs(t, out, "items")); n != 0 {
+		t.Fatalf("items = %d, want none", n)
+	}
+}
+
+func TestSuggestLabelsRanksByOccurrenceAndSkipsApplied(t *testing.T) {
+	up := upstream{
+		bookmarks: map[string]map[string]any{
+			"b1": {"id": "b1", "title": "Go concurrency", "url": "https://example.com/go", "labels": []string{"Go"}},
+		},
+		content: map[string]string{
+			"b1": `<p>Channels in Rust and Go. Rust again, rust-lang, then postgres.</p><p>Gopher talk.</p>`,
+		},
+	}
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bookmarks/labels" {
+			writeJSON(t, w, map[string]any{"items": []any{
+				map[string]any{"name": "go"},
+				map[string]any{"name": "postgres"},
+				map[string]any{"name": "rust"},
+				map[string]any{"name": "Rust"},
+				map[string]any{"name": "python"},
+				map[string]any{"name": "channels"},
+			}})
+			return
+		}
+		up.handler(t)(w, r)
+	})
+
+	out := mustCallTool(t, s, "readeck.labels.suggest", `{"id":"b1"}`)
+	var got []string
+	for _, item := range out["suggestions"].([]any) {
+		suggestion := item.(map[string]any)
+		got = append(got, suggestion["label"].(string)+":"+strconv.Itoa(int(suggestion["count"].(float64))))
+	}
+	if want := []string{"rust:3", "channels:1", "postgres:1"}; !slices.Equal(got, want) {
+		t.Fatalf("suggestions = %v, want %v", got, want)
+	}
+
+	out = mustCallTool(t, s, "readeck.labels.suggest", `{"id":"b1","max":1}`)
+	if n := len(out["suggestions"].([]any)); n != 1 {
+		t.Fatalf("suggestions with max 1 = %d", n)
+	}
+}
+
+func TestSetLabelsRejectsInvalidLabels(t *testing.T) {
+	s := newTestServer(t, nil, "READECK_MAX_LABEL_LENGTH", "10")
+
+	_, err := callTool(t, s, "readeck.labels.set", `{"id":"b1","labels":["ok","way too long a label","tab\there"]}`)
+	assertInputError(t, err, `invalid labels: "way too long a label" (longer than 10 characters), "tab\there" (contains control characters)`)
+	_, err = callTool(t, s, "readeck.labels.set", `{"id":"b1","labels":["line\nbreak"]}`)
+	assertInputError(t, err, `invalid labels: "line\nbreak" (contains control characters)`)
+}
+
+func TestSetLabelsAcceptsLabelAtTheLimit(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, "READECK_MAX_LABEL_LENGTH", "10")
+
+	out := mustCallTool(t, s, "readeck.labels.set", `{"id":"b1","labels":["ünïcödé 10"]}`)
+	if labels := out["labels"].([]any); len(labels) != 1 || labels[0] != "ünïcödé 10" {
+		t.Fatalf("labels = %v", out["labels"])
+	}
+}
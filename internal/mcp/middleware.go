@@ -0,0 +1,71 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+type Middleware func(next http.Handler) http.Handler
+
+// Use registers HTTP middleware around the MCP endpoint. The first one
+// registered is the outermost. It must be called before RunHTTP.
+func (s *Server) Use(mw ...Middleware) {
+	s.middleware = append(s.middleware, mw...)
+}
+
+func (s *Server) httpHandler() http.Handler {
+	var h http.Handler = http.HandlerFunc(s.handleHTTPMCP)
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		h = s.middleware[i](h)
+	}
+	return h
+}
+
+// RequestIDMiddleware ensures every request carries an X-Request-Id, keeping
+// one supplied by the client, and echoes it on the response.
+func RequestIDMiddleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(requestIDHeader)
+			if id == "" {
+				buf := make([]byte, 8)
+				if _, err := rand.Read(buf); err == nil {
+					id = hex.EncodeToString(buf)
+					r.Header.Set(requestIDHeader, id)
+				}
+			}
+			if id != "" {
+				w.Header().Set(requestIDHeader, id)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LoggingMiddleware logs method, path, status and duration per request.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			logger.Printf("http method=%s path=%s status=%d request_id=%s duration_ms=%d",
+				r.Method, r.URL.Path, rec.status, r.Header.Get(requestIDHeader), time.Since(start).Milliseconds())
+		})
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,54 @@
+package mcp
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func headerHook(name, value string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add(name, value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestUseWrapsTheEndpointInOrder(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.Use(headerHook("X-Hook", "outer"), headerHook("X-Hook", "inner"))
+
+	rec := postHTTP(t, s, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	decodeHTTPResponse(t, rec)
+	if got := rec.Header().Values("X-Hook"); strings.Join(got, ",") != "outer,inner" {
+		t.Fatalf("X-Hook = %q, want outer then inner", got)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.Use(RequestIDMiddleware())
+
+	rec := postHTTP(t, s, `{"jsonrpc":"2.0","id":1,"method":"ping"}`, requestIDHeader, "client-id")
+	if got := rec.Header().Get(requestIDHeader); got != "client-id" {
+		t.Fatalf("echoed request id = %q, want client-id", got)
+	}
+	rec = postHTTP(t, s, `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	if got := rec.Header().Get(requestIDHeader); len(got) != 16 {
+		t.Fatalf("generated request id = %q, want 16 hex chars", got)
+	}
+}
+
+func TestLoggingMiddlewareRecordsStatus(t *testing.T) {
+	var buf bytes.Buffer
+	s := newTestServer(t, nil)
+	s.Use(RequestIDMiddleware(), LoggingMiddleware(log.New(&buf, "", 0)))
+
+	postHTTP(t, s, `{"jsonrpc":"2.0","id":1,"method":"ping"}`, requestIDHeader, "abc")
+	if line := buf.String(); !strings.Contains(line, "status=200") || !strings.Contains(line, "request_id=abc") {
+		t.Fatalf("log line = %q", line)
+	}
+}
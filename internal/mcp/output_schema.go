@@ -0,0 +1,360 @@
+package mcp
+
+// Output schemas describe the structuredContent each tool returns. They
+// mirror the JSON shape of the readeck types and must be kept in step with
+// them.
+
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func arraySchema(items map[string]any) map[string]any {
+	return map[string]any{"type": "array", "items": items}
+}
+
+func stringSchema() map[string]any {
+	return map[string]any{"type": "string"}
+}
+
+func integerSchema() map[string]any {
+	return map[string]any{"type": "integer"}
+}
+
+func numberSchema() map[string]any {
+	return map[string]any{"type": "number"}
+}
+
+func booleanSchema() map[string]any {
+	return map[string]any{"type": "boolean"}
+}
+
+func labelSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":    stringSchema(),
+		"name":  stringSchema(),
+		"color": stringSchema(),
+	}, "name")
+}
+
+func highlightSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":          stringSchema(),
+		"bookmark_id": stringSchema(),
+		"text":        stringSchema(),
+		"note":        stringSchema(),
+		"color":       stringSchema(),
+		"created_at":  stringSchema(),
+		"updated_at":  stringSchema(),
+		"location":    map[string]any{},
+	}, "id", "bookmark_id", "text")
+}
+
+func bookmarkSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":                         stringSchema(),
+		"url":                        stringSchema(),
+		"title":                      stringSchema(),
+		"site_name":                  stringSchema(),
+		"author":                     stringSchema(),
+		"author_raw":                 stringSchema(),
+		"published_at":               stringSchema(),
+		"created_at":                 stringSchema(),
+		"updated_at":                 stringSchema(),
+		"is_archived":                booleanSchema(),
+		"is_favorite":                booleanSchema(),
+		"word_count":                 integerSchema(),
+		"reading_minutes":            integerSchema(),
+		"read_progress":              integerSchema(),
+		"labels":                     arraySchema(labelSchema()),
+		"collection":                 stringSchema(),
+		"content_text":               stringSchema(),
+		"content_html":               stringSchema(),
+		"content_unavailable_reason": stringSchema(),
+		"images": arraySchema(objectSchema(map[string]any{
+			"src": stringSchema(),
+			"alt": stringSchema(),
+		}, "src")),
+		"highlights": arraySchema(highlightSchema()),
+		"snippet":    stringSchema(),
+		"icon_url":   stringSchema(),
+		"icon_data":  stringSchema(),
+		"warnings":   arraySchema(stringSchema()),
+	}, "id", "url", "title", "is_archived")
+}
+
+func bookmarkSummarySchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":           stringSchema(),
+		"title":        stringSchema(),
+		"url":          stringSchema(),
+		"is_archived":  booleanSchema(),
+		"is_favorite":  booleanSchema(),
+		"labels":       arraySchema(stringSchema()),
+		"collection":   stringSchema(),
+		"created_at":   stringSchema(),
+		"updated_at":   stringSchema(),
+		"published_at": stringSchema(),
+		"snippet":      stringSchema(),
+	}, "id", "title", "url", "is_archived")
+}
+
+func citationSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"style":    stringSchema(),
+		"text":     stringSchema(),
+		"csl_json": map[string]any{"type": "object"},
+		"bibtex":   stringSchema(),
+		"metadata": objectSchema(map[string]any{
+			"title":        stringSchema(),
+			"author":       stringSchema(),
+			"site_name":    stringSchema(),
+			"published_at": stringSchema(),
+			"url":          stringSchema(),
+			"accessed_at":  stringSchema(),
+		}, "accessed_at"),
+	}, "style", "metadata")
+}
+
+func searchOutputSchema() map[string]any {
+	item := bookmarkSummarySchema()
+	item["properties"].(map[string]any)["content_text"] = stringSchema()
+	return objectSchema(map[string]any{
+		"items":       arraySchema(item),
+		"next_cursor": stringSchema(),
+		"prev_cursor": stringSchema(),
+		"partial":     booleanSchema(),
+	}, "items")
+}
+
+func countOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"count":   integerSchema(),
+		"capped":  booleanSchema(),
+		"partial": booleanSchema(),
+	}, "count")
+}
+
+func getOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"bookmark":         bookmarkSchema(),
+		"content_text":     stringSchema(),
+		"content_markdown": stringSchema(),
+		"content_range": objectSchema(map[string]any{
+			"start":        integerSchema(),
+			"length":       integerSchema(),
+			"total_length": integerSchema(),
+		}, "start", "length", "total_length"),
+	}, "bookmark")
+}
+
+func contentCheckOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":              stringSchema(),
+		"char_count":      integerSchema(),
+		"word_count":      integerSchema(),
+		"looks_truncated": booleanSchema(),
+		"reasons":         arraySchema(stringSchema()),
+	}, "char_count", "word_count", "looks_truncated")
+}
+
+func linksOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id": stringSchema(),
+		"links": arraySchema(objectSchema(map[string]any{
+			"url":  stringSchema(),
+			"text": stringSchema(),
+		}, "url")),
+		"count": integerSchema(),
+	}, "links", "count")
+}
+
+func resourceResolveOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"uri":       stringSchema(),
+		"supported": booleanSchema(),
+		"id":        stringSchema(),
+		"kind":      stringSchema(),
+		"options":   map[string]any{"type": "object"},
+		"reason":    stringSchema(),
+	}, "uri", "supported")
+}
+
+func diffOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":      stringSchema(),
+		"changed": booleanSchema(),
+		"changes": arraySchema(objectSchema(map[string]any{
+			"field":    stringSchema(),
+			"previous": map[string]any{},
+			"current":  map[string]any{},
+		}, "field", "previous", "current")),
+	}, "id", "changed", "changes")
+}
+
+func archiveOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":          stringSchema(),
+		"is_archived": booleanSchema(),
+		"updated_at":  stringSchema(),
+	}, "id", "is_archived")
+}
+
+func labelsListOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"labels":      arraySchema(labelSchema()),
+		"next_cursor": stringSchema(),
+		"prev_cursor": stringSchema(),
+	}, "labels")
+}
+
+func collectionsListOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"collections": arraySchema(objectSchema(map[string]any{
+			"id":   stringSchema(),
+			"name": stringSchema(),
+		}, "name")),
+		"next_cursor": stringSchema(),
+		"prev_cursor": stringSchema(),
+	}, "collections")
+}
+
+func labelsSuggestOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"suggestions": arraySchema(objectSchema(map[string]any{
+			"label": stringSchema(),
+			"count": integerSchema(),
+		}, "label", "count")),
+	}, "suggestions")
+}
+
+func relatedOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":      stringSchema(),
+		"labels":  arraySchema(stringSchema()),
+		"items":   arraySchema(bookmarkSummarySchema()),
+		"partial": booleanSchema(),
+	}, "labels", "items")
+}
+
+func labelsSetOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"id":     stringSchema(),
+		"labels": arraySchema(stringSchema()),
+	}, "id", "labels")
+}
+
+func highlightsListOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"highlights":  arraySchema(highlightSchema()),
+		"next_cursor": stringSchema(),
+		"prev_cursor": stringSchema(),
+		"partial":     booleanSchema(),
+	}, "highlights")
+}
+
+func highlightsColorsOutputSchema() map[string]any {
+	return objectSchema(map[string]any{"colors": arraySchema(stringSchema())}, "colors")
+}
+
+func markdownOutputSchema() map[string]any {
+	return objectSchema(map[string]any{"markdown": stringSchema()}, "markdown")
+}
+
+func exportAnkiOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"tsv":     stringSchema(),
+		"count":   integerSchema(),
+		"skipped": integerSchema(),
+		"partial": booleanSchema(),
+	}, "tsv", "count")
+}
+
+func citeOutputSchema() map[string]any {
+	return objectSchema(map[string]any{"citation": citationSchema()}, "citation")
+}
+
+func citeSearchOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"citations":   arraySchema(citationSchema()),
+		"next_cursor": stringSchema(),
+	}, "citations")
+}
+
+func bibliographyOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"bibliography": stringSchema(),
+		"count":        integerSchema(),
+		"duplicates":   integerSchema(),
+	}, "bibliography", "count")
+}
+
+func citeStylesOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"styles": arraySchema(objectSchema(map[string]any{
+			"style":  stringSchema(),
+			"label":  stringSchema(),
+			"sample": stringSchema(),
+		}, "style", "label", "sample")),
+	}, "styles")
+}
+
+func diagnosticsOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"version": stringSchema(),
+		"upstream": objectSchema(map[string]any{
+			"reachable":  booleanSchema(),
+			"latency_ms": integerSchema(),
+			"error":      stringSchema(),
+		}, "reachable", "latency_ms"),
+		"limits": objectSchema(map[string]any{
+			"max_page_size":        integerSchema(),
+			"default_search_limit": integerSchema(),
+			"default_list_limit":   integerSchema(),
+			"highlight_scan_cap":   integerSchema(),
+			"max_label_length":     integerSchema(),
+			"max_concurrency":      integerSchema(),
+			"rate_limit_rps":       numberSchema(),
+			"timeout_seconds":      numberSchema(),
+		}, "max_page_size", "max_concurrency", "timeout_seconds"),
+		"config": map[string]any{"type": "object", "description": "Effective configuration with secrets reduced to flags."},
+		"client": objectSchema(map[string]any{
+			"max_concurrency":    integerSchema(),
+			"rate_limited":       booleanSchema(),
+			"content_endpoints":  arraySchema(stringSchema()),
+			"request_coalescing": booleanSchema(),
+			"cache":              stringSchema(),
+			"retries": objectSchema(map[string]any{
+				"max_attempts":  integerSchema(),
+				"base_delay_ms": integerSchema(),
+				"methods":       arraySchema(stringSchema()),
+				"on_status":     stringSchema(),
+			}),
+			"writes": objectSchema(map[string]any{
+				"serialized": booleanSchema(),
+				"on_status":  stringSchema(),
+			}),
+		}),
+		"tools": arraySchema(stringSchema()),
+	}, "version", "upstream", "limits", "config", "client", "tools")
+}
+
+func changedSinceOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"items":      arraySchema(bookmarkSummarySchema()),
+		"next_since": stringSchema(),
+		"partial":    booleanSchema(),
+		"warning":    stringSchema(),
+	}, "items", "next_since")
+}
+
+func highlightsSinceOutputSchema() map[string]any {
+	return objectSchema(map[string]any{
+		"highlights": arraySchema(highlightSchema()),
+		"next_since": stringSchema(),
+		"partial":    booleanSchema(),
+	}, "highlights", "next_since")
+}
@@ -0,0 +1,38 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type progressFunc func(progress, total int)
+
+type progressKey struct{}
+
+func withProgress(ctx context.Context, fn progressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, progress, total int) {
+	if fn, ok := ctx.Value(progressKey{}).(progressFunc); ok {
+		fn(progress, total)
+	}
+}
+
+// progressReporter returns a callback emitting notifications/progress for
+// token, or nil when the request carries no progress token. Clients ask for
+// progress per request through params._meta.progressToken.
+func (s *Server) progressReporter(token json.RawMessage) progressFunc {
+	if len(token) == 0 || string(token) == "null" {
+		return nil
+	}
+	return func(progress, total int) {
+		params := map[string]any{"progressToken": token, "progress": progress}
+		if total > 0 {
+			params["total"] = total
+		}
+		if err := s.writeMessage(rpcNotification{JSONRPC: "2.0", Method: "notifications/progress", Params: params}); err != nil {
+			s.logger.Printf("progress notify failed: %v", err)
+		}
+	}
+}
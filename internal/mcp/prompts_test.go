@@ -0,0 +1,122 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func promptArgument(t *testing.T, prompt, name string) map[string]any {
+	t.Helper()
+	for _, def := range promptDefinitions() {
+		if def["name"] != prompt {
+			continue
+		}
+		for _, arg := range def["arguments"].([]map[string]any) {
+			if arg["name"] == name {
+				return arg
+			}
+		}
+	}
+	t.Fatalf("prompt %s has no argument %s", prompt, name)
+	return nil
+}
+
+func TestPromptArgumentsDescribeTheirSchema(t *testing.T) {
+	for _, def := range promptDefinitions() {
+		for _, arg := range def["arguments"].([]map[string]any) {
+			if _, ok := arg["type"].(string); !ok {
+				t.Errorf("%s.%s has no type", def["name"], arg["name"])
+			}
+			if _, ok := arg["required"].(bool); !ok {
+				t.Errorf("%s.%s has no required flag", def["name"], arg["name"])
+			}
+		}
+	}
+
+	numCards := promptArgument(t, "readeck.prompt.flashcards", "num_cards")
+	if numCards["type"] != "integer" || numCards["minimum"] != 1 || numCards["default"] != 10 {
+		t.Fatalf("num_cards = %v", numCards)
+	}
+	useHighlights := promptArgument(t, "readeck.prompt.flashcards", "use_highlights")
+	if useHighlights["type"] != "boolean" || useHighlights["default"] != true {
+		t.Fatalf("use_highlights = %v", useHighlights)
+	}
+	if id := promptArgument(t, "readeck.prompt.summarize", "bookmark_id"); id["type"] != "string" || id["required"] != true {
+		t.Fatalf("bookmark_id = %v", id)
+	}
+}
+
+func promptText(t *testing.T, name string, args map[string]any) string {
+	t.Helper()
+	result, rpcErr := buildPrompt(promptGetParams{Name: name, Arguments: args})
+	if rpcErr != nil {
+		t.Fatalf("%s: %s", name, rpcErr.Message)
+	}
+	messages := result["messages"].([]map[string]any)
+	return messages[0]["content"].(map[string]any)["text"].(string)
+}
+
+func TestFlashcardPromptPerCardType(t *testing.T) {
+	tests := []struct{ cardType, want string }{
+		{"", "question/answer flashcards"},
+		{"qa", "question/answer flashcards"},
+		{"cloze", "{{c1::term}}"},
+		{"basic", "front/back flashcards"},
+	}
+	for _, tt := range tests {
+		text := promptText(t, "readeck.prompt.flashcards", map[string]any{"bookmark_id": "b1", "card_type": tt.cardType, "num_cards": float64(5)})
+		if !strings.Contains(text, tt.want) || !strings.Contains(text, "Generate 5 ") {
+			t.Errorf("card_type %q: %q", tt.cardType, text)
+		}
+	}
+
+	_, rpcErr := buildPrompt(promptGetParams{Name: "readeck.prompt.flashcards", Arguments: map[string]any{"bookmark_id": "b1", "card_type": "essay"}})
+	if rpcErr == nil || rpcErr.Code != -32602 {
+		t.Fatalf("unknown card_type error = %v", rpcErr)
+	}
+}
+
+func TestFlashcardPromptHighlightsAreOptional(t *testing.T) {
+	text := promptText(t, "readeck.prompt.flashcards", map[string]any{"bookmark_id": "b1", "use_highlights": false})
+	if strings.Contains(text, "highlights.md") {
+		t.Fatalf("highlights read despite use_highlights=false: %q", text)
+	}
+	if text := promptText(t, "readeck.prompt.flashcards", map[string]any{"bookmark_id": "b1"}); !strings.Contains(text, "readeck://bookmark/b1/highlights.md") {
+		t.Fatalf("highlights missing by default: %q", text)
+	}
+}
+
+func TestSummarizeFocusGuidance(t *testing.T) {
+	focus := promptArgument(t, "readeck.prompt.summarize", "focus")
+	enum := focus["enum"].([]string)
+	if len(enum) != len(summarizeFocusGuidance) {
+		t.Fatalf("focus enum %v does not match the guided focuses", enum)
+	}
+	for _, name := range enum {
+		text := promptText(t, "readeck.prompt.summarize", map[string]any{"bookmark_id": "b1", "focus": name})
+		if !strings.Contains(text, summarizeFocusGuidance[name]) {
+			t.Errorf("focus %s: guidance missing from %q", name, text)
+		}
+	}
+
+	text := promptText(t, "readeck.prompt.summarize", map[string]any{"bookmark_id": "b1", "focus": "security implications"})
+	if !strings.Contains(text, "focus on security implications. Read:") {
+		t.Fatalf("free-form focus = %q", text)
+	}
+}
+
+func TestSummarizeFocusOverBothTransports(t *testing.T) {
+	params := `{"name":"readeck.prompt.summarize","arguments":{"bookmark_id":"b1","focus":"critique"}}`
+	want := summarizeFocusGuidance["critique"]
+
+	s := newTestServer(t, nil)
+	stdio := byID(runStdio(t, s, frame(request(1, "prompts/get", params))))[1]
+	httpResp := decodeHTTPResponse(t, postHTTP(t, newTestServer(t, nil), request(1, "prompts/get", params)))
+
+	for transport, msg := range map[string]map[string]any{"stdio": stdio, "http": httpResp} {
+		if !strings.Contains(fmt.Sprint(msg["result"]), want) {
+			t.Errorf("%s: guidance missing from %v", transport, msg)
+		}
+	}
+}
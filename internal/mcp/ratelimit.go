@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"math"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const rateLimitSweepInterval = time.Minute
+
+// rateLimiter is a per-client-IP token bucket. Each bucket refills at rate
+// tokens per second and holds at most burst tokens.
+type rateLimiter struct {
+	rate      float64
+	burst     float64
+	mu        sync.Mutex
+	buckets   map[netip.Addr]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rps float64) *rateLimiter {
+	return &rateLimiter{
+		rate:    rps,
+		burst:   max(1, math.Ceil(rps)),
+		buckets: map[netip.Addr]*tokenBucket{},
+	}
+}
+
+// allow takes a token for addr, or reports how long until one is available.
+func (l *rateLimiter) allow(addr netip.Addr, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[addr]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[addr] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(l.burst, b.tokens+elapsed*l.rate)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / l.rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// sweep drops buckets that have refilled completely, since a fresh bucket
+// behaves the same.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for addr, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, addr)
+		}
+	}
+}
+
+func (s *Server) allowHTTPRequest(w http.ResponseWriter, r *http.Request) bool {
+	if s.limiter == nil {
+		return true
+	}
+	addr, ok := clientIP(r, s.cfg.HTTPTrustedProxies)
+	if !ok {
+		return true
+	}
+	allowed, wait := s.limiter.allow(addr, time.Now())
+	if allowed {
+		return true
+	}
+	seconds := max(1, int(math.Ceil(wait.Seconds())))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	http.Error(w, "too many requests", http.StatusTooManyRequests)
+	return false
+}
+
+// clientIP returns the peer address of r. When the peer is a trusted proxy,
+// X-Forwarded-For is walked from the right and the first untrusted hop is
+// used instead, so a client cannot spoof its address by prepending entries.
+func clientIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	addr, ok := parseIP(r.RemoteAddr)
+	if !ok || !isTrustedProxy(addr, trusted) {
+		return addr, ok
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for _, hop := range slices.Backward(hops) {
+		hopAddr, ok := parseIP(strings.TrimSpace(hop))
+		if !ok {
+			break
+		}
+		addr = hopAddr
+		if !isTrustedProxy(addr, trusted) {
+			break
+		}
+	}
+	return addr, true
+}
+
+func parseIP(raw string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(raw); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func isTrustedProxy(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
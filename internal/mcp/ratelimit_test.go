@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllowsBurstThenThrottles(t *testing.T) {
+	l := newRateLimiter(2)
+	addr := netip.MustParseAddr("192.0.2.1")
+	now := time.Unix(1_700_000_000, 0)
+
+	for i := range 2 {
+		if ok, _ := l.allow(addr, now); !ok {
+			t.Fatalf("request %d throttled within the burst", i+1)
+		}
+	}
+	ok, wait := l.allow(addr, now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("third request: allowed = %v wait = %v", ok, wait)
+	}
+	if ok, _ := l.allow(netip.MustParseAddr("192.0.2.2"), now); !ok {
+		t.Fatal("another client throttled by the first one's bucket")
+	}
+	if ok, _ := l.allow(addr, now.Add(wait)); !ok {
+		t.Fatal("request after the refill wait throttled")
+	}
+}
+
+func TestHTTPRateLimitReturns429(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_HTTP_RATE_LIMIT_RPS", "1")
+	send := func(remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, s.cfg.HTTPPath, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		s.httpHandler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send("198.51.100.7:1000"); rec.Code != http.StatusOK {
+		t.Fatalf("first request status = %d", rec.Code)
+	}
+	rec := send("198.51.100.7:1001")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("second request status = %d Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if rec := send("198.51.100.8:1000"); rec.Code != http.StatusOK {
+		t.Fatalf("other client status = %d", rec.Code)
+	}
+}
+
+func TestHTTPRateLimitDisabledByDefault(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_HTTP_RATE_LIMIT_RPS", "")
+	for range 5 {
+		decodeHTTPResponse(t, postHTTP(t, s, `{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	}
+}
+
+func TestClientIPHonoursOnlyTrustedProxies(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	tests := []struct {
+		remote, forwarded, want string
+	}{
+		{"203.0.113.5:1234", "198.51.100.1", "203.0.113.5"},
+		{"10.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"10.0.0.1:1234", "6.6.6.6, 198.51.100.1, 10.0.0.2", "198.51.100.1"},
+		{"10.0.0.1:1234", "", "10.0.0.1"},
+		{"[::ffff:10.0.0.1]:1234", "198.51.100.1", "198.51.100.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got, ok := clientIP(r, trusted); !ok || got.String() != tt.want {
+			t.Errorf("remote %s forwarded %q: clientIP = %v, want %s", tt.remote, tt.forwarded, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package mcp
+
+import "sync"
+
+// renderCache keeps rendered content.md/content.txt bodies keyed by resource
+// URI. An entry is only valid for the bookmark's updated_at it was rendered
+// from, so a changed bookmark is re-rendered. Eviction is first-in first-out.
+type renderCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]renderEntry
+	order   []string
+}
+
+type renderEntry struct {
+	updatedAt string
+	text      string
+}
+
+func newRenderCache(max int) *renderCache {
+	return &renderCache{max: max, entries: map[string]renderEntry{}}
+}
+
+func (c *renderCache) get(uri, updatedAt string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[uri]
+	if !ok || entry.updatedAt != updatedAt {
+		return "", false
+	}
+	return entry.text, true
+}
+
+func (c *renderCache) put(uri, updatedAt, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[uri]; !ok {
+		c.order = append(c.order, uri)
+	}
+	c.entries[uri] = renderEntry{updatedAt: updatedAt, text: text}
+	for len(c.order) > c.max {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+}
@@ -0,0 +1,218 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func readText(t *testing.T, s *Server, uri string) string {
+	t.Helper()
+	result, rpcErr := s.readResource(context.Background(), uri)
+	if rpcErr != nil {
+		t.Fatalf("read %s: %s", uri, rpcErr.Message)
+	}
+	contents := result["contents"].([]map[string]any)
+	return contents[0]["text"].(string)
+}
+
+func articleUpstream() upstream {
+	return upstream{
+		bookmarks: map[string]map[string]any{
+			"b1": {"id": "b1", "title": "Article", "url": "https://example.com/a", "updated": "2024-01-01T00:00:00Z", "labels": []string{"go"}},
+		},
+		content: map[string]string{
+			"b1": `<p>The first paragraph of the article body.</p><p>A second one.</p><img src="https://example.com/i.png" alt="pic">`,
+		},
+	}
+}
+
+func TestContentMaxCharsAppliesAfterRendering(t *testing.T) {
+	s := newTestServer(t, articleUpstream().handler(t))
+
+	text := readText(t, s, "readeck://bookmark/b1/content.md?images=1&max_chars=500")
+	if !strings.Contains(text, "https://example.com/i.png") {
+		t.Fatalf("images missing under max_chars:\n%s", text)
+	}
+
+	text = readText(t, s, "readeck://bookmark/b1/content.md?max_chars=20&hashtags=1")
+	if !strings.Contains(text, "title: ") || !strings.Contains(text, "readeck_id: ") {
+		t.Fatalf("frontmatter truncated:\n%s", text)
+	}
+	_, body, _ := strings.Cut(strings.TrimPrefix(text, "---\n"), "---\n\n")
+	if !strings.HasSuffix(body, "…[truncated]") || strings.Contains(body, "#go") {
+		t.Fatalf("body not truncated after rendering: %q", body)
+	}
+
+	text = readText(t, s, "readeck://bookmark/b1/content.txt?max_chars=20")
+	if text != "The first paragraph…[truncated]" {
+		t.Fatalf("content.txt = %q", text)
+	}
+}
+
+func TestContentMarkdownCountsHighlights(t *testing.T) {
+	up := articleUpstream()
+	up.highlights = map[string][]map[string]any{
+		"b1": {{"id": "h1", "text": "first"}, {"id": "h2", "text": "second"}},
+	}
+	s := newTestServer(t, up.handler(t))
+
+	if text := readText(t, s, "readeck://bookmark/b1/content.md"); !strings.Contains(text, "highlight_count: 2\n") {
+		t.Fatalf("highlight_count missing:\n%s", text)
+	}
+
+	// A new highlight leaves updated_at alone but must not be served from
+	// the render cache.
+	up.highlights["b1"] = append(up.highlights["b1"], map[string]any{"id": "h3", "text": "third"})
+	if text := readText(t, s, "readeck://bookmark/b1/content.md"); !strings.Contains(text, "highlight_count: 3\n") {
+		t.Fatalf("stale highlight_count:\n%s", text)
+	}
+}
+
+func TestContentMarkdownSkipsHighlightsWhenNotInFrontmatter(t *testing.T) {
+	up := articleUpstream()
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/annotations") {
+			t.Errorf("highlights fetched without highlight_count in frontmatter")
+		}
+		up.handler(t)(w, r)
+	}, "READECK_FRONTMATTER_FIELDS", "title,url")
+
+	readText(t, s, "readeck://bookmark/b1/content.md")
+}
+
+func TestSummaryResource(t *testing.T) {
+	up := articleUpstream()
+	up.bookmarks["b2"] = map[string]any{"id": "b2", "title": "With excerpt", "url": "https://example.com/b", "excerpt": "An excerpt. It has three. Sentences here. And a fourth."}
+	s := newTestServer(t, up.handler(t))
+
+	if text := readText(t, s, "readeck://bookmark/b2/summary.txt"); text != "An excerpt. It has three. Sentences here." {
+		t.Fatalf("summary from excerpt = %q", text)
+	}
+	if text := readText(t, s, "readeck://bookmark/b1/summary.txt"); text != "The first paragraph of the article body. A second one." {
+		t.Fatalf("summary from content = %q", text)
+	}
+}
+
+func TestContentMarkdownFrontmatterFlag(t *testing.T) {
+	s := newTestServer(t, articleUpstream().handler(t))
+
+	for _, uri := range []string{"readeck://bookmark/b1/content.md", "readeck://bookmark/b1/content.md?frontmatter=1"} {
+		if text := readText(t, s, uri); !strings.HasPrefix(text, "---\ntitle: ") {
+			t.Fatalf("%s: frontmatter missing:\n%s", uri, text)
+		}
+	}
+	text := readText(t, s, "readeck://bookmark/b1/content.md?frontmatter=0")
+	if !strings.HasPrefix(text, "The first paragraph") || strings.Contains(text, "readeck_id:") {
+		t.Fatalf("frontmatter=0 kept the YAML block:\n%s", text)
+	}
+
+	if _, rpcErr := s.readResource(context.Background(), "readeck://bookmark/b1/content.md?frontmatter=maybe"); rpcErr == nil {
+		t.Fatal("frontmatter=maybe accepted")
+	}
+}
+
+func TestContentResourcesSkipContentOnCacheHit(t *testing.T) {
+	for _, tt := range []struct {
+		cacheSize string
+		want      []int32
+	}{
+		{"", []int32{1, 1, 2, 3}},
+		{"0", []int32{1, 2, 3, 4}},
+	} {
+		up := articleUpstream()
+		var hits atomic.Int32
+		s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/content") {
+				hits.Add(1)
+			}
+			up.handler(t)(w, r)
+		}, "MCP_RENDER_CACHE_SIZE", tt.cacheSize)
+
+		var got []int32
+		readText(t, s, "readeck://bookmark/b1/content.md")
+		got = append(got, hits.Load())
+		readText(t, s, "readeck://bookmark/b1/content.md")
+		got = append(got, hits.Load())
+		up.bookmarks["b1"]["updated"] = "2024-02-01T00:00:00Z"
+		readText(t, s, "readeck://bookmark/b1/content.md")
+		got = append(got, hits.Load())
+		readText(t, s, "readeck://bookmark/b1/content.txt")
+		got = append(got, hits.Load())
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("cache size %q: content hits after each read = %v, want %v", tt.cacheSize, got, tt.want)
+		}
+	}
+}
+
+func TestRenderCacheEvictsOldestFirst(t *testing.T) {
+	c := newRenderCache(2)
+	c.put("a", "t1", "A")
+	c.put("b", "t1", "B")
+	c.put("a", "t2", "A2")
+	c.put("c", "t1", "C")
+	if _, ok := c.get("a", "t2"); ok {
+		t.Fatal("oldest entry survived eviction")
+	}
+	if text, ok := c.get("b", "t1"); !ok || text != "B" {
+		t.Fatalf("b = %q, %v", text, ok)
+	}
+	if _, ok := c.get("b", "t2"); ok {
+		t.Fatal("entry served for a different updated_at")
+	}
+}
+
+func TestResourceResolveKinds(t *testing.T) {
+	var hits atomic.Int32
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.NotFound(w, r)
+	})
+
+	supported := []struct {
+		uri, id, kind string
+		options       map[string]any
+	}{
+		{"readeck://bookmark/b1", "b1", "metadata", map[string]any{}},
+		{"readeck://bookmark/b1/content.md?max_chars=50&tags=1&frontmatter=0", "b1", "content.md",
+			map[string]any{"max_chars": float64(50), "tags": true, "frontmatter": false, "images": false}},
+		{"readeck://bookmark/b1/content.txt?max_chars=10", "b1", "content.txt", map[string]any{"max_chars": float64(10)}},
+		{"readeck://bookmark/b1/summary.txt", "b1", "summary.txt", map[string]any{}},
+		{"readeck://bookmark/b1/highlights.json?order=position", "b1", "highlights.json", map[string]any{"order": "position"}},
+		{"readeck://bookmark/b1/highlights.md", "b1", "highlights.md", map[string]any{}},
+	}
+	for _, tt := range supported {
+		out := mustCallTool(t, s, "readeck.resource.resolve", `{"uri":"`+tt.uri+`"}`)
+		if out["supported"] != true || out["id"] != tt.id || out["kind"] != tt.kind || !reflect.DeepEqual(out["options"], tt.options) {
+			t.Errorf("%s: resolved to %v", tt.uri, out)
+		}
+	}
+
+	unsupported := []struct{ uri, reason string }{
+		{"readeck://bookmark/b1/content.pdf", `unsupported kind "content.pdf"`},
+		{"readeck://bookmark/b1/content.txt?max_chars=0", "max_chars must be a positive integer"},
+		{"readeck://bookmark/b1/content.md?images=maybe", "images must be a boolean"},
+		{"readeck://bookmark/b1/highlights.md?order=newest", "order must be position"},
+		{"readeck://bookmark/", "missing id"},
+		{"https://example.com/b1", "uri must start with readeck://bookmark/"},
+	}
+	for _, tt := range unsupported {
+		out := mustCallTool(t, s, "readeck.resource.resolve", `{"uri":"`+tt.uri+`"}`)
+		if out["supported"] != false || out["reason"] != tt.reason {
+			t.Errorf("%s: resolved to %v, want reason %q", tt.uri, out, tt.reason)
+		}
+	}
+
+	_, err := callTool(t, s, "readeck.resource.resolve", `{"uri":" "}`)
+	assertInputError(t, err, "uri is required")
+	if _, err := callTool(t, s, "readeck.resource.resolve", `{"uri":"readeck://bookmark/%zz"}`); err == nil || !strings.Contains(err.Error(), "invalid resource uri") {
+		t.Fatalf("malformed uri err = %v", err)
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("resolve made %d upstream requests", n)
+	}
+}
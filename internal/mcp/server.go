@@ -12,10 +12,13 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 
 	"github.com/akrisanov/readeck-mcp/internal/citation"
 	"github.com/akrisanov/readeck-mcp/internal/config"
@@ -24,40 +27,94 @@ import (
 )
 
 type Server struct {
-	cfg     config.Config
-	client  *readeck.Client
-	logger  *log.Logger
-	in      io.Reader
-	out     io.Writer
-	writeMu sync.Mutex
+	cfg         config.Config
+	client      *readeck.Client
+	logger      *log.Logger
+	in          io.Reader
+	out         io.Writer
+	writeMu     sync.Mutex
+	subs        *subscriptionSet
+	sessions    *sessionStore
+	initialized atomic.Bool
+	middleware  []Middleware
+	limiter     *rateLimiter
+	renders     *renderCache
 }
 
 func NewServer(cfg config.Config, client *readeck.Client, logger *log.Logger) *Server {
 	if logger == nil {
 		logger = log.New(io.Discard, "", 0)
 	}
-	return &Server{
-		cfg:    cfg,
-		client: client,
-		logger: logger,
-		in:     os.Stdin,
-		out:    os.Stdout,
+	s := &Server{
+		cfg:      cfg,
+		client:   client,
+		logger:   logger,
+		in:       os.Stdin,
+		out:      os.Stdout,
+		subs:     newSubscriptionSet(),
+		sessions: newSessionStore(),
 	}
+	if cfg.HTTPRateLimitRPS > 0 {
+		s.limiter = newRateLimiter(cfg.HTTPRateLimitRPS)
+	}
+	if cfg.RenderCacheSize > 0 {
+		s.renders = newRenderCache(cfg.RenderCacheSize)
+	}
+	return s
+}
+
+const shutdownDrainTimeout = 10 * time.Second
+
+type readResult struct {
+	payload []byte
+	err     error
 }
 
+// Run serves MCP over the server's input and output until ctx is done or the
+// input ends. On return the input is interrupted so the reader goroutine
+// exits; an input that supports neither read deadlines nor Close is left
+// blocked until its next read returns.
 func (s *Server) Run(ctx context.Context) error {
-	reader := bufio.NewReader(s.in)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if d, ok := s.in.(readDeadliner); ok {
+		_ = d.SetReadDeadline(time.Time{})
+	}
+	defer s.stopReading()
+	go s.pollSubscriptions(ctx)
+	if s.cfg.Keepalive > 0 {
+		go s.keepalive(ctx)
+	}
+
+	// Handlers outlive ctx so in-flight requests can finish during shutdown.
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+	var inflight sync.WaitGroup
+	defer s.drain(&inflight, cancelHandlers)
+
+	messages := make(chan readResult)
+	go s.readLoop(ctx, bufio.NewReader(s.in), messages)
+
 	for {
-		payload, err := readMessage(reader)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
+		var msg readResult
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg = <-messages:
+		}
+		if msg.err != nil {
+			if errors.Is(msg.err, io.EOF) {
 				return nil
 			}
-			return err
+			return msg.err
 		}
 
 		var req rpcRequest
-		if err := json.Unmarshal(payload, &req); err != nil {
+		if err := json.Unmarshal(msg.payload, &req); err != nil {
+			if json.Valid(msg.payload) {
+				_ = s.writeError(recoverRequestID(msg.payload), -32600, "invalid request", nil)
+				continue
+			}
 			_ = s.writeError(nil, -32700, "parse error", nil)
 			continue
 		}
@@ -72,14 +129,92 @@ func (s *Server) Run(ctx context.Context) error {
 			continue
 		}
 
-		if err := s.handleRequest(ctx, req); err != nil {
-			if writeErr := s.writeError(req.ID, -32000, err.Error(), nil); writeErr != nil {
-				return writeErr
+		if req.Method == "initialize" {
+			s.initialized.Store(true)
+		} else if s.cfg.StrictInit && !s.initialized.Load() && !allowedBeforeInit(req.Method) {
+			_ = s.writeError(req.ID, -32002, "server not initialized", nil)
+			continue
+		}
+
+		inflight.Add(1)
+		go func(req rpcRequest) {
+			defer inflight.Done()
+			if err := s.handleRequest(handlerCtx, req); err != nil {
+				if writeErr := s.writeError(req.ID, -32000, err.Error(), nil); writeErr != nil {
+					s.logger.Printf("request_id=%s write failed: %v", req.idString(), writeErr)
+				}
+			}
+		}(req)
+	}
+}
+
+type readDeadliner interface {
+	SetReadDeadline(time.Time) error
+}
+
+// stopReading unblocks readLoop. Inputs with read deadlines, such as
+// os.Stdin on a pipe, are interrupted and stay usable for another Run;
+// other inputs are closed if they can be.
+func (s *Server) stopReading() {
+	if d, ok := s.in.(readDeadliner); ok && d.SetReadDeadline(time.Now()) == nil {
+		return
+	}
+	if c, ok := s.in.(io.Closer); ok {
+		_ = c.Close()
+	}
+}
+
+func (s *Server) readLoop(ctx context.Context, reader *bufio.Reader, out chan<- readResult) {
+	for {
+		payload, err := readMessage(reader)
+		select {
+		case out <- readResult{payload: payload, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) drain(inflight *sync.WaitGroup, cancel context.CancelFunc) {
+	done := make(chan struct{})
+	go func() {
+		inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(shutdownDrainTimeout):
+		s.logger.Printf("shutdown: cancelling in-flight requests after %s", shutdownDrainTimeout)
+		cancel()
+		<-done
+	}
+}
+
+func (s *Server) keepalive(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Keepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.writeMessage(rpcNotification{JSONRPC: "2.0", Method: "notifications/ping"}); err != nil {
+				s.logger.Printf("keepalive write failed: %v", err)
+				return
 			}
 		}
 	}
 }
 
+func allowedBeforeInit(method string) bool {
+	return method == "initialize" || method == "ping"
+}
+
 func (s *Server) handleNotification(req rpcRequest) {
 	if req.Method == "notifications/initialized" || req.Method == "initialized" {
 		s.logger.Printf("client initialized")
@@ -111,6 +246,10 @@ func (s *Server) handleRequest(ctx context.Context, req rpcRequest) error {
 		return s.handleResourcesList(req)
 	case "resources/read":
 		return s.handleResourcesRead(ctx, req)
+	case "resources/subscribe":
+		return s.handleResourcesSubscribe(ctx, req)
+	case "resources/unsubscribe":
+		return s.handleResourcesUnsubscribe(req)
 	case "prompts/list":
 		return s.handlePromptsList(req)
 	case "prompts/get":
@@ -121,11 +260,20 @@ func (s *Server) handleRequest(ctx context.Context, req rpcRequest) error {
 }
 
 func (s *Server) handleInitialize(req rpcRequest) error {
-	result := map[string]any{
-		"protocolVersion": s.cfg.Protocol,
+	return s.writeResult(req.ID, s.initializeResult(req.Params, true))
+}
+
+func (s *Server) initializeResult(rawParams json.RawMessage, subscribe bool) map[string]any {
+	var params struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(rawParams, &params)
+
+	return map[string]any{
+		"protocolVersion": s.negotiateProtocol(params.ProtocolVersion),
 		"capabilities": map[string]any{
 			"tools":     map[string]any{},
-			"resources": map[string]any{"subscribe": false},
+			"resources": map[string]any{"subscribe": subscribe},
 			"prompts":   map[string]any{},
 		},
 		"serverInfo": map[string]any{
@@ -133,48 +281,214 @@ func (s *Server) handleInitialize(req rpcRequest) error {
 			"version": s.cfg.ServerVersion,
 		},
 	}
-	return s.writeResult(req.ID, result)
+}
+
+func (s *Server) negotiateProtocol(requested string) string {
+	for _, supported := range s.cfg.Protocols {
+		if requested == supported {
+			return requested
+		}
+	}
+	return s.cfg.Protocol
 }
 
 func (s *Server) handleToolsList(req rpcRequest) error {
-	tools := []map[string]any{
+	return s.writeResult(req.ID, map[string]any{"tools": s.tools()})
+}
+
+func toolDefinitions() []map[string]any {
+	return []map[string]any{
+		{
+			"name":         "readeck.search",
+			"description":  "Search and filter bookmarks.",
+			"inputSchema":  searchInputSchema(),
+			"outputSchema": searchOutputSchema(),
+		},
+		{
+			"name":         "readeck.count",
+			"description":  "Count bookmarks matching the same filters as readeck.search.",
+			"inputSchema":  countInputSchema(),
+			"outputSchema": countOutputSchema(),
+		},
+		{
+			"name":         "readeck.get",
+			"description":  "Fetch one bookmark with optional content and highlights.",
+			"inputSchema":  getInputSchema(),
+			"outputSchema": getOutputSchema(),
+		},
+		{
+			"name":         "readeck.content.check",
+			"description":  "Fetch a bookmark's content and report its size and whether the scrape looks truncated.",
+			"inputSchema":  contentCheckInputSchema(),
+			"outputSchema": contentCheckOutputSchema(),
+		},
+		{
+			"name":         "readeck.links",
+			"description":  "List the outbound links in a bookmark's content, resolved to absolute URLs.",
+			"inputSchema":  linksInputSchema(),
+			"outputSchema": linksOutputSchema(),
+		},
+		{
+			"name":         "readeck.resource.resolve",
+			"description":  "Parse a readeck:// resource URI and report its id, kind and options without fetching it.",
+			"inputSchema":  resourceResolveInputSchema(),
+			"outputSchema": resourceResolveOutputSchema(),
+		},
+		{
+			"name":         "readeck.diff",
+			"description":  "Compare a previously fetched bookmark with its current state.",
+			"inputSchema":  diffInputSchema(),
+			"outputSchema": diffOutputSchema(),
+		},
+		{
+			"name":         "readeck.archive",
+			"description":  "Archive or unarchive a bookmark.",
+			"inputSchema":  archiveInputSchema(),
+			"outputSchema": archiveOutputSchema(),
+		},
+		{
+			"name":         "readeck.labels.list",
+			"description":  "List all labels.",
+			"inputSchema":  labelsListInputSchema(),
+			"outputSchema": labelsListOutputSchema(),
+		},
+		{
+			"name":         "readeck.labels.suggest",
+			"description":  "Suggest existing labels whose names appear in a bookmark's title or content.",
+			"inputSchema":  labelsSuggestInputSchema(),
+			"outputSchema": labelsSuggestOutputSchema(),
+		},
+		{
+			"name":         "readeck.related",
+			"description":  "Find other bookmarks sharing labels with a bookmark, most shared labels first.",
+			"inputSchema":  relatedInputSchema(),
+			"outputSchema": relatedOutputSchema(),
+		},
 		{
-			"name":        "readeck.search",
-			"description": "Search and filter bookmarks.",
-			"inputSchema": searchInputSchema(),
+			"name":         "readeck.labels.set",
+			"description":  "Replace labels on a bookmark.",
+			"inputSchema":  labelsSetInputSchema(),
+			"outputSchema": labelsSetOutputSchema(),
 		},
 		{
-			"name":        "readeck.get",
-			"description": "Fetch one bookmark with optional content and highlights.",
-			"inputSchema": getInputSchema(),
+			"name":         "readeck.collections.list",
+			"description":  "List bookmark collections. Empty when the Readeck instance has no collections.",
+			"inputSchema":  collectionsListInputSchema(),
+			"outputSchema": collectionsListOutputSchema(),
 		},
 		{
-			"name":        "readeck.archive",
-			"description": "Archive or unarchive a bookmark.",
-			"inputSchema": archiveInputSchema(),
+			"name":         "readeck.highlights.list",
+			"description":  "List annotations/highlights globally or per bookmark, with optional date and text filtering.",
+			"inputSchema":  highlightsListInputSchema(),
+			"outputSchema": highlightsListOutputSchema(),
 		},
 		{
-			"name":        "readeck.labels.list",
-			"description": "List all labels.",
-			"inputSchema": labelsListInputSchema(),
+			"name":         "readeck.highlights.colors",
+			"description":  "List highlight colors in use plus the known palette.",
+			"inputSchema":  highlightsColorsInputSchema(),
+			"outputSchema": highlightsColorsOutputSchema(),
 		},
 		{
-			"name":        "readeck.labels.set",
-			"description": "Replace labels on a bookmark.",
-			"inputSchema": labelsSetInputSchema(),
+			"name":         "readeck.highlights.digest",
+			"description":  "Build a Markdown digest of a bookmark's highlights and notes.",
+			"inputSchema":  highlightsDigestInputSchema(),
+			"outputSchema": markdownOutputSchema(),
 		},
 		{
-			"name":        "readeck.highlights.list",
-			"description": "List annotations/highlights globally or per bookmark, with optional date filtering.",
-			"inputSchema": highlightsListInputSchema(),
+			"name":         "readeck.highlights.since",
+			"description":  "List highlights created or edited after a timestamp, oldest first, for incremental sync.",
+			"inputSchema":  highlightsSinceInputSchema(),
+			"outputSchema": highlightsSinceOutputSchema(),
 		},
 		{
-			"name":        "readeck.cite",
-			"description": "Generate citations for a bookmark in multiple styles.",
-			"inputSchema": citeInputSchema(),
+			"name":         "readeck.changed_since",
+			"description":  "List bookmarks created or updated after a timestamp, oldest first, for incremental sync.",
+			"inputSchema":  changedSinceInputSchema(),
+			"outputSchema": changedSinceOutputSchema(),
+		},
+		{
+			"name":         "readeck.export.anki",
+			"description":  "Export highlights as Anki-importable TSV (quote, then note or citation).",
+			"inputSchema":  exportAnkiInputSchema(),
+			"outputSchema": exportAnkiOutputSchema(),
+		},
+		{
+			"name":         "readeck.cite",
+			"description":  "Generate citations for a bookmark in multiple styles.",
+			"inputSchema":  citeInputSchema(),
+			"outputSchema": citeOutputSchema(),
+		},
+		{
+			"name":         "readeck.cite.search",
+			"description":  "Run a search and cite every result in one style, for reading-list bibliographies.",
+			"inputSchema":  citeSearchInputSchema(),
+			"outputSchema": citeSearchOutputSchema(),
+		},
+		{
+			"name":         "readeck.bibliography",
+			"description":  "Build one formatted reference list from bookmark IDs or, when ids is omitted, a search.",
+			"inputSchema":  bibliographyInputSchema(),
+			"outputSchema": bibliographyOutputSchema(),
+		},
+		{
+			"name":         "readeck.cite.styles",
+			"description":  "List supported citation styles with labels and sample output.",
+			"inputSchema":  citeStylesInputSchema(),
+			"outputSchema": citeStylesOutputSchema(),
+		},
+	}
+}
+
+// tools returns the advertised tool list; debug-only tools are included
+// when MCP_DEBUG is set.
+func (s *Server) tools() []map[string]any {
+	tools := toolDefinitions()
+	if s.cfg.Debug {
+		tools = append(tools, map[string]any{
+			"name":         "readeck.diagnostics",
+			"description":  "Show non-secret effective configuration and client settings.",
+			"inputSchema":  map[string]any{"type": "object", "properties": map[string]any{}},
+			"outputSchema": diagnosticsOutputSchema(),
+		})
+	}
+	return tools
+}
+
+// diagnostics reports the effective settings and whether Readeck answers a
+// one-label listing; an unreachable upstream is reported, not returned as an
+// error.
+func (s *Server) diagnostics(ctx context.Context) map[string]any {
+	names := make([]string, 0)
+	for _, tool := range s.tools() {
+		names = append(names, tool["name"].(string))
+	}
+	client := s.client.Diagnostics()
+
+	upstream := map[string]any{"reachable": true}
+	start := time.Now()
+	if _, err := s.client.ListLabels(ctx, 1, ""); err != nil {
+		upstream["reachable"] = false
+		upstream["error"] = err.Error()
+	}
+	upstream["latency_ms"] = time.Since(start).Milliseconds()
+
+	return map[string]any{
+		"version":  s.cfg.ServerVersion,
+		"upstream": upstream,
+		"limits": map[string]any{
+			"max_page_size":        s.cfg.MaxPageSize,
+			"default_search_limit": s.cfg.DefaultSearchLimit,
+			"default_list_limit":   s.cfg.DefaultListLimit,
+			"highlight_scan_cap":   s.cfg.HighlightScanCap,
+			"max_label_length":     s.cfg.MaxLabelLength,
+			"max_concurrency":      client["max_concurrency"],
+			"rate_limit_rps":       s.cfg.RateLimitRPS,
+			"timeout_seconds":      s.cfg.Timeout.Seconds(),
 		},
+		"config": s.cfg.Redacted(),
+		"client": client,
+		"tools":  names,
 	}
-	return s.writeResult(req.ID, map[string]any{"tools": tools})
 }
 
 func (s *Server) handleToolsCall(ctx context.Context, req rpcRequest) error {
@@ -182,6 +496,9 @@ func (s *Server) handleToolsCall(ctx context.Context, req rpcRequest) error {
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return s.writeError(req.ID, -32602, "invalid params", nil)
 	}
+	if progress := s.progressReporter(params.Meta.ProgressToken); progress != nil {
+		ctx = withProgress(ctx, progress)
+	}
 
 	result, err := s.executeTool(ctx, params.Name, params.Arguments)
 	if err != nil {
@@ -196,7 +513,7 @@ func (s *Server) handleToolsCall(ctx context.Context, req rpcRequest) error {
 		return s.writeResult(req.ID, payload)
 	}
 
-	outText := mustJSON(result)
+	outText := mustJSON(result, s.cfg.CompactJSON)
 	payload := map[string]any{
 		"content":           []map[string]any{{"type": "text", "text": outText}},
 		"structuredContent": result,
@@ -208,43 +525,47 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 	switch name {
 	case "readeck.search":
 		var in struct {
-			Query     string   `json:"query"`
-			Title     string   `json:"title"`
-			Text      string   `json:"text"`
-			Labels    []string `json:"labels"`
-			Archived  string   `json:"archived"`
-			Favorites *bool    `json:"favorites"`
-			Sort      string   `json:"sort"`
-			Limit     int      `json:"limit"`
-			Cursor    string   `json:"cursor"`
+			searchArgs
+			IncludeContent bool `json:"include_content"`
 		}
 		if err := decodeArgs(args, &in); err != nil {
 			return nil, err
 		}
-		opts := readeck.SearchOptions{
-			Query:     in.Query,
-			Title:     in.Title,
-			Text:      in.Text,
-			Labels:    in.Labels,
-			Archived:  readeck.ArchivedMode(in.Archived),
-			Favorites: in.Favorites,
-			Sort:      readeck.SortMode(in.Sort),
-			Limit:     in.Limit,
-			Cursor:    in.Cursor,
+		opts, err := in.options(s.cfg)
+		if err != nil {
+			return nil, err
 		}
-		if opts.Archived == "" {
-			opts.Archived = readeck.ArchivedExclude
+		if in.IncludeContent {
+			return s.searchWithContent(ctx, opts)
 		}
 		return s.client.Search(ctx, opts)
 
+	case "readeck.count":
+		var in searchArgs
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		opts, err := in.options(s.cfg)
+		if err != nil {
+			return nil, err
+		}
+		return s.client.Count(ctx, opts)
+
 	case "readeck.get":
 		var in struct {
 			ID      string `json:"id"`
 			Include struct {
-				Content    *bool `json:"content"`
-				Highlights *bool `json:"highlights"`
-				Labels     *bool `json:"labels"`
+				Content       *bool  `json:"content"`
+				Highlights    *bool  `json:"highlights"`
+				Labels        *bool  `json:"labels"`
+				Icon          *bool  `json:"icon"`
+				Images        bool   `json:"images"`
+				ContentFormat string `json:"content_format"`
 			} `json:"include"`
+			ContentRange *struct {
+				Start  int `json:"start"`
+				Length int `json:"length"`
+			} `json:"content_range"`
 		}
 		if err := decodeArgs(args, &in); err != nil {
 			return nil, err
@@ -262,11 +583,121 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 		if in.Include.Labels != nil {
 			include.Labels = *in.Include.Labels
 		}
+		if in.Include.Icon != nil {
+			include.Icon = *in.Include.Icon
+		}
+		if in.Include.Images {
+			include.Content = true
+		}
+		format := strings.TrimSpace(in.Include.ContentFormat)
+		switch format {
+		case "", "text":
+		case "markdown", "both":
+			include.Content = true
+		default:
+			return nil, newInputError("include.content_format must be one of text, markdown, both")
+		}
+		if in.ContentRange != nil {
+			if in.ContentRange.Start < 0 {
+				return nil, newInputError("content_range.start must be >= 0")
+			}
+			if in.ContentRange.Length <= 0 {
+				return nil, newInputError("content_range.length must be > 0")
+			}
+			include.Content = true
+		}
 		bookmark, err := s.client.GetBookmark(ctx, in.ID, include)
 		if err != nil {
 			return nil, err
 		}
-		return map[string]any{"bookmark": bookmark}, nil
+		if in.Include.Images {
+			bookmark.Images = render.Images(bookmark)
+		}
+		result := map[string]any{}
+		if format == "markdown" || format == "both" {
+			result["content_markdown"] = render.BookmarkContentMarkdown(bookmark, render.MarkdownOptions{
+				FrontmatterFields: s.cfg.FrontmatterFields,
+				Images:            in.Include.Images,
+				Paragraphs:        s.cfg.TextParagraphs,
+			})
+		}
+		if format == "both" {
+			result["content_text"] = render.ContentText(bookmark, s.textOptions())
+		}
+		if in.ContentRange != nil {
+			text, contentRange := sliceContent(render.ContentText(bookmark, s.textOptions()), in.ContentRange.Start, in.ContentRange.Length)
+			bookmark.ContentText = text
+			bookmark.ContentHTML = ""
+			result["content_range"] = contentRange
+		}
+		result["bookmark"] = bookmark
+		return result, nil
+
+	case "readeck.content.check":
+		var in struct {
+			ID string `json:"id"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(in.ID) == "" {
+			return nil, newInputError("id is required")
+		}
+		return s.checkContent(ctx, in.ID)
+
+	case "readeck.links":
+		var in struct {
+			ID string `json:"id"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(in.ID) == "" {
+			return nil, newInputError("id is required")
+		}
+		bookmark, err := s.client.GetBookmark(ctx, in.ID, readeck.IncludeOptions{Content: true})
+		if err != nil {
+			return nil, err
+		}
+		links := render.Links(bookmark)
+		if links == nil {
+			links = []render.Link{}
+		}
+		return map[string]any{"id": bookmark.ID, "links": links, "count": len(links)}, nil
+
+	case "readeck.resource.resolve":
+		var in struct {
+			URI string `json:"uri"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		return resolveResourceURI(in.URI)
+
+	case "readeck.diff":
+		var in struct {
+			ID       string            `json:"id"`
+			Previous *readeck.Bookmark `json:"previous"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(in.ID) == "" {
+			return nil, newInputError("id is required")
+		}
+		if in.Previous == nil {
+			return nil, newInputError("previous is required")
+		}
+		current, err := s.client.GetBookmark(ctx, in.ID, readeck.IncludeOptions{
+			Content:    in.Previous.ContentText != "",
+			Highlights: true,
+			Labels:     true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		changes := readeck.DiffBookmarks(*in.Previous, current)
+		return map[string]any{"id": current.ID, "changed": len(changes) > 0, "changes": changes}, nil
 
 	case "readeck.archive":
 		var in struct {
@@ -295,6 +726,42 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 		}
 		return s.client.ListLabels(ctx, in.Limit, in.Cursor)
 
+	case "readeck.collections.list":
+		var in struct {
+			Limit  int    `json:"limit"`
+			Cursor string `json:"cursor"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		return s.client.ListCollections(ctx, in.Limit, in.Cursor)
+
+	case "readeck.labels.suggest":
+		var in struct {
+			ID  string `json:"id"`
+			Max int    `json:"max"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(in.ID) == "" {
+			return nil, newInputError("id is required")
+		}
+		return s.suggestLabels(ctx, in.ID, in.Max)
+
+	case "readeck.related":
+		var in struct {
+			ID    string `json:"id"`
+			Limit int    `json:"limit"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(in.ID) == "" {
+			return nil, newInputError("id is required")
+		}
+		return s.relatedBookmarks(ctx, in.ID, in.Limit)
+
 	case "readeck.labels.set":
 		var in struct {
 			ID     string   `json:"id"`
@@ -309,6 +776,9 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 		if len(in.Labels) == 0 {
 			return nil, newInputError("labels is required")
 		}
+		if err := validateLabels(in.Labels, s.cfg.MaxLabelLength); err != nil {
+			return nil, err
+		}
 		return s.client.SetLabels(ctx, in.ID, in.Labels)
 
 	case "readeck.highlights.list":
@@ -319,6 +789,7 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 			Date       string `json:"date"`
 			DateFrom   string `json:"date_from"`
 			DateTo     string `json:"date_to"`
+			Query      string `json:"query"`
 		}
 		if err := decodeArgs(args, &in); err != nil {
 			return nil, err
@@ -326,11 +797,83 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 		if in.Offset < 0 {
 			return nil, newInputError("offset must be >= 0")
 		}
-		dateFilter, err := parseHighlightDateFilter(in.Date, in.DateFrom, in.DateTo)
+		if err := validateLength("query", in.Query, maxSearchTextLen); err != nil {
+			return nil, err
+		}
+		filter, err := parseHighlightDateFilter(in.Date, in.DateFrom, in.DateTo)
 		if err != nil {
 			return nil, newInputError(err.Error())
 		}
-		return s.listHighlights(ctx, in.BookmarkID, in.Limit, in.Offset, dateFilter)
+		filter.Query = strings.TrimSpace(in.Query)
+		return s.listHighlights(ctx, in.BookmarkID, in.Limit, in.Offset, filter)
+
+	case "readeck.highlights.colors":
+		return s.highlightColors(ctx)
+
+	case "readeck.highlights.digest":
+		var in struct {
+			BookmarkID string `json:"bookmark_id"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(in.BookmarkID) == "" {
+			return nil, newInputError("bookmark_id is required")
+		}
+		bookmark, err := s.client.GetBookmark(ctx, in.BookmarkID, readeck.IncludeOptions{Highlights: true})
+		if err != nil {
+			return nil, err
+		}
+		bookmark.Highlights = render.SortHighlightsByPosition(bookmark.Highlights)
+		return map[string]any{"markdown": render.HighlightsDigest(bookmark)}, nil
+
+	case "readeck.highlights.since":
+		var in struct {
+			Since string `json:"since"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		since, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(in.Since))
+		if err != nil {
+			return nil, newInputError("since must be RFC3339")
+		}
+		return s.highlightsSince(ctx, since.UTC())
+
+	case "readeck.changed_since":
+		var in struct {
+			Since string `json:"since"`
+			Limit int    `json:"limit"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		since, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(in.Since))
+		if err != nil {
+			return nil, newInputError("since must be RFC3339")
+		}
+		if in.Limit < 0 {
+			return nil, newInputError("limit must be >= 0")
+		}
+		return s.bookmarksChangedSince(ctx, since.UTC(), in.Limit)
+
+	case "readeck.export.anki":
+		var in struct {
+			BookmarkID string `json:"bookmark_id"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		return s.exportAnki(ctx, strings.TrimSpace(in.BookmarkID))
+
+	case "readeck.diagnostics":
+		if !s.cfg.Debug {
+			return nil, newInputError("unknown tool: " + name)
+		}
+		return s.diagnostics(ctx), nil
+
+	case "readeck.cite.styles":
+		return map[string]any{"styles": citationStyles(s.cfg.CompactJSON)}, nil
 
 	case "readeck.cite":
 		var in struct {
@@ -339,6 +882,7 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 			Quote      string `json:"quote"`
 			Style      string `json:"style"`
 			AccessedAt string `json:"accessed_at"`
+			TitleCase  bool   `json:"title_case"`
 		}
 		if err := decodeArgs(args, &in); err != nil {
 			return nil, err
@@ -346,6 +890,9 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 		if strings.TrimSpace(in.BookmarkID) == "" {
 			return nil, newInputError("bookmark_id is required")
 		}
+		if err := validateLength("quote", in.Quote, maxQuoteLength); err != nil {
+			return nil, err
+		}
 		bookmark, err := s.client.GetBookmark(ctx, in.BookmarkID, readeck.IncludeOptions{Highlights: true, Labels: true})
 		if err != nil {
 			return nil, err
@@ -364,33 +911,78 @@ func (s *Server) executeTool(ctx context.Context, name string, args json.RawMess
 			}
 		}
 
-		accessedAt := time.Now().UTC()
-		if strings.TrimSpace(in.AccessedAt) != "" {
-			parsed, err := time.Parse(time.RFC3339, in.AccessedAt)
-			if err != nil {
-				return nil, newInputError("accessed_at must be RFC3339")
-			}
-			accessedAt = parsed
+		accessedAt, err := parseAccessedAt(in.AccessedAt)
+		if err != nil {
+			return nil, err
 		}
 
 		style := readeck.CitationStyle(strings.TrimSpace(in.Style))
-		cite := citation.Generate(bookmark, selected, in.Quote, style, accessedAt)
+		cite := citation.Generate(bookmark, selected, in.Quote, style, accessedAt, citation.Options{
+			CompactJSON: s.cfg.CompactJSON,
+			TitleCase:   in.TitleCase,
+		})
 		return map[string]any{"citation": cite}, nil
 
+	case "readeck.cite.search":
+		var in struct {
+			searchArgs
+			Style      string `json:"style"`
+			AccessedAt string `json:"accessed_at"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		opts, err := in.options(s.cfg)
+		if err != nil {
+			return nil, err
+		}
+		accessedAt, err := parseAccessedAt(in.AccessedAt)
+		if err != nil {
+			return nil, err
+		}
+		return s.citeSearch(ctx, opts, readeck.CitationStyle(strings.TrimSpace(in.Style)), accessedAt)
+
+	case "readeck.bibliography":
+		var in struct {
+			searchArgs
+			IDs        []string `json:"ids"`
+			Style      string   `json:"style"`
+			AccessedAt string   `json:"accessed_at"`
+		}
+		if err := decodeArgs(args, &in); err != nil {
+			return nil, err
+		}
+		if len(in.IDs) > maxCitedResults {
+			return nil, newInputError(fmt.Sprintf("ids must have at most %d entries", maxCitedResults))
+		}
+		opts, err := in.options(s.cfg)
+		if err != nil {
+			return nil, err
+		}
+		accessedAt, err := parseAccessedAt(in.AccessedAt)
+		if err != nil {
+			return nil, err
+		}
+		return s.bibliography(ctx, in.IDs, opts, readeck.CitationStyle(strings.TrimSpace(in.Style)), accessedAt)
+
 	default:
 		return nil, newInputError("unknown tool: " + name)
 	}
 }
 
 func (s *Server) handleResourcesList(req rpcRequest) error {
-	resources := []map[string]any{
+	return s.writeResult(req.ID, map[string]any{"resources": resourceTemplates()})
+}
+
+func resourceTemplates() []map[string]any {
+	return []map[string]any{
 		{"uriTemplate": "readeck://bookmark/{id}", "name": "Bookmark metadata", "mimeType": "application/json"},
 		{"uriTemplate": "readeck://bookmark/{id}/content.md", "name": "Bookmark content markdown", "mimeType": "text/markdown"},
 		{"uriTemplate": "readeck://bookmark/{id}/content.txt", "name": "Bookmark content text", "mimeType": "text/plain"},
+		{"uriTemplate": "readeck://bookmark/{id}/summary.txt", "name": "Bookmark summary text", "mimeType": "text/plain"},
 		{"uriTemplate": "readeck://bookmark/{id}/highlights.json", "name": "Bookmark highlights JSON", "mimeType": "application/json"},
 		{"uriTemplate": "readeck://bookmark/{id}/highlights.md", "name": "Bookmark highlights markdown", "mimeType": "text/markdown"},
 	}
-	return s.writeResult(req.ID, map[string]any{"resources": resources})
 }
 
 func (s *Server) handleResourcesRead(ctx context.Context, req rpcRequest) error {
@@ -400,23 +992,55 @@ func (s *Server) handleResourcesRead(ctx context.Context, req rpcRequest) error
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return s.writeError(req.ID, -32602, "invalid params", nil)
 	}
-	if strings.TrimSpace(params.URI) == "" {
-		return s.writeError(req.ID, -32602, "uri is required", nil)
+	result, rpcErr := s.readResource(ctx, params.URI)
+	if rpcErr != nil {
+		return s.writeError(req.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+	}
+	return s.writeResult(req.ID, result)
+}
+
+func (s *Server) readResource(ctx context.Context, uri string) (map[string]any, *rpcError) {
+	if strings.TrimSpace(uri) == "" {
+		return nil, &rpcError{Code: -32602, Message: "uri is required"}
 	}
 
-	parsed, err := parseReadeckURI(params.URI)
+	parsed, err := parseReadeckURI(uri)
 	if err != nil {
-		return s.writeError(req.ID, -32602, "invalid resource uri", nil)
+		return nil, &rpcError{Code: -32602, Message: "invalid resource uri"}
 	}
 
+	// Rendered content is cached per updated_at, so content is fetched
+	// separately after the metadata has been checked against the cache.
+	wantsContent := parsed.Kind == "content.md" || parsed.Kind == "content.txt"
+	countsHighlights := parsed.Kind == "content.md" && !parsed.OmitFrontmatter && s.frontmatterHas("highlight_count")
+	cached := wantsContent && s.renders != nil
 	bookmark, err := s.client.GetBookmark(ctx, parsed.ID, readeck.IncludeOptions{
-		Content:    parsed.Kind == "content.md" || parsed.Kind == "content.txt",
-		Highlights: parsed.Kind == "highlights.json" || parsed.Kind == "highlights.md",
+		Content:    wantsContent && !cached,
+		Highlights: parsed.Kind == "highlights.json" || parsed.Kind == "highlights.md" || countsHighlights,
 		Labels:     true,
 	})
 	if err != nil {
 		mapped := mapToolError(err)
-		return s.writeError(req.ID, -32000, mapped.Message, map[string]any{"error": mapped})
+		return nil, &rpcError{Code: -32000, Message: mapped.Message, Data: map[string]any{"error": mapped}}
+	}
+	// Adding a highlight need not touch updated_at, so the count is part of
+	// the cache version when it is rendered.
+	version := bookmark.UpdatedAt
+	if countsHighlights {
+		version += "#" + strconv.Itoa(len(bookmark.Highlights))
+	}
+	cached = cached && bookmark.UpdatedAt != ""
+	if cached {
+		if text, ok := s.renders.get(uri, version); ok {
+			return resourceContents(uri, contentMimeType(parsed.Kind), text), nil
+		}
+	}
+	if wantsContent && s.renders != nil {
+		s.client.LoadContent(ctx, &bookmark)
+	}
+
+	if parsed.OrderByPosition {
+		bookmark.Highlights = render.SortHighlightsByPosition(bookmark.Highlights)
 	}
 
 	mime := "application/json"
@@ -427,68 +1051,89 @@ func (s *Server) handleResourcesRead(ctx context.Context, req rpcRequest) error
 		data.ContentText = ""
 		data.ContentHTML = ""
 		data.Highlights = nil
-		text = mustJSON(data)
+		text = mustJSON(data, s.cfg.CompactJSON)
 	case "content.md":
 		mime = "text/markdown"
-		text = render.BookmarkContentMarkdown(bookmark, false)
+		text = render.BookmarkContentMarkdown(bookmark, render.MarkdownOptions{
+			Hashtags:          parsed.Hashtags,
+			OmitFrontmatter:   parsed.OmitFrontmatter,
+			FrontmatterFields: s.cfg.FrontmatterFields,
+			Images:            parsed.Images,
+			Paragraphs:        s.cfg.TextParagraphs,
+			MaxChars:          parsed.MaxChars,
+		})
 	case "content.txt":
 		mime = "text/plain"
-		text = render.BookmarkContentText(bookmark)
+		text = render.Truncate(render.ContentText(bookmark, s.textOptions()), parsed.MaxChars)
 	case "highlights.json":
-		text = mustJSON(map[string]any{"highlights": bookmark.Highlights})
+		text = mustJSON(map[string]any{"highlights": bookmark.Highlights}, s.cfg.CompactJSON)
+	case "summary.txt":
+		mime = "text/plain"
+		source := bookmark.Snippet
+		if source == "" {
+			contentText, contentHTML, err := s.client.GetContent(ctx, parsed.ID)
+			if err != nil {
+				mapped := mapToolError(err)
+				return nil, &rpcError{Code: -32000, Message: mapped.Message, Data: map[string]any{"error": mapped}}
+			}
+			source = render.ContentText(readeck.Bookmark{ContentText: contentText, ContentHTML: contentHTML}, s.textOptions())
+		}
+		text = render.Summary(source, summarySentences)
 	case "highlights.md":
 		mime = "text/markdown"
 		text = render.HighlightsMarkdown(bookmark.Highlights)
 	default:
-		return s.writeError(req.ID, -32602, "unsupported resource uri", nil)
+		return nil, &rpcError{Code: -32602, Message: "unsupported resource uri"}
+	}
+
+	if cached && len(bookmark.Warnings) == 0 {
+		s.renders.put(uri, version, text)
 	}
+	return resourceContents(uri, mime, text), nil
+}
 
-	result := map[string]any{
+func resourceContents(uri, mime, text string) map[string]any {
+	return map[string]any{
 		"contents": []map[string]any{{
-			"uri":      params.URI,
+			"uri":      uri,
 			"mimeType": mime,
 			"text":     text,
 		}},
 	}
-	return s.writeResult(req.ID, result)
-}
-
-func (s *Server) handlePromptsList(req rpcRequest) error {
-	prompts := []map[string]any{
-		{
-			"name":        "readeck.prompt.summarize",
-			"description": "Summarize a bookmark with optional focus mode.",
-			"arguments": []map[string]any{
-				{"name": "bookmark_id", "required": true},
-				{"name": "focus", "required": false},
-			},
-		},
-		{
-			"name":        "readeck.prompt.flashcards",
-			"description": "Create flashcards from bookmark content/highlights.",
-			"arguments": []map[string]any{
-				{"name": "bookmark_id", "required": true},
-				{"name": "num_cards", "required": false},
-				{"name": "card_type", "required": false},
-				{"name": "use_highlights", "required": false},
-			},
-		},
-	}
-	return s.writeResult(req.ID, map[string]any{"prompts": prompts})
 }
 
-func (s *Server) handlePromptsGet(req rpcRequest) error {
-	var params struct {
-		Name      string         `json:"name"`
-		Arguments map[string]any `json:"arguments"`
+func contentMimeType(kind string) string {
+	if kind == "content.md" {
+		return "text/markdown"
 	}
+	return "text/plain"
+}
+
+func (s *Server) handlePromptsList(req rpcRequest) error {
+	return s.writeResult(req.ID, map[string]any{"prompts": promptDefinitions()})
+}
+
+func (s *Server) handlePromptsGet(req rpcRequest) error {
+	var params promptGetParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return s.writeError(req.ID, -32602, "invalid params", nil)
 	}
+	result, rpcErr := buildPrompt(params)
+	if rpcErr != nil {
+		return s.writeError(req.ID, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+	}
+	return s.writeResult(req.ID, result)
+}
+
+type promptGetParams struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
 
+func buildPrompt(params promptGetParams) (map[string]any, *rpcError) {
 	bookmarkID, _ := params.Arguments["bookmark_id"].(string)
 	if strings.TrimSpace(bookmarkID) == "" {
-		return s.writeError(req.ID, -32602, "bookmark_id is required", nil)
+		return nil, &rpcError{Code: -32602, Message: "bookmark_id is required"}
 	}
 
 	switch params.Name {
@@ -497,8 +1142,12 @@ func (s *Server) handlePromptsGet(req rpcRequest) error {
 		if focus == "" {
 			focus = "key_ideas"
 		}
-		text := fmt.Sprintf("Summarize this article with focus on %s. Read:\n- readeck://bookmark/%s/content.md\n- readeck://bookmark/%s/highlights.md", focus, bookmarkID, bookmarkID)
-		return s.writeResult(req.ID, promptResult("Summarize bookmark", text))
+		text := fmt.Sprintf("Summarize this article with focus on %s.", focus)
+		if guidance, ok := summarizeFocusGuidance[focus]; ok {
+			text += " " + guidance
+		}
+		text += fmt.Sprintf(" Read:\n- readeck://bookmark/%s/content.md\n- readeck://bookmark/%s/highlights.md", bookmarkID, bookmarkID)
+		return promptResult("Summarize bookmark", text), nil
 	case "readeck.prompt.flashcards":
 		numCards := 10
 		if raw, ok := params.Arguments["num_cards"]; ok {
@@ -508,19 +1157,98 @@ func (s *Server) handlePromptsGet(req rpcRequest) error {
 		if cardType == "" {
 			cardType = "qa"
 		}
+		instructions, ok := flashcardInstructions[cardType]
+		if !ok {
+			return nil, &rpcError{Code: -32602, Message: "card_type must be one of: qa, cloze, basic"}
+		}
 		useHighlights := true
 		if raw, ok := params.Arguments["use_highlights"]; ok {
 			if b, ok := raw.(bool); ok {
 				useHighlights = b
 			}
 		}
-		text := fmt.Sprintf("Generate %d %s flashcards from this article. Read:\n- readeck://bookmark/%s/content.md", numCards, cardType, bookmarkID)
+		text := fmt.Sprintf(instructions, numCards)
+		text += fmt.Sprintf(" Read:\n- readeck://bookmark/%s/content.md", bookmarkID)
 		if useHighlights {
 			text += fmt.Sprintf("\n- readeck://bookmark/%s/highlights.md", bookmarkID)
 		}
-		return s.writeResult(req.ID, promptResult("Flashcards from bookmark", text))
+		return promptResult("Flashcards from bookmark", text), nil
 	default:
-		return s.writeError(req.ID, -32602, "unknown prompt", nil)
+		return nil, &rpcError{Code: -32602, Message: "unknown prompt"}
+	}
+}
+
+var summarizeFocusGuidance = map[string]string{
+	"key_ideas":   "List the central ideas in order of importance, one or two sentences each.",
+	"methodology": "Describe how the author reached their results: data, methods, assumptions and limitations.",
+	"conclusions": "State the conclusions and recommendations, and what evidence each one rests on.",
+	"definitions": "Extract the key terms and concepts the article defines or relies on, with a concise definition for each.",
+	"critique":    "Evaluate the strength of the argument: weak evidence, missing perspectives, and open questions.",
+}
+
+var flashcardInstructions = map[string]string{
+	"qa":    "Generate %d question/answer flashcards from this article. Each card asks one focused question and gives a short, self-contained answer.",
+	"cloze": "Generate %d cloze deletion flashcards from this article using Anki syntax: hide each key term as {{c1::term}}, numbering c1, c2, ... when a card has several deletions.",
+	"basic": "Generate %d basic front/back flashcards from this article: a term or concept on the front and its explanation on the back.",
+}
+
+func promptDefinitions() []map[string]any {
+	return []map[string]any{
+		{
+			"name":        "readeck.prompt.summarize",
+			"description": "Summarize a bookmark with optional focus mode.",
+			"arguments": []map[string]any{
+				{
+					"name":        "bookmark_id",
+					"description": "Bookmark ID to summarize.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "focus",
+					"description": "What the summary should concentrate on. Known values add tailored guidance; any other text is used as-is.",
+					"required":    false,
+					"type":        "string",
+					"enum":        []string{"key_ideas", "methodology", "conclusions", "definitions", "critique"},
+					"default":     "key_ideas",
+				},
+			},
+		},
+		{
+			"name":        "readeck.prompt.flashcards",
+			"description": "Create flashcards from bookmark content/highlights.",
+			"arguments": []map[string]any{
+				{
+					"name":        "bookmark_id",
+					"description": "Bookmark ID to build flashcards from.",
+					"required":    true,
+					"type":        "string",
+				},
+				{
+					"name":        "num_cards",
+					"description": "Number of flashcards to generate.",
+					"required":    false,
+					"type":        "integer",
+					"minimum":     1,
+					"default":     10,
+				},
+				{
+					"name":        "card_type",
+					"description": "Flashcard format.",
+					"required":    false,
+					"type":        "string",
+					"enum":        []string{"qa", "cloze", "basic"},
+					"default":     "qa",
+				},
+				{
+					"name":        "use_highlights",
+					"description": "Also read the bookmark highlights.",
+					"required":    false,
+					"type":        "boolean",
+					"default":     true,
+				},
+			},
+		},
 	}
 }
 
@@ -554,10 +1282,14 @@ func (s *Server) writeMessage(v any) error {
 	if err != nil {
 		return err
 	}
-	frame := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(payload))
 
 	s.writeMu.Lock()
 	defer s.writeMu.Unlock()
+	if s.cfg.StdioFraming == "ndjson" {
+		_, err = s.out.Write(append(payload, '\n'))
+		return err
+	}
+	frame := fmt.Sprintf("Content-Length: %d\r\n\r\n", len(payload))
 	if _, err := io.WriteString(s.out, frame); err != nil {
 		return err
 	}
@@ -567,15 +1299,26 @@ func (s *Server) writeMessage(v any) error {
 
 func readMessage(reader *bufio.Reader) ([]byte, error) {
 	length := -1
+	sawHeader := false
 	for {
 		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
 		if err != nil {
+			if errors.Is(err, io.EOF) && !sawHeader && strings.HasPrefix(line, "{") {
+				return []byte(line), nil
+			}
 			return nil, err
 		}
-		line = strings.TrimRight(line, "\r\n")
 		if line == "" {
+			if !sawHeader {
+				continue
+			}
 			break
 		}
+		if !sawHeader && strings.HasPrefix(line, "{") {
+			return []byte(line), nil
+		}
+		sawHeader = true
 		parts := strings.SplitN(line, ":", 2)
 		if len(parts) != 2 {
 			continue
@@ -601,6 +1344,99 @@ func readMessage(reader *bufio.Reader) ([]byte, error) {
 	return payload, nil
 }
 
+const (
+	maxLabelCount    = 100
+	maxQuoteLength   = 5000
+	maxSearchTextLen = 1000
+)
+
+type searchArgs struct {
+	Query      string   `json:"query"`
+	Title      string   `json:"title"`
+	Text       string   `json:"text"`
+	Labels     []string `json:"labels"`
+	LabelMode  string   `json:"label_mode"`
+	Collection string   `json:"collection"`
+	Archived   string   `json:"archived"`
+	Favorites  *bool    `json:"favorites"`
+	Sort       string   `json:"sort"`
+	Limit      int      `json:"limit"`
+	Cursor     string   `json:"cursor"`
+	Snippets   *bool    `json:"snippets"`
+}
+
+func (a searchArgs) options(cfg config.Config) (readeck.SearchOptions, error) {
+	if err := validateSearchInput(a.Query, a.Title, a.Text, a.Labels, cfg.MaxLabelLength); err != nil {
+		return readeck.SearchOptions{}, err
+	}
+	switch readeck.LabelMatchMode(a.LabelMode) {
+	case "", readeck.LabelMatchAll, readeck.LabelMatchAny:
+	default:
+		return readeck.SearchOptions{}, newInputError("label_mode must be one of all, any")
+	}
+	opts := readeck.SearchOptions{
+		Query:      a.Query,
+		Title:      a.Title,
+		Text:       a.Text,
+		Labels:     a.Labels,
+		LabelMode:  readeck.LabelMatchMode(a.LabelMode),
+		Collection: a.Collection,
+		Archived:   readeck.ArchivedMode(a.Archived),
+		Favorites:  a.Favorites,
+		Sort:       readeck.SortMode(a.Sort),
+		Limit:      a.Limit,
+		Cursor:     a.Cursor,
+	}
+	if a.Snippets != nil {
+		opts.NoSnippets = !*a.Snippets
+	}
+	if opts.Archived == "" {
+		opts.Archived = readeck.ArchivedMode(cfg.DefaultArchived)
+	}
+	return opts, nil
+}
+
+func validateSearchInput(query, title, text string, labels []string, maxLabelLength int) error {
+	if err := validateLength("query", query, maxSearchTextLen); err != nil {
+		return err
+	}
+	if err := validateLength("title", title, maxSearchTextLen); err != nil {
+		return err
+	}
+	if err := validateLength("text", text, maxSearchTextLen); err != nil {
+		return err
+	}
+	return validateLabels(labels, maxLabelLength)
+}
+
+// validateLabels rejects oversize labels and labels containing newlines or
+// other control characters, naming every offending label in the error.
+func validateLabels(labels []string, maxLength int) error {
+	if len(labels) > maxLabelCount {
+		return newInputError(fmt.Sprintf("labels must have at most %d entries", maxLabelCount))
+	}
+	var invalid []string
+	for _, label := range labels {
+		switch {
+		case len([]rune(label)) > maxLength:
+			invalid = append(invalid, fmt.Sprintf("%q (longer than %d characters)", render.Truncate(label, 40), maxLength))
+		case strings.IndexFunc(label, unicode.IsControl) >= 0:
+			invalid = append(invalid, fmt.Sprintf("%q (contains control characters)", label))
+		}
+	}
+	if len(invalid) > 0 {
+		return newInputError("invalid labels: " + strings.Join(invalid, ", "))
+	}
+	return nil
+}
+
+func validateLength(field, value string, max int) error {
+	if len([]rune(value)) > max {
+		return newInputError(fmt.Sprintf("%s must be at most %d characters", field, max))
+	}
+	return nil
+}
+
 func decodeArgs(raw json.RawMessage, out any) error {
 	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
 		return nil
@@ -611,8 +1447,25 @@ func decodeArgs(raw json.RawMessage, out any) error {
 	return nil
 }
 
-func mustJSON(v any) string {
-	b, err := json.MarshalIndent(v, "", "  ")
+func parseAccessedAt(raw string) (time.Time, error) {
+	if strings.TrimSpace(raw) == "" {
+		return time.Now().UTC(), nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, newInputError("accessed_at must be RFC3339")
+	}
+	return parsed, nil
+}
+
+func mustJSON(v any, compact bool) string {
+	var b []byte
+	var err error
+	if compact {
+		b, err = json.Marshal(v)
+	} else {
+		b, err = json.MarshalIndent(v, "", "  ")
+	}
 	if err != nil {
 		return "{}"
 	}
@@ -626,6 +1479,27 @@ type rpcRequest struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 }
 
+// recoverRequestID pulls a string or numeric id out of a message that is
+// valid JSON but not a well-formed request, e.g. one whose method is not a
+// string, so the error response can still be correlated.
+func recoverRequestID(payload []byte) json.RawMessage {
+	var envelope struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil
+	}
+	var id any
+	if err := json.Unmarshal(envelope.ID, &id); err != nil {
+		return nil
+	}
+	switch id.(type) {
+	case string, float64:
+		return envelope.ID
+	}
+	return nil
+}
+
 func (r rpcRequest) hasID() bool {
 	trimmed := strings.TrimSpace(string(r.ID))
 	return trimmed != "" && trimmed != "null"
@@ -653,6 +1527,12 @@ type rpcResponse struct {
 	Error   *rpcError       `json:"error,omitempty"`
 }
 
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
 type rpcError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -662,6 +1542,9 @@ type rpcError struct {
 type toolCallParams struct {
 	Name      string          `json:"name"`
 	Arguments json.RawMessage `json:"arguments"`
+	Meta      struct {
+		ProgressToken json.RawMessage `json:"progressToken"`
+	} `json:"_meta"`
 }
 
 type toolError struct {
@@ -715,30 +1598,47 @@ func mapToolError(err error) toolError {
 	return toolError{Code: "upstream_error", Message: err.Error()}
 }
 
+const (
+	maxColorScan     = 500
+	summarySentences = 3
+)
+
+var knownHighlightColors = []string{"yellow", "red", "blue", "green"}
+
 type parsedURI struct {
-	ID   string
-	Kind string
+	ID              string
+	Kind            string
+	MaxChars        int
+	Hashtags        bool
+	OmitFrontmatter bool
+	Images          bool
+	OrderByPosition bool
 }
 
-type highlightDateFilter struct {
+type highlightFilter struct {
 	Start time.Time
 	End   time.Time
+	Query string
+}
+
+func (f highlightFilter) enabled() bool {
+	return f.dated() || f.Query != ""
 }
 
-func (f highlightDateFilter) enabled() bool {
+func (f highlightFilter) dated() bool {
 	return !f.Start.IsZero() || !f.End.IsZero()
 }
 
-func (s *Server) listHighlights(ctx context.Context, bookmarkID string, limit, offset int, filter highlightDateFilter) (readeck.HighlightListResult, error) {
+func (s *Server) listHighlights(ctx context.Context, bookmarkID string, limit, offset int, filter highlightFilter) (readeck.HighlightListResult, error) {
 	if !filter.enabled() {
 		return s.client.ListHighlights(ctx, bookmarkID, limit, offset)
 	}
 
 	if limit <= 0 {
-		limit = 200
+		limit = s.cfg.DefaultListLimit
 	}
-	if limit > 500 {
-		limit = 500
+	if limit > config.MaxListLimit {
+		limit = config.MaxListLimit
 	}
 
 	batchSize := limit
@@ -749,38 +1649,44 @@ func (s *Server) listHighlights(ctx context.Context, bookmarkID string, limit, o
 		batchSize = 500
 	}
 
-	scanOffset := 0
-	filteredSeen := 0
+	// With a filter, offset and next_cursor are positions in the upstream
+	// feed rather than among the matches, so a scan stopped by the cap or
+	// the deadline resumes where it left off instead of starting over.
+	scanOffset := offset
 	out := make([]readeck.Highlight, 0, limit)
-	hasMore := false
+	nextCursor := ""
+	partial := false
+	scanned := 0
 
 	for {
 		page, err := s.client.ListHighlights(ctx, bookmarkID, batchSize, scanOffset)
 		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && len(out) > 0 {
+				partial = true
+				nextCursor = strconv.Itoa(scanOffset)
+				break
+			}
 			return readeck.HighlightListResult{}, err
 		}
 		if len(page.Highlights) == 0 {
 			break
 		}
 
-		for _, h := range page.Highlights {
-			if !highlightMatchesDateFilter(h, filter) {
-				continue
-			}
-			if filteredSeen < offset {
-				filteredSeen++
+		for i, h := range page.Highlights {
+			if !highlightMatchesFilter(h, filter) {
 				continue
 			}
 			if len(out) >= limit {
-				hasMore = true
+				nextCursor = strconv.Itoa(scanOffset + i)
 				break
 			}
 			out = append(out, h)
-			filteredSeen++
 		}
-		if hasMore {
+		if nextCursor != "" {
 			break
 		}
+		reportProgress(ctx, len(out), limit)
+		scanned += len(page.Highlights)
 
 		nextOffset, ok := parseNonNegativeInt(page.NextCursor)
 		if !ok {
@@ -792,53 +1698,89 @@ func (s *Server) listHighlights(ctx context.Context, bookmarkID string, limit, o
 		if nextOffset <= scanOffset {
 			break
 		}
+		if scanned >= s.cfg.HighlightScanCap || readeck.NearDeadline(ctx, s.cfg.DeadlineSlack) {
+			partial = true
+			nextCursor = strconv.Itoa(nextOffset)
+			break
+		}
 		scanOffset = nextOffset
 	}
 
-	nextCursor := ""
-	if hasMore {
-		nextCursor = strconv.Itoa(offset + len(out))
+	return readeck.HighlightListResult{Highlights: out, NextCursor: nextCursor, Partial: partial}, nil
+}
+
+func (s *Server) highlightColors(ctx context.Context) (map[string]any, error) {
+	colors := append([]string(nil), knownHighlightColors...)
+	seen := map[string]struct{}{}
+	for _, c := range colors {
+		seen[c] = struct{}{}
+	}
+
+	scanned := 0
+	offset := 0
+	for scanned < maxColorScan {
+		page, err := s.client.ListHighlights(ctx, "", maxColorScan-scanned, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range page.Highlights {
+			color := strings.ToLower(strings.TrimSpace(h.Color))
+			if color == "" {
+				continue
+			}
+			if _, ok := seen[color]; ok {
+				continue
+			}
+			seen[color] = struct{}{}
+			colors = append(colors, color)
+		}
+		scanned += len(page.Highlights)
+		nextOffset, ok := parseNonNegativeInt(page.NextCursor)
+		if !ok || nextOffset <= offset || len(page.Highlights) == 0 {
+			break
+		}
+		offset = nextOffset
 	}
-	return readeck.HighlightListResult{Highlights: out, NextCursor: nextCursor}, nil
+	return map[string]any{"colors": colors}, nil
 }
 
-func parseHighlightDateFilter(date, dateFrom, dateTo string) (highlightDateFilter, error) {
+func parseHighlightDateFilter(date, dateFrom, dateTo string) (highlightFilter, error) {
 	date = strings.TrimSpace(date)
 	dateFrom = strings.TrimSpace(dateFrom)
 	dateTo = strings.TrimSpace(dateTo)
 
 	if date != "" && (dateFrom != "" || dateTo != "") {
-		return highlightDateFilter{}, errors.New("date cannot be combined with date_from/date_to")
+		return highlightFilter{}, errors.New("date cannot be combined with date_from/date_to")
 	}
 
 	if date != "" {
 		day, err := parseISODate(date)
 		if err != nil {
-			return highlightDateFilter{}, errors.New("date must be YYYY-MM-DD")
+			return highlightFilter{}, errors.New("date must be YYYY-MM-DD")
 		}
-		return highlightDateFilter{
+		return highlightFilter{
 			Start: day,
 			End:   day.Add(24 * time.Hour),
 		}, nil
 	}
 
-	var filter highlightDateFilter
+	var filter highlightFilter
 	if dateFrom != "" {
 		day, err := parseISODate(dateFrom)
 		if err != nil {
-			return highlightDateFilter{}, errors.New("date_from must be YYYY-MM-DD")
+			return highlightFilter{}, errors.New("date_from must be YYYY-MM-DD")
 		}
 		filter.Start = day
 	}
 	if dateTo != "" {
 		day, err := parseISODate(dateTo)
 		if err != nil {
-			return highlightDateFilter{}, errors.New("date_to must be YYYY-MM-DD")
+			return highlightFilter{}, errors.New("date_to must be YYYY-MM-DD")
 		}
 		filter.End = day.Add(24 * time.Hour)
 	}
 	if !filter.Start.IsZero() && !filter.End.IsZero() && !filter.Start.Before(filter.End) {
-		return highlightDateFilter{}, errors.New("date_from must be <= date_to")
+		return highlightFilter{}, errors.New("date_from must be <= date_to")
 	}
 	return filter, nil
 }
@@ -872,8 +1814,11 @@ func parseHighlightTimestamp(raw string) (time.Time, error) {
 	return time.Time{}, errors.New("unsupported timestamp format")
 }
 
-func highlightMatchesDateFilter(h readeck.Highlight, filter highlightDateFilter) bool {
-	if !filter.enabled() {
+func highlightMatchesFilter(h readeck.Highlight, filter highlightFilter) bool {
+	if filter.Query != "" && !containsFold(h.Text, filter.Query) && !containsFold(h.Note, filter.Query) {
+		return false
+	}
+	if !filter.dated() {
 		return true
 	}
 	createdAt, err := parseHighlightTimestamp(h.CreatedAt)
@@ -889,6 +1834,24 @@ func highlightMatchesDateFilter(h readeck.Highlight, filter highlightDateFilter)
 	return true
 }
 
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// sliceContent returns length runes of text starting at start, clamped to
+// the text, along with the effective range and the full length.
+func sliceContent(text string, start, length int) (string, map[string]any) {
+	runes := []rune(text)
+	total := len(runes)
+	start = min(start, total)
+	end := min(start+length, total)
+	return string(runes[start:end]), map[string]any{
+		"start":        start,
+		"length":       end - start,
+		"total_length": total,
+	}
+}
+
 func parseNonNegativeInt(raw string) (int, bool) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
@@ -907,7 +1870,7 @@ func parseReadeckURI(raw string) (parsedURI, error) {
 		return parsedURI{}, err
 	}
 	if u.Scheme != "readeck" || u.Host != "bookmark" {
-		return parsedURI{}, fmt.Errorf("unsupported uri")
+		return parsedURI{}, fmt.Errorf("uri must start with readeck://bookmark/")
 	}
 
 	parts := strings.Split(strings.Trim(strings.TrimSpace(u.Path), "/"), "/")
@@ -920,11 +1883,99 @@ func parseReadeckURI(raw string) (parsedURI, error) {
 	}
 	kind := strings.Join(parts[1:], "/")
 	switch kind {
-	case "content.md", "content.txt", "highlights.json", "highlights.md":
+	case "content.md", "content.txt":
+		parsed := parsedURI{ID: id, Kind: kind}
+		if raw := u.Query().Get("max_chars"); raw != "" {
+			n, ok := parseNonNegativeInt(raw)
+			if !ok || n == 0 {
+				return parsedURI{}, fmt.Errorf("max_chars must be a positive integer")
+			}
+			parsed.MaxChars = n
+		}
+		if kind == "content.md" {
+			if raw := u.Query().Get("tags"); raw != "" {
+				tags, err := strconv.ParseBool(raw)
+				if err != nil {
+					return parsedURI{}, fmt.Errorf("tags must be a boolean")
+				}
+				parsed.Hashtags = tags
+			}
+			if raw := u.Query().Get("frontmatter"); raw != "" {
+				frontmatter, err := strconv.ParseBool(raw)
+				if err != nil {
+					return parsedURI{}, fmt.Errorf("frontmatter must be a boolean")
+				}
+				parsed.OmitFrontmatter = !frontmatter
+			}
+			if raw := u.Query().Get("images"); raw != "" {
+				images, err := strconv.ParseBool(raw)
+				if err != nil {
+					return parsedURI{}, fmt.Errorf("images must be a boolean")
+				}
+				parsed.Images = images
+			}
+		}
+		return parsed, nil
+	case "summary.txt":
 		return parsedURI{ID: id, Kind: kind}, nil
+	case "highlights.json", "highlights.md":
+		parsed := parsedURI{ID: id, Kind: kind}
+		switch u.Query().Get("order") {
+		case "":
+		case "position":
+			parsed.OrderByPosition = true
+		default:
+			return parsedURI{}, fmt.Errorf("order must be position")
+		}
+		return parsed, nil
 	default:
-		return parsedURI{}, fmt.Errorf("unsupported kind")
+		return parsedURI{}, fmt.Errorf("unsupported kind %q", kind)
+	}
+}
+
+// resolveResourceURI reports how readResource would interpret uri without
+// fetching anything. Only syntactically broken URIs are errors; a readeck
+// URI with an unknown kind or bad options is reported as unsupported.
+func resolveResourceURI(uri string) (map[string]any, error) {
+	if strings.TrimSpace(uri) == "" {
+		return nil, newInputError("uri is required")
+	}
+	if _, err := url.Parse(uri); err != nil {
+		return nil, newInputError("invalid resource uri: " + err.Error())
 	}
+	parsed, err := parseReadeckURI(uri)
+	if err != nil {
+		return map[string]any{"uri": uri, "supported": false, "reason": err.Error()}, nil
+	}
+	options := map[string]any{}
+	if parsed.MaxChars > 0 {
+		options["max_chars"] = parsed.MaxChars
+	}
+	if parsed.Kind == "content.md" {
+		options["tags"] = parsed.Hashtags
+		options["frontmatter"] = !parsed.OmitFrontmatter
+		options["images"] = parsed.Images
+	}
+	if parsed.OrderByPosition {
+		options["order"] = "position"
+	}
+	return map[string]any{
+		"uri":       uri,
+		"supported": true,
+		"id":        parsed.ID,
+		"kind":      parsed.Kind,
+		"options":   options,
+	}, nil
+}
+
+// frontmatterHas reports whether content.md frontmatter includes field; an
+// unset READECK_FRONTMATTER_FIELDS means the default set, which has them all.
+func (s *Server) frontmatterHas(field string) bool {
+	return len(s.cfg.FrontmatterFields) == 0 || slices.Contains(s.cfg.FrontmatterFields, field)
+}
+
+func (s *Server) textOptions() render.TextOptions {
+	return render.TextOptions{Paragraphs: s.cfg.TextParagraphs}
 }
 
 func toFloat(v any, fallback float64) float64 {
@@ -953,15 +2004,31 @@ func searchInputSchema() map[string]any {
 				"type":  "array",
 				"items": map[string]any{"type": "string"},
 			},
-			"archived":  map[string]any{"type": "string", "enum": []string{"exclude", "include", "only"}},
-			"favorites": map[string]any{"type": "boolean"},
-			"sort":      map[string]any{"type": "string", "enum": []string{"relevance", "updated_desc", "created_desc", "published_desc"}},
-			"limit":     map[string]any{"type": "integer", "minimum": 1},
-			"cursor":    map[string]any{"type": "string"},
+			"label_mode": map[string]any{"type": "string", "enum": []string{"all", "any"}},
+			"collection": map[string]any{"type": "string", "description": "Collection as reported in search results."},
+			"archived":   map[string]any{"type": "string", "enum": []string{"exclude", "include", "only"}},
+			"favorites":  map[string]any{"type": "boolean"},
+			"sort":       map[string]any{"type": "string", "enum": []string{"relevance", "updated_desc", "created_desc", "published_desc", "label_match"}},
+			"limit":      map[string]any{"type": "integer", "minimum": 1},
+			"cursor":     map[string]any{"type": "string"},
+			"snippets":   map[string]any{"type": "boolean", "description": "Include a text snippet per item (default true)."},
+			"include_content": map[string]any{
+				"type":        "boolean",
+				"description": fmt.Sprintf("Also fetch each item's full text as content_text. Costs one upstream request per item; limit is capped at %d.", maxContentResults),
+			},
 		},
 	}
 }
 
+func countInputSchema() map[string]any {
+	schema := searchInputSchema()
+	props := schema["properties"].(map[string]any)
+	for _, key := range []string{"sort", "limit", "cursor", "snippets", "include_content"} {
+		delete(props, key)
+	}
+	return schema
+}
+
 func getInputSchema() map[string]any {
 	return map[string]any{
 		"type":     "object",
@@ -974,12 +2041,75 @@ func getInputSchema() map[string]any {
 					"content":    map[string]any{"type": "boolean"},
 					"highlights": map[string]any{"type": "boolean"},
 					"labels":     map[string]any{"type": "boolean"},
+					"icon":       map[string]any{"type": "boolean", "description": "Inline the favicon as a base64 data URI in icon_data."},
+					"images": map[string]any{
+						"type":        "boolean",
+						"description": "List images referenced by the content HTML as {src, alt}, with sources resolved against the bookmark URL. Implies content.",
+					},
+					"content_format": map[string]any{
+						"type":        "string",
+						"enum":        []string{"text", "markdown", "both"},
+						"description": "markdown and both add rendered content_markdown (and content_text) alongside the bookmark. Implies content.",
+					},
+				},
+			},
+			"content_range": map[string]any{
+				"type":        "object",
+				"description": "Return only this character range of the plain-text content. Implies include.content.",
+				"required":    []string{"length"},
+				"properties": map[string]any{
+					"start":  map[string]any{"type": "integer", "minimum": 0},
+					"length": map[string]any{"type": "integer", "minimum": 1},
 				},
 			},
 		},
 	}
 }
 
+func diffInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"id", "previous"},
+		"properties": map[string]any{
+			"id": map[string]any{"type": "string"},
+			"previous": map[string]any{
+				"type":        "object",
+				"description": "Bookmark object as previously returned by readeck.get. Content is compared only when present; a missing highlights list counts as no highlights.",
+			},
+		},
+	}
+}
+
+func contentCheckInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"id"},
+		"properties": map[string]any{
+			"id": map[string]any{"type": "string"},
+		},
+	}
+}
+
+func resourceResolveInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"uri"},
+		"properties": map[string]any{
+			"uri": map[string]any{"type": "string", "description": "Resource URI such as readeck://bookmark/{id}/content.md?tags=true."},
+		},
+	}
+}
+
+func linksInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"id"},
+		"properties": map[string]any{
+			"id": map[string]any{"type": "string"},
+		},
+	}
+}
+
 func archiveInputSchema() map[string]any {
 	return map[string]any{
 		"type":     "object",
@@ -1001,6 +2131,32 @@ func labelsListInputSchema() map[string]any {
 	}
 }
 
+func collectionsListInputSchema() map[string]any {
+	return labelsListInputSchema()
+}
+
+func labelsSuggestInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"id"},
+		"properties": map[string]any{
+			"id":  map[string]any{"type": "string"},
+			"max": map[string]any{"type": "integer", "minimum": 1},
+		},
+	}
+}
+
+func relatedInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"id"},
+		"properties": map[string]any{
+			"id":    map[string]any{"type": "string"},
+			"limit": map[string]any{"type": "integer", "minimum": 1, "description": "Maximum bookmarks returned (default 10)."},
+		},
+	}
+}
+
 func labelsSetInputSchema() map[string]any {
 	return map[string]any{
 		"type":     "object",
@@ -1018,7 +2174,7 @@ func labelsSetInputSchema() map[string]any {
 func highlightsListInputSchema() map[string]any {
 	return map[string]any{
 		"type":        "object",
-		"description": "When bookmark_id is omitted, returns a global annotations feed across all bookmarks. Date filters are applied by this MCP server.",
+		"description": "When bookmark_id is omitted, returns a global annotations feed across all bookmarks. Date and query filters are applied by this MCP server.",
 		"properties": map[string]any{
 			"bookmark_id": map[string]any{
 				"type":        "string",
@@ -1032,7 +2188,7 @@ func highlightsListInputSchema() map[string]any {
 			"offset": map[string]any{
 				"type":        "integer",
 				"minimum":     0,
-				"description": "Zero-based pagination offset.",
+				"description": "Zero-based pagination offset. With date or query filters this is a position in the unfiltered feed; pass back next_cursor.",
 			},
 			"date": map[string]any{
 				"type":        "string",
@@ -1046,10 +2202,92 @@ func highlightsListInputSchema() map[string]any {
 				"type":        "string",
 				"description": "Filter annotations created on or before this UTC date (YYYY-MM-DD).",
 			},
+			"query": map[string]any{
+				"type":        "string",
+				"description": "Case-insensitive substring matched against highlight text and note.",
+			},
+		},
+	}
+}
+
+func highlightsColorsInputSchema() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{},
+	}
+}
+
+func highlightsDigestInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"bookmark_id"},
+		"properties": map[string]any{
+			"bookmark_id": map[string]any{"type": "string"},
+		},
+	}
+}
+
+func highlightsSinceInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"since"},
+		"properties": map[string]any{
+			"since": map[string]any{"type": "string", "format": "date-time"},
+		},
+	}
+}
+
+func changedSinceInputSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"since"},
+		"properties": map[string]any{
+			"since": map[string]any{"type": "string", "format": "date-time"},
+			"limit": map[string]any{"type": "integer", "minimum": 1, "description": "Maximum bookmarks returned (default 100)."},
+		},
+	}
+}
+
+func exportAnkiInputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"bookmark_id": map[string]any{"type": "string"},
 		},
 	}
 }
 
+func citeStylesInputSchema() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{},
+	}
+}
+
+func citeSearchInputSchema() map[string]any {
+	schema := searchInputSchema()
+	props := schema["properties"].(map[string]any)
+	delete(props, "snippets")
+	delete(props, "include_content")
+	props["limit"] = map[string]any{"type": "integer", "minimum": 1, "maximum": maxCitedResults}
+	props["style"] = map[string]any{"type": "string", "enum": citationStyleNames()}
+	props["accessed_at"] = map[string]any{"type": "string", "format": "date-time"}
+	return schema
+}
+
+func bibliographyInputSchema() map[string]any {
+	schema := citeSearchInputSchema()
+	props := schema["properties"].(map[string]any)
+	delete(props, "cursor")
+	props["ids"] = map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"type": "string"},
+		"maxItems":    maxCitedResults,
+		"description": "Bookmarks to include. When set, search filters are ignored.",
+	}
+	return schema
+}
+
 func citeInputSchema() map[string]any {
 	return map[string]any{
 		"type":     "object",
@@ -1060,9 +2298,10 @@ func citeInputSchema() map[string]any {
 			"quote":        map[string]any{"type": "string"},
 			"style": map[string]any{
 				"type": "string",
-				"enum": []string{"apa", "mla", "chicago", "bibtex", "csl-json", "markdown"},
+				"enum": citationStyleNames(),
 			},
 			"accessed_at": map[string]any{"type": "string", "format": "date-time"},
+			"title_case":  map[string]any{"type": "boolean"},
 		},
 	}
 }
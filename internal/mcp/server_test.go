@@ -0,0 +1,420 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/akrisanov/readeck-mcp/internal/config"
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+// newTestServer builds a server whose client talks to a stub upstream. env
+// holds extra name/value pairs applied before config.Load.
+func newTestServer(t *testing.T, handler http.HandlerFunc, env ...string) *Server {
+	t.Helper()
+	if handler == nil {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected upstream request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	t.Setenv("READECK_BASE_URL", srv.URL)
+	t.Setenv("READECK_API_TOKEN", "test-token")
+	for i := 0; i+1 < len(env); i += 2 {
+		t.Setenv(env[i], env[i+1])
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	return NewServer(cfg, readeck.NewClient(cfg, nil), nil)
+}
+
+// callTool runs a tool and round-trips its result through JSON so tests can
+// inspect it the way a client would.
+func callTool(t *testing.T, s *Server, name, args string) (map[string]any, error) {
+	t.Helper()
+	result, err := s.executeTool(context.Background(), name, json.RawMessage(args))
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err := json.Unmarshal([]byte(mustJSON(result, true)), &out); err != nil {
+		t.Fatalf("decode %s result: %v", name, err)
+	}
+	return out, nil
+}
+
+func mustCallTool(t *testing.T, s *Server, name, args string) map[string]any {
+	t.Helper()
+	out, err := callTool(t, s, name, args)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return out
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("encode response: %v", err)
+	}
+}
+
+// upstream is a canned Readeck API serving bookmarks, their content and
+// their highlights by bookmark id.
+type upstream struct {
+	bookmarks  map[string]map[string]any
+	content    map[string]string
+	highlights map[string][]map[string]any
+}
+
+func (u upstream) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/bookmarks/"), "/")
+		switch {
+		case rest == "" && u.bookmarks[id] != nil:
+			writeJSON(t, w, u.bookmarks[id])
+		case rest == "content" && u.content[id] != "":
+			writeJSON(t, w, map[string]any{"content_html": u.content[id]})
+		case rest == "annotations" && r.URL.Query().Get("offset") == "0":
+			writeJSON(t, w, map[string]any{"items": u.highlights[id]})
+		case rest == "annotations":
+			writeJSON(t, w, map[string]any{"items": []any{}})
+		default:
+			http.NotFound(w, r)
+		}
+	}
+}
+
+func itemIDs(t *testing.T, out map[string]any, key string) []string {
+	t.Helper()
+	items, _ := out[key].([]any)
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.(map[string]any)["id"].(string))
+	}
+	return ids
+}
+
+func assertInputError(t *testing.T, err error, want string) {
+	t.Helper()
+	var inErr inputError
+	if !errors.As(err, &inErr) {
+		t.Fatalf("err = %v, want input error %q", err, want)
+	}
+	if inErr.msg != want {
+		t.Fatalf("input error = %q, want %q", inErr.msg, want)
+	}
+}
+
+func TestSearchRejectsUnknownLabelMode(t *testing.T) {
+	s := newTestServer(t, nil)
+	_, err := callTool(t, s, "readeck.search", `{"labels":["a"],"label_mode":"most"}`)
+	assertInputError(t, err, "label_mode must be one of all, any")
+}
+
+// toolText returns the text content of a tools/call response.
+func toolText(t *testing.T, msg map[string]any) string {
+	t.Helper()
+	result, _ := msg["result"].(map[string]any)
+	content, _ := result["content"].([]any)
+	if len(content) == 0 {
+		t.Fatalf("no content in %v", msg)
+	}
+	return content[0].(map[string]any)["text"].(string)
+}
+
+func TestCompactJSONToolOutput(t *testing.T) {
+	call := request(1, "tools/call", `{"name":"readeck.cite.styles","arguments":{}}`)
+	for _, compact := range []bool{false, true} {
+		env := []string{"MCP_COMPACT_JSON", strconv.FormatBool(compact)}
+		stdio := toolText(t, byID(runStdio(t, newTestServer(t, nil, env...), frame(call)))[1])
+		overHTTP := toolText(t, decodeHTTPResponse(t, postHTTP(t, newTestServer(t, nil, env...), call)))
+
+		for transport, text := range map[string]string{"stdio": stdio, "http": overHTTP} {
+			if !json.Valid([]byte(text)) {
+				t.Fatalf("%s compact=%v: invalid JSON %q", transport, compact, text)
+			}
+			if indented := strings.Contains(text, "\n  "); indented == compact {
+				t.Errorf("%s compact=%v: text = %q", transport, compact, text)
+			}
+		}
+	}
+}
+
+func TestOversizeToolInputsAreRejected(t *testing.T) {
+	s := newTestServer(t, nil)
+	long := strings.Repeat("x", maxSearchTextLen+1)
+	manyLabels, _ := json.Marshal(make([]string, maxLabelCount+1))
+
+	tests := []struct{ tool, args, want string }{
+		{"readeck.search", `{"query":"` + long + `"}`, fmt.Sprintf("query must be at most %d characters", maxSearchTextLen)},
+		{"readeck.search", `{"title":"` + long + `"}`, fmt.Sprintf("title must be at most %d characters", maxSearchTextLen)},
+		{"readeck.search", `{"labels":` + string(manyLabels) + `}`, fmt.Sprintf("labels must have at most %d entries", maxLabelCount)},
+		{"readeck.labels.set", `{"id":"b1","labels":` + string(manyLabels) + `}`, fmt.Sprintf("labels must have at most %d entries", maxLabelCount)},
+		{"readeck.cite", `{"bookmark_id":"b1","quote":"` + strings.Repeat("q", maxQuoteLength+1) + `"}`, fmt.Sprintf("quote must be at most %d characters", maxQuoteLength)},
+	}
+	for _, tt := range tests {
+		_, err := callTool(t, s, tt.tool, tt.args)
+		assertInputError(t, err, tt.want)
+	}
+}
+
+func TestInputAtTheLimitIsAccepted(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{"items": []any{}})
+	})
+	mustCallTool(t, s, "readeck.search", `{"query":"`+strings.Repeat("x", maxSearchTextLen)+`"}`)
+}
+
+func TestDiffToolFetchesContentOnlyWhenPreviousHadIt(t *testing.T) {
+	up := articleUpstream()
+	up.highlights = map[string][]map[string]any{"b1": {{"id": "h1", "text": "new"}}}
+	var paths []string
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		up.handler(t)(w, r)
+	})
+
+	out := mustCallTool(t, s, "readeck.diff", `{"id":"b1","previous":{"id":"b1","title":"Old title","url":"https://example.com/a","updated_at":"2024-01-01T00:00:00Z","labels":[{"name":"go"}]}}`)
+	if out["changed"] != true {
+		t.Fatalf("changed = %v", out["changed"])
+	}
+	var fields []string
+	for _, c := range out["changes"].([]any) {
+		fields = append(fields, c.(map[string]any)["field"].(string))
+	}
+	if want := []string{"title", "highlights"}; !slices.Equal(fields, want) {
+		t.Fatalf("changed fields = %v, want %v", fields, want)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "/content") {
+			t.Fatalf("upstream paths = %v, content fetched without previous content", paths)
+		}
+	}
+
+	_, err := callTool(t, s, "readeck.diff", `{"id":"b1"}`)
+	assertInputError(t, err, "previous is required")
+}
+
+func archivedMixUpstream(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{"items": []any{
+			map[string]any{"id": "live", "title": "live", "url": "https://example.com/live"},
+			map[string]any{"id": "old", "title": "old", "url": "https://example.com/old", "is_archived": true},
+		}})
+	}
+}
+
+func TestSearchAppliesConfiguredDefaultArchivedMode(t *testing.T) {
+	tests := []struct {
+		defaultMode, args string
+		want              []string
+	}{
+		{"", `{}`, []string{"live"}},
+		{"include", `{}`, []string{"live", "old"}},
+		{"only", `{}`, []string{"old"}},
+		{"only", `{"archived":"exclude"}`, []string{"live"}},
+	}
+	for _, tt := range tests {
+		s := newTestServer(t, archivedMixUpstream(t), "READECK_DEFAULT_ARCHIVED", tt.defaultMode)
+		out := mustCallTool(t, s, "readeck.search", tt.args)
+		if got := itemIDs(t, out, "items"); !slices.Equal(got, tt.want) {
+			t.Errorf("default %q args %s: items = %v, want %v", tt.defaultMode, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSearchOutputIncludesPrevCursor(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{"items": []any{bookmarkJSON("b1", "2024-01-01T00:00:00Z")}, "next_cursor": "n", "prev_cursor": "p"})
+	})
+	out := mustCallTool(t, s, "readeck.search", `{"cursor":"c"}`)
+	if out["next_cursor"] != "n" || out["prev_cursor"] != "p" {
+		t.Fatalf("next_cursor = %v prev_cursor = %v", out["next_cursor"], out["prev_cursor"])
+	}
+}
+
+func TestEveryToolAdvertisesAnOutputSchema(t *testing.T) {
+	s := newTestServer(t, nil, "MCP_DEBUG", "true")
+	tools := s.tools()
+	if len(tools) == 0 {
+		t.Fatal("no tools advertised")
+	}
+	for _, tool := range tools {
+		schema, ok := tool["outputSchema"].(map[string]any)
+		if !ok || schema["type"] != "object" {
+			t.Errorf("%s: outputSchema = %v, want an object schema", tool["name"], tool["outputSchema"])
+		}
+	}
+}
+
+func TestDiagnosticsOmitsTheToken(t *testing.T) {
+	const token = "s3cret-readeck-token"
+	labels := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/labels" {
+			t.Errorf("unexpected upstream request %s %s", r.Method, r.URL)
+		}
+		writeJSON(t, w, map[string]any{"items": []any{}})
+	}
+	s := newTestServer(t, labels, "READECK_API_TOKEN", token, "MCP_DEBUG", "true")
+
+	out := mustCallTool(t, s, "readeck.diagnostics", `{}`)
+	if dump := mustJSON(out, true); strings.Contains(dump, token) {
+		t.Fatalf("diagnostics leak the token:\n%s", dump)
+	}
+	if cfg := out["config"].(map[string]any); cfg["base_url_host"] == nil {
+		t.Fatalf("config = %v, want the base URL host", cfg)
+	}
+	if out["version"] != s.cfg.ServerVersion {
+		t.Fatalf("version = %v, want %q", out["version"], s.cfg.ServerVersion)
+	}
+	if up := out["upstream"].(map[string]any); up["reachable"] != true || up["error"] != nil {
+		t.Fatalf("upstream = %v, want reachable", up)
+	}
+	if limits := out["limits"].(map[string]any); limits["max_page_size"] != float64(s.cfg.MaxPageSize) {
+		t.Fatalf("limits = %v", limits)
+	}
+
+	down := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusUnauthorized)
+	}, "READECK_API_TOKEN", token, "MCP_DEBUG", "true")
+	out = mustCallTool(t, down, "readeck.diagnostics", `{}`)
+	if up := out["upstream"].(map[string]any); up["reachable"] != false || up["error"] == nil {
+		t.Fatalf("upstream = %v, want unreachable with an error", up)
+	}
+
+	quiet := newTestServer(t, nil, "READECK_API_TOKEN", token, "MCP_DEBUG", "false")
+	_, err := callTool(t, quiet, "readeck.diagnostics", `{}`)
+	assertInputError(t, err, "unknown tool: readeck.diagnostics")
+}
+
+func TestGetContentRange(t *testing.T) {
+	up := upstream{
+		bookmarks: map[string]map[string]any{"b1": {"id": "b1", "title": "T", "url": "https://example.com/t"}},
+		content:   map[string]string{"b1": "<p>abcdéfghij</p>"},
+	}
+	s := newTestServer(t, up.handler(t))
+
+	tests := []struct {
+		start, length int
+		text          string
+		gotStart      float64
+		gotLength     float64
+	}{
+		{2, 3, "cdé", 2, 3},
+		{8, 5, "ij", 8, 2},
+		{20, 5, "", 10, 0},
+	}
+	for _, tt := range tests {
+		args := fmt.Sprintf(`{"id":"b1","content_range":{"start":%d,"length":%d}}`, tt.start, tt.length)
+		out := mustCallTool(t, s, "readeck.get", args)
+		if text, _ := out["bookmark"].(map[string]any)["content_text"].(string); text != tt.text {
+			t.Errorf("%s: content_text = %q, want %q", args, text, tt.text)
+		}
+		r := out["content_range"].(map[string]any)
+		if r["start"] != tt.gotStart || r["length"] != tt.gotLength || r["total_length"] != float64(10) {
+			t.Errorf("%s: content_range = %v", args, r)
+		}
+	}
+
+	_, err := callTool(t, s, "readeck.get", `{"id":"b1","content_range":{"start":-1,"length":5}}`)
+	assertInputError(t, err, "content_range.start must be >= 0")
+	_, err = callTool(t, s, "readeck.get", `{"id":"b1","content_range":{"start":0,"length":0}}`)
+	assertInputError(t, err, "content_range.length must be > 0")
+}
+
+func snippetUpstream(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{"items": []any{
+			map[string]any{"id": "b1", "title": "One", "excerpt": "An excerpt worth showing."},
+			map[string]any{"id": "b2", "title": "Two", "content_text": "Body text."},
+		}})
+	}
+}
+
+func TestSearchSnippetsCanBeDisabled(t *testing.T) {
+	s := newTestServer(t, snippetUpstream(t))
+
+	for _, tc := range []struct {
+		args string
+		want bool
+	}{
+		{`{}`, true},
+		{`{"snippets":true}`, true},
+		{`{"snippets":false}`, false},
+	} {
+		out := mustCallTool(t, s, "readeck.search", tc.args)
+		for _, item := range out["items"].([]any) {
+			snippet, _ := item.(map[string]any)["snippet"].(string)
+			if (snippet != "") != tc.want {
+				t.Errorf("%s: snippet = %q", tc.args, snippet)
+			}
+		}
+	}
+}
+
+func TestSearchSummariesCarryFavoriteFlag(t *testing.T) {
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{"items": []any{
+			map[string]any{"id": "star", "title": "Starred", "is_favorite": true},
+			map[string]any{"id": "alias", "title": "Alias", "favorite": true},
+			map[string]any{"id": "plain", "title": "Plain"},
+		}})
+	})
+
+	out := mustCallTool(t, s, "readeck.search", `{}`)
+	for _, item := range out["items"].([]any) {
+		summary := item.(map[string]any)
+		if fav, _ := summary["is_favorite"].(bool); fav != (summary["id"] != "plain") {
+			t.Errorf("%s: is_favorite = %v", summary["id"], summary["is_favorite"])
+		}
+	}
+}
+
+func TestGetContentFormats(t *testing.T) {
+	up := upstream{
+		bookmarks: map[string]map[string]any{"b1": {"id": "b1", "title": "Formats", "url": "https://example.com/f"}},
+		content:   map[string]string{"b1": "<p>Some <strong>bold</strong> words.</p>"},
+	}
+	s := newTestServer(t, up.handler(t))
+
+	tests := []struct {
+		format             string
+		markdown, topLevel bool
+	}{
+		{"", false, false},
+		{"text", false, false},
+		{"markdown", true, false},
+		{"both", true, true},
+	}
+	for _, tt := range tests {
+		out := mustCallTool(t, s, "readeck.get", `{"id":"b1","include":{"content":true,"content_format":"`+tt.format+`"}}`)
+		md, hasMarkdown := out["content_markdown"].(string)
+		if hasMarkdown != tt.markdown || (hasMarkdown && !strings.HasSuffix(md, "---\n\nSome bold words.\n")) {
+			t.Errorf("%q: content_markdown = %q", tt.format, md)
+		}
+		text, hasText := out["content_text"].(string)
+		if hasText != tt.topLevel || (hasText && text != "Some bold words.") {
+			t.Errorf("%q: content_text = %q", tt.format, text)
+		}
+		if got := out["bookmark"].(map[string]any)["content_html"]; got == nil {
+			t.Errorf("%q: bookmark content missing", tt.format)
+		}
+	}
+
+	_, err := callTool(t, s, "readeck.get", `{"id":"b1","include":{"content_format":"html"}}`)
+	assertInputError(t, err, "include.content_format must be one of text, markdown, both")
+}
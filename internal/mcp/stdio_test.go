@@ -0,0 +1,328 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// frame wraps payload in Content-Length framing.
+func frame(payload string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(payload), payload)
+}
+
+func request(id int, method, params string) string {
+	if params == "" {
+		params = "{}"
+	}
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`, id, method, params)
+}
+
+// runStdio feeds input to the stdio transport until EOF and returns every
+// message it wrote.
+func runStdio(t *testing.T, s *Server, input string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	s.in = strings.NewReader(input)
+	s.out = &out
+	if err := s.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return readMessages(t, out.Bytes())
+}
+
+func readMessages(t *testing.T, raw []byte) []map[string]any {
+	t.Helper()
+	reader := bufio.NewReader(bytes.NewReader(raw))
+	var messages []map[string]any
+	for {
+		payload, err := readMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return messages
+		}
+		if err != nil {
+			t.Fatalf("read output: %v\n%s", err, raw)
+		}
+		var msg map[string]any
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatalf("decode output %q: %v", payload, err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+// byID indexes responses by their numeric id.
+func byID(messages []map[string]any) map[int]map[string]any {
+	out := map[int]map[string]any{}
+	for _, msg := range messages {
+		if id, ok := msg["id"].(float64); ok {
+			out[int(id)] = msg
+		}
+	}
+	return out
+}
+
+// syncBuffer lets the test read output the server is still writing.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func TestKeepaliveFramesAreWellFormedAndSerialized(t *testing.T) {
+	s := newTestServer(t, nil)
+	s.cfg.Keepalive = time.Millisecond
+	out := &syncBuffer{}
+	s.out = out
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.keepalive(ctx)
+		close(done)
+	}()
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = s.writeResult(json.RawMessage(strconv.Itoa(i)), map[string]any{"padding": strings.Repeat("x", 512)})
+		}()
+	}
+	wg.Wait()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	pings, results := 0, 0
+	for _, msg := range readMessages(t, out.Bytes()) {
+		switch {
+		case msg["method"] == "notifications/ping":
+			if _, ok := msg["id"]; ok {
+				t.Fatalf("ping carries an id: %v", msg)
+			}
+			pings++
+		case msg["result"] != nil:
+			results++
+		default:
+			t.Fatalf("unexpected message %v", msg)
+		}
+	}
+	if pings == 0 || results != 50 {
+		t.Fatalf("pings = %d results = %d", pings, results)
+	}
+}
+
+func TestKeepaliveIsOffByDefault(t *testing.T) {
+	s := newTestServer(t, nil)
+	if s.cfg.Keepalive != 0 {
+		t.Fatalf("Keepalive = %v, want 0", s.cfg.Keepalive)
+	}
+	pr, pw := io.Pipe()
+	out := &syncBuffer{}
+	s.in, s.out = pr, out
+	done := make(chan error, 1)
+	go func() { done <- s.Run(context.Background()) }()
+	time.Sleep(50 * time.Millisecond)
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(out.Bytes()) != 0 {
+		t.Fatalf("unexpected output %q", out.Bytes())
+	}
+}
+
+func TestReadMessageFraming(t *testing.T) {
+	a, b := `{"id":1}`, `{"id":2}`
+	tests := []struct{ name, input string }{
+		{"CRLF", fmt.Sprintf("Content-Length: %d\r\n\r\n%sContent-Length: %d\r\n\r\n%s", len(a), a, len(b), b)},
+		{"LF only", fmt.Sprintf("Content-Length: %d\n\n%sContent-Length: %d\n\n%s", len(a), a, len(b), b)},
+		{"mixed", fmt.Sprintf("Content-Length: %d\r\n\n%s\r\ncontent-length:   %d  \n\r\n%s", len(a), a, len(b), b)},
+		{"extra headers", fmt.Sprintf("Content-Type: application/json\nContent-Length: %d\n\n%sContent-Length: %d\r\nX-Other: y\r\n\r\n%s", len(a), a, len(b), b)},
+	}
+	for _, tt := range tests {
+		reader := bufio.NewReader(strings.NewReader(tt.input))
+		for _, want := range []string{a, b} {
+			got, err := readMessage(reader)
+			if err != nil || string(got) != want {
+				t.Fatalf("%s: readMessage = %q, %v; want %q", tt.name, got, err, want)
+			}
+		}
+		if _, err := readMessage(reader); !errors.Is(err, io.EOF) {
+			t.Fatalf("%s: trailing read err = %v, want EOF", tt.name, err)
+		}
+	}
+}
+
+func TestReadMessageRejectsBadLength(t *testing.T) {
+	for _, input := range []string{"Content-Length: -1\n\n{}", "Content-Length: x\n\n{}", "X-Other: y\n\n{}"} {
+		if _, err := readMessage(bufio.NewReader(strings.NewReader(input))); err == nil {
+			t.Errorf("%q: no error", input)
+		}
+	}
+}
+
+func TestInitializeRoundTripsInBothFramings(t *testing.T) {
+	initialize := request(1, "initialize", `{"protocolVersion":"2025-06-18","capabilities":{}}`)
+	tests := []struct{ framing, input, prefix string }{
+		{"content-length", frame(initialize), "Content-Length: "},
+		{"ndjson", initialize + "\n", "{"},
+	}
+	for _, tt := range tests {
+		s := newTestServer(t, nil, "MCP_STDIO_FRAMING", tt.framing)
+		var out bytes.Buffer
+		s.in, s.out = strings.NewReader(tt.input), &out
+		if err := s.Run(context.Background()); err != nil {
+			t.Fatalf("%s: Run: %v", tt.framing, err)
+		}
+		if !strings.HasPrefix(out.String(), tt.prefix) {
+			t.Fatalf("%s: output %q", tt.framing, out.String())
+		}
+		if tt.framing == "ndjson" && (strings.Count(out.String(), "\n") != 1 || !strings.HasSuffix(out.String(), "\n")) {
+			t.Fatalf("ndjson output is not one line: %q", out.String())
+		}
+		msgs := readMessages(t, out.Bytes())
+		result, _ := byID(msgs)[1]["result"].(map[string]any)
+		if result == nil || result["serverInfo"] == nil {
+			t.Fatalf("%s: initialize response = %v", tt.framing, msgs)
+		}
+	}
+}
+
+func TestShutdownDrainsSlowHandlers(t *testing.T) {
+	release := make(chan struct{})
+	s := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		writeJSON(t, w, map[string]any{"id": "b1", "title": "Slow", "url": "https://example.com/slow"})
+	})
+	var once sync.Once
+	unblock := func() { once.Do(func() { close(release) }) }
+	defer unblock()
+	out := &syncBuffer{}
+	pr, pw := io.Pipe()
+	s.in, s.out = pr, out
+
+	done := make(chan error, 1)
+	go func() { done <- s.Run(context.Background()) }()
+	_, _ = io.WriteString(pw, frame(request(1, "tools/call", `{"name":"readeck.get","arguments":{"id":"b1"}}`)))
+	_, _ = io.WriteString(pw, frame(request(2, "ping", "")))
+
+	// The ping is answered while the slow call is still in flight.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(byID(readMessages(t, out.Bytes()))) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("ping not answered while a slow call was in flight")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// EOF starts shutdown; Run must wait for the slow call to finish.
+	pw.Close()
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned before the in-flight call finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	unblock()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	responses := byID(readMessages(t, out.Bytes()))
+	if responses[2]["result"] == nil || responses[1]["result"] == nil {
+		t.Fatalf("responses = %v", responses)
+	}
+	if text := toolText(t, responses[1]); !strings.Contains(text, "Slow") {
+		t.Fatalf("slow call result = %q", text)
+	}
+}
+
+// readReturns reports when a Read on the wrapped input fails, which is how
+// readLoop learns to stop.
+type readReturns struct {
+	io.Reader
+	failed chan struct{}
+	once   sync.Once
+}
+
+func (r *readReturns) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil {
+		r.once.Do(func() { close(r.failed) })
+	}
+	return n, err
+}
+
+type deadlineInput struct {
+	*readReturns
+	file *os.File
+}
+
+func (d deadlineInput) SetReadDeadline(t time.Time) error { return d.file.SetReadDeadline(t) }
+
+type closerInput struct {
+	*readReturns
+	pipe *io.PipeReader
+}
+
+func (c closerInput) Close() error { return c.pipe.Close() }
+
+func TestRunStopsReadingOnShutdown(t *testing.T) {
+	file, fileWriter, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close(); fileWriter.Close() })
+	pipe, pipeWriter := io.Pipe()
+	t.Cleanup(func() { pipeWriter.Close() })
+
+	fileReads := &readReturns{Reader: file, failed: make(chan struct{})}
+	pipeReads := &readReturns{Reader: pipe, failed: make(chan struct{})}
+	for name, tt := range map[string]struct {
+		in    io.Reader
+		reads *readReturns
+	}{
+		"deadline": {deadlineInput{fileReads, file}, fileReads},
+		"closer":   {closerInput{pipeReads, pipe}, pipeReads},
+	} {
+		s := newTestServer(t, nil)
+		s.in = tt.in
+		s.out = io.Discard
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() { done <- s.Run(ctx) }()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("%s: Run: %v", name, err)
+		}
+		select {
+		case <-tt.reads.failed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: reader still blocked after Run returned", name)
+		}
+	}
+}
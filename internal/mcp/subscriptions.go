@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+type subscription struct {
+	bookmarkID string
+	updatedAt  string
+}
+
+type subscriptionSet struct {
+	mu   sync.Mutex
+	uris map[string]subscription
+}
+
+func newSubscriptionSet() *subscriptionSet {
+	return &subscriptionSet{uris: map[string]subscription{}}
+}
+
+func (s *subscriptionSet) add(uri string, sub subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uris[uri] = sub
+}
+
+func (s *subscriptionSet) remove(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uris, uri)
+}
+
+func (s *subscriptionSet) snapshot() map[string]subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]subscription, len(s.uris))
+	for uri, sub := range s.uris {
+		out[uri] = sub
+	}
+	return out
+}
+
+// markUpdated stores the new timestamp and reports whether it differs from the
+// last one seen. URIs unsubscribed in the meantime are ignored.
+func (s *subscriptionSet) markUpdated(uri, updatedAt string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub, ok := s.uris[uri]
+	if !ok || sub.updatedAt == updatedAt {
+		return false
+	}
+	sub.updatedAt = updatedAt
+	s.uris[uri] = sub
+	return true
+}
+
+func (s *Server) handleResourcesSubscribe(ctx context.Context, req rpcRequest) error {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return s.writeError(req.ID, -32602, "uri is required", nil)
+	}
+	parsed, err := parseReadeckURI(params.URI)
+	if err != nil {
+		return s.writeError(req.ID, -32602, "invalid resource uri", nil)
+	}
+
+	bookmark, err := s.client.GetBookmark(ctx, parsed.ID, readeck.IncludeOptions{})
+	if err != nil {
+		mapped := mapToolError(err)
+		return s.writeError(req.ID, -32000, mapped.Message, map[string]any{"error": mapped})
+	}
+	s.subs.add(params.URI, subscription{bookmarkID: parsed.ID, updatedAt: bookmark.UpdatedAt})
+	return s.writeResult(req.ID, map[string]any{})
+}
+
+func (s *Server) handleResourcesUnsubscribe(req rpcRequest) error {
+	var params struct {
+		URI string `json:"uri"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil || params.URI == "" {
+		return s.writeError(req.ID, -32602, "uri is required", nil)
+	}
+	s.subs.remove(params.URI)
+	return s.writeResult(req.ID, map[string]any{})
+}
+
+func (s *Server) pollSubscriptions(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.SubscriptionPoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkSubscriptions(ctx)
+		}
+	}
+}
+
+func (s *Server) checkSubscriptions(ctx context.Context) {
+	current := s.subs.snapshot()
+	if len(current) == 0 {
+		return
+	}
+
+	updatedByID := map[string]string{}
+	for uri, sub := range current {
+		updatedAt, ok := updatedByID[sub.bookmarkID]
+		if !ok {
+			bookmark, err := s.client.GetBookmark(ctx, sub.bookmarkID, readeck.IncludeOptions{})
+			if err != nil {
+				s.logger.Printf("subscription poll failed uri=%s: %v", uri, err)
+				continue
+			}
+			updatedAt = bookmark.UpdatedAt
+			updatedByID[sub.bookmarkID] = updatedAt
+		}
+		if !s.subs.markUpdated(uri, updatedAt) {
+			continue
+		}
+		notification := rpcNotification{
+			JSONRPC: "2.0",
+			Method:  "notifications/resources/updated",
+			Params:  map[string]any{"uri": uri},
+		}
+		if err := s.writeMessage(notification); err != nil {
+			s.logger.Printf("subscription notify failed uri=%s: %v", uri, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestSubscriptionSetBookkeeping(t *testing.T) {
+	set := newSubscriptionSet()
+	set.add("readeck://bookmark/b1/content.md", subscription{bookmarkID: "b1", updatedAt: "t1"})
+	set.add("readeck://bookmark/b1/highlights.md", subscription{bookmarkID: "b1", updatedAt: "t1"})
+
+	if set.markUpdated("readeck://bookmark/b1/content.md", "t1") {
+		t.Fatal("unchanged timestamp reported as an update")
+	}
+	if !set.markUpdated("readeck://bookmark/b1/content.md", "t2") {
+		t.Fatal("new timestamp not reported")
+	}
+	if set.markUpdated("readeck://bookmark/b1/content.md", "t2") {
+		t.Fatal("same update reported twice")
+	}
+
+	set.remove("readeck://bookmark/b1/highlights.md")
+	if set.markUpdated("readeck://bookmark/b1/highlights.md", "t2") {
+		t.Fatal("update reported for an unsubscribed uri")
+	}
+	if got := set.snapshot(); len(got) != 1 || got["readeck://bookmark/b1/content.md"].updatedAt != "t2" {
+		t.Fatalf("snapshot = %v", got)
+	}
+}
+
+func TestSubscriptionsNotifyOnlyWhenBookmarkChanges(t *testing.T) {
+	up := articleUpstream()
+	s := newTestServer(t, up.handler(t))
+	var out bytes.Buffer
+	s.out = &out
+
+	uri := "readeck://bookmark/b1/content.md"
+	req := rpcRequest{JSONRPC: "2.0", ID: json.RawMessage("1"), Method: "resources/subscribe", Params: json.RawMessage(`{"uri":"` + uri + `"}`)}
+	if err := s.handleResourcesSubscribe(context.Background(), req); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if msgs := readMessages(t, out.Bytes()); len(msgs) != 1 || msgs[0]["error"] != nil {
+		t.Fatalf("subscribe response = %v", msgs)
+	}
+
+	out.Reset()
+	s.checkSubscriptions(context.Background())
+	if out.Len() != 0 {
+		t.Fatalf("notified without a change: %s", out.String())
+	}
+
+	up.bookmarks["b1"]["updated"] = "2024-02-01T00:00:00Z"
+	s.checkSubscriptions(context.Background())
+	msgs := readMessages(t, out.Bytes())
+	if len(msgs) != 1 || msgs[0]["method"] != "notifications/resources/updated" {
+		t.Fatalf("notifications = %v", msgs)
+	}
+	if params := msgs[0]["params"].(map[string]any); params["uri"] != uri {
+		t.Fatalf("notified uri = %v", params["uri"])
+	}
+
+	req.Method, req.ID = "resources/unsubscribe", json.RawMessage("2")
+	if err := s.handleResourcesUnsubscribe(req); err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	out.Reset()
+	up.bookmarks["b1"]["updated"] = "2024-03-01T00:00:00Z"
+	s.checkSubscriptions(context.Background())
+	if out.Len() != 0 {
+		t.Fatalf("notified after unsubscribe: %s", out.String())
+	}
+}
+
+func TestSubscribeRejectsInvalidURI(t *testing.T) {
+	s := newTestServer(t, nil)
+	msgs := runStdio(t, s, frame(request(1, "resources/subscribe", `{"uri":"https://example.com"}`)))
+	errObj, _ := byID(msgs)[1]["error"].(map[string]any)
+	if errObj == nil || errObj["code"] != float64(-32602) {
+		t.Fatalf("response = %v", msgs)
+	}
+}
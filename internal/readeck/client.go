@@ -3,13 +3,17 @@ package readeck
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -23,34 +27,100 @@ type ctxKey string
 const requestIDKey ctxKey = "request_id"
 
 const (
-	defaultSearchLimit = 20
-	defaultListLimit   = 200
-	maxLabelsLimit     = 500
+	maxLabelsLimit = config.MaxListLimit
+	maxIconBytes   = 64 << 10
+	maxCountScan   = 5000
+	maxAttempts    = 4
+	retryBaseDelay = 200 * time.Millisecond
 )
 
+var defaultContentEndpoints = []string{"/content", "/article", "/text"}
+
 type Client struct {
-	apiBase     string
-	token       string
-	userAgent   string
-	httpClient  *http.Client
-	maxPageSize int
-	logger      *log.Logger
+	apiBase          string
+	token            string
+	timeout          time.Duration
+	userAgent        string
+	httpClient       *http.Client
+	maxPageSize      int
+	deadlineSlack    time.Duration
+	searchLimit      int
+	listLimit        int
+	contentEndpoints []string
+	fields           fieldMap
+	sorts            sortTokens
+	boolFormat       string
+	sem              chan struct{}
+	limiter          *rateLimiter
+	writes           *writeQueue
+	flights          flightGroup
+	logger           *log.Logger
 }
 
 func NewClient(cfg config.Config, logger *log.Logger) *Client {
 	if logger == nil {
 		logger = log.New(io.Discard, "", 0)
 	}
+	maxConcurrency := cfg.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+	contentEndpoints := cfg.ContentEndpoints
+	if len(contentEndpoints) == 0 {
+		contentEndpoints = defaultContentEndpoints
+	}
 	return &Client{
-		apiBase:     strings.TrimRight(cfg.APIBaseURL, "/"),
-		token:       cfg.APIToken,
-		userAgent:   cfg.UserAgent,
-		httpClient:  config.NewHTTPClient(cfg),
-		maxPageSize: cfg.MaxPageSize,
-		logger:      logger,
+		apiBase:          strings.TrimRight(cfg.APIBaseURL, "/"),
+		token:            cfg.APIToken,
+		timeout:          cfg.Timeout,
+		userAgent:        cfg.UserAgent,
+		httpClient:       config.NewHTTPClient(cfg),
+		maxPageSize:      cfg.MaxPageSize,
+		deadlineSlack:    cfg.DeadlineSlack,
+		searchLimit:      cfg.DefaultSearchLimit,
+		listLimit:        cfg.DefaultListLimit,
+		contentEndpoints: contentEndpoints,
+		fields:           fieldMap(cfg.FieldMap),
+		sorts:            newSortTokens(cfg.SortMap),
+		boolFormat:       cfg.BoolFormat,
+		sem:              make(chan struct{}, maxConcurrency),
+		limiter:          newRateLimiter(cfg.RateLimitRPS),
+		writes:           newWriteQueue(),
+		logger:           logger,
+	}
+}
+
+// Diagnostics reports the client's effective request policy.
+func (c *Client) Diagnostics() map[string]any {
+	return map[string]any{
+		"max_concurrency":    cap(c.sem),
+		"rate_limited":       c.limiter != nil,
+		"content_endpoints":  c.contentEndpoints,
+		"request_coalescing": true,
+		"cache":              "none",
+		"retries": map[string]any{
+			"max_attempts":  maxAttempts,
+			"base_delay_ms": retryBaseDelay.Milliseconds(),
+			"methods":       []string{http.MethodGet},
+			"on_status":     "429, 5xx",
+		},
+		"writes": map[string]any{
+			"serialized": true,
+			"on_status":  "429",
+		},
 	}
 }
 
+// NearDeadline reports whether ctx expires within slack, so long scans can
+// return what they have instead of failing with a context error.
+func NearDeadline(ctx context.Context, slack time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false
+	}
+	return time.Until(deadline) <= slack
+}
+
 func WithRequestID(ctx context.Context, requestID string) context.Context {
 	if requestID == "" {
 		return ctx
@@ -58,42 +128,151 @@ func WithRequestID(ctx context.Context, requestID string) context.Context {
 	return context.WithValue(ctx, requestIDKey, requestID)
 }
 
+// Search returns one page of summaries. Any-mode label searches are filtered
+// locally, so they keep following upstream pages until limit matches are
+// collected, stopping early with Partial set after maxCountScan bookmarks or
+// when ctx is about to expire. When the page fills up partway through an
+// upstream page, NextCursor points inside that page so the remaining
+// matches come next rather than being dropped.
 func (c *Client) Search(ctx context.Context, opts SearchOptions) (SearchResult, error) {
-	opts = normalizeSearchOptions(opts, c.maxPageSize)
-	params := buildSearchQuery(opts)
+	opts = normalizeSearchOptions(opts, c.searchLimit, c.maxPageSize)
+	fillPage := opts.LabelMode == LabelMatchAny && len(opts.Labels) > 0
+
+	var result SearchResult
+	var skip int
+	opts.Cursor, skip = splitSearchCursor(opts.Cursor)
+	items := make([]BookmarkSummary, 0, opts.Limit)
+	scanned := 0
+	for page := 0; ; page++ {
+		respMap, err := c.getObject(ctx, "/bookmarks", c.buildSearchQuery(opts))
+		if err != nil {
+			return SearchResult{}, err
+		}
+		rawItems, next, prev := extractItemsAndCursor(respMap)
+		if page == 0 {
+			result.PrevCursor = prev
+		}
+		result.NextCursor = next
+		full := false
+		for i, raw := range rawItems {
+			if i < skip {
+				continue
+			}
+			bm := mapBookmark(raw, c.fields)
+			if !matchesFilters(bm, opts) {
+				continue
+			}
+			summary := BookmarkSummary{
+				ID:          bm.ID,
+				Title:       bm.Title,
+				URL:         bm.URL,
+				IsArchived:  bm.IsArchived,
+				IsFavorite:  bm.IsFavorite,
+				Labels:      labelNames(bm.Labels),
+				Collection:  bm.Collection,
+				CreatedAt:   bm.CreatedAt,
+				UpdatedAt:   bm.UpdatedAt,
+				PublishedAt: bm.PublishedAt,
+			}
+			if !opts.NoSnippets {
+				summary.Snippet = snippetFromMap(raw)
+			}
+			items = append(items, summary)
+			if fillPage && len(items) >= opts.Limit {
+				if i < len(rawItems)-1 {
+					result.NextCursor = joinSearchCursor(opts.Cursor, i+1)
+				}
+				full = true
+				break
+			}
+		}
+		scanned += len(rawItems) - skip
+		skip = 0
 
-	respMap, err := c.getObject(ctx, "/bookmarks", params)
-	if err != nil {
-		return SearchResult{}, err
+		if !fillPage || full || next == "" || next == opts.Cursor || len(rawItems) == 0 {
+			break
+		}
+		if scanned >= maxCountScan || NearDeadline(ctx, c.deadlineSlack) {
+			result.Partial = true
+			break
+		}
+		opts.Cursor = next
 	}
 
-	rawItems, next := extractItemsAndCursor(respMap)
-	items := make([]BookmarkSummary, 0, len(rawItems))
-	for _, raw := range rawItems {
-		bm := mapBookmark(raw)
-		if !matchesFilters(bm, opts) {
-			continue
+	sortSummaries(items, opts.Sort, labelsFor(opts))
+	result.Items = items
+	return result, nil
+}
+
+// searchCursorPrefix marks a cursor that points partway into an upstream
+// page: "~<skip>~<upstream cursor>".
+const searchCursorPrefix = "~"
+
+func joinSearchCursor(upstream string, skip int) string {
+	return searchCursorPrefix + strconv.Itoa(skip) + searchCursorPrefix + upstream
+}
+
+// splitSearchCursor undoes joinSearchCursor. Anything else is an upstream
+// cursor and is passed through unchanged.
+func splitSearchCursor(cursor string) (string, int) {
+	rest, ok := strings.CutPrefix(cursor, searchCursorPrefix)
+	if !ok {
+		return cursor, 0
+	}
+	rawSkip, upstream, ok := strings.Cut(rest, searchCursorPrefix)
+	skip, err := strconv.Atoi(rawSkip)
+	if !ok || err != nil || skip < 0 {
+		return cursor, 0
+	}
+	return upstream, skip
+}
+
+// Count sizes a search without returning items. The upstream total is only
+// trusted when every filter is applied upstream; otherwise pages are scanned
+// and filtered locally up to maxCountScan bookmarks, or until ctx is about to
+// expire.
+func (c *Client) Count(ctx context.Context, opts SearchOptions) (CountResult, error) {
+	opts = normalizeSearchOptions(opts, c.searchLimit, c.maxPageSize)
+	opts.Cursor = ""
+
+	if opts.Archived == ArchivedInclude && opts.LabelMode != LabelMatchAny {
+		probe := opts
+		probe.Limit = 1
+		respMap, err := c.getObject(ctx, "/bookmarks", c.buildSearchQuery(probe))
+		if err != nil {
+			return CountResult{}, err
 		}
-		summary := BookmarkSummary{
-			ID:          bm.ID,
-			Title:       bm.Title,
-			URL:         bm.URL,
-			IsArchived:  bm.IsArchived,
-			Labels:      labelNames(bm.Labels),
-			CreatedAt:   bm.CreatedAt,
-			UpdatedAt:   bm.UpdatedAt,
-			PublishedAt: bm.PublishedAt,
-			Snippet:     snippetFromMap(raw),
+		if total, ok := extractTotal(respMap); ok {
+			return CountResult{Count: total}, nil
 		}
-		items = append(items, summary)
 	}
 
-	sortSummaries(items, opts.Sort)
-	if len(items) > opts.Limit {
-		items = items[:opts.Limit]
+	opts.Limit = c.maxPageSize
+	count := 0
+	scanned := 0
+	for {
+		respMap, err := c.getObject(ctx, "/bookmarks", c.buildSearchQuery(opts))
+		if err != nil {
+			return CountResult{}, err
+		}
+		rawItems, next, _ := extractItemsAndCursor(respMap)
+		for _, raw := range rawItems {
+			if matchesFilters(mapBookmark(raw, c.fields), opts) {
+				count++
+			}
+		}
+		scanned += len(rawItems)
+		if next == "" || next == opts.Cursor || len(rawItems) == 0 {
+			return CountResult{Count: count}, nil
+		}
+		if scanned >= maxCountScan {
+			return CountResult{Count: count, Capped: true}, nil
+		}
+		if NearDeadline(ctx, c.deadlineSlack) {
+			return CountResult{Count: count, Partial: true}, nil
+		}
+		opts.Cursor = next
 	}
-
-	return SearchResult{Items: items, NextCursor: next}, nil
 }
 
 func (c *Client) GetBookmark(ctx context.Context, id string, include IncludeOptions) (Bookmark, error) {
@@ -105,20 +284,30 @@ func (c *Client) GetBookmark(ctx context.Context, id string, include IncludeOpti
 	if err != nil {
 		return Bookmark{}, err
 	}
-	bookmark := mapBookmark(respMap)
+	bookmark := mapBookmark(respMap, c.fields)
+	bookmark.Snippet = snippetFromMap(respMap)
 
+	// Includes are best effort: a failed fetch leaves the field empty and adds
+	// a warning instead of failing the whole bookmark.
 	if include.Content {
-		text, html, err := c.fetchContent(ctx, id)
-		if err == nil {
-			bookmark.ContentText = text
-			bookmark.ContentHTML = html
-		}
+		c.loadContent(ctx, id, &bookmark)
 	}
 
 	if include.Highlights {
-		highlights, err := c.ListHighlights(ctx, id, defaultListLimit, 0)
+		highlights, err := c.ListHighlights(ctx, id, c.listLimit, 0)
 		if err == nil {
 			bookmark.Highlights = highlights.Highlights
+		} else if httpErr := new(HTTPError); !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+			bookmark.Warnings = append(bookmark.Warnings, "highlights could not be loaded: "+err.Error())
+		}
+	}
+
+	if include.Icon && bookmark.IconURL != "" {
+		data, err := c.fetchIcon(ctx, bookmark.IconURL)
+		if err == nil {
+			bookmark.IconData = data
+		} else {
+			bookmark.Warnings = append(bookmark.Warnings, "icon could not be loaded: "+err.Error())
 		}
 	}
 
@@ -129,6 +318,33 @@ func (c *Client) GetBookmark(ctx context.Context, id string, include IncludeOpti
 	return bookmark, nil
 }
 
+// LoadContent fetches content for a bookmark obtained without it, exactly as
+// GetBookmark's content include would.
+func (c *Client) LoadContent(ctx context.Context, bookmark *Bookmark) {
+	c.loadContent(ctx, bookmark.ID, bookmark)
+}
+
+func (c *Client) loadContent(ctx context.Context, id string, bookmark *Bookmark) {
+	content, err := c.fetchContent(ctx, id)
+	switch {
+	case err != nil:
+		bookmark.Warnings = append(bookmark.Warnings, "content could not be loaded: "+err.Error())
+	case content.text != "" || content.html != "":
+		bookmark.ContentText = content.text
+		bookmark.ContentHTML = content.html
+	case bookmark.ContentText == "" && bookmark.ContentHTML == "":
+		bookmark.ContentUnavailableReason = content.unavailable
+	}
+}
+
+func (c *Client) GetContent(ctx context.Context, id string) (string, string, error) {
+	if strings.TrimSpace(id) == "" {
+		return "", "", errors.New("id is required")
+	}
+	content, err := c.fetchContent(ctx, id)
+	return content.text, content.html, err
+}
+
 func (c *Client) SetArchived(ctx context.Context, id string, archived bool) (ArchiveResult, error) {
 	if strings.TrimSpace(id) == "" {
 		return ArchiveResult{}, errors.New("id is required")
@@ -150,7 +366,8 @@ func (c *Client) SetArchived(ctx context.Context, id string, archived bool) (Arc
 		return ArchiveResult{}, err
 	}
 
-	bm, err := c.GetBookmark(ctx, id, IncludeOptions{Labels: true})
+	// Write responses (often 204) don't carry the new state, so confirm it.
+	bm, err := c.GetBookmark(ctx, id, IncludeOptions{})
 	if err != nil {
 		return ArchiveResult{}, err
 	}
@@ -188,7 +405,7 @@ func (c *Client) patchArchivedField(ctx context.Context, id string, archived boo
 
 func (c *Client) ListLabels(ctx context.Context, limit int, cursor string) (LabelListResult, error) {
 	if limit <= 0 {
-		limit = defaultListLimit
+		limit = c.listLimit
 	}
 	if limit > maxLabelsLimit {
 		limit = maxLabelsLimit
@@ -210,16 +427,57 @@ func (c *Client) ListLabels(ctx context.Context, limit int, cursor string) (Labe
 		return LabelListResult{}, err
 	}
 
-	rawItems, next := extractItemsAndCursor(respMap)
+	rawItems, next, prev := extractItemsAndCursor(respMap)
 	labels := make([]Label, 0, len(rawItems))
 	for _, raw := range rawItems {
-		label := mapLabel(raw)
+		label := mapLabel(raw, c.fields)
 		if strings.TrimSpace(label.Name) == "" {
 			continue
 		}
 		labels = append(labels, label)
 	}
-	return LabelListResult{Labels: labels, NextCursor: next}, nil
+	return LabelListResult{Labels: labels, NextCursor: next, PrevCursor: prev}, nil
+}
+
+// ListCollections lists bookmark collections. Readeck versions without
+// collections yield an empty list rather than an error.
+func (c *Client) ListCollections(ctx context.Context, limit int, cursor string) (CollectionListResult, error) {
+	if limit <= 0 {
+		limit = c.listLimit
+	}
+	if limit > maxLabelsLimit {
+		limit = maxLabelsLimit
+	}
+
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	respMap, err := c.getObject(ctx, "/collections", params)
+	if err != nil {
+		if httpErr := new(HTTPError); errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			respMap, err = c.getObject(ctx, "/bookmarks/collections", params)
+		}
+	}
+	if err != nil {
+		if httpErr := new(HTTPError); errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			return CollectionListResult{Collections: []Collection{}}, nil
+		}
+		return CollectionListResult{}, err
+	}
+
+	rawItems, next, prev := extractItemsAndCursor(respMap)
+	collections := make([]Collection, 0, len(rawItems))
+	for _, raw := range rawItems {
+		collection := mapCollection(raw, c.fields)
+		if strings.TrimSpace(collection.Name) == "" && collection.ID == "" {
+			continue
+		}
+		collections = append(collections, collection)
+	}
+	return CollectionListResult{Collections: collections, NextCursor: next, PrevCursor: prev}, nil
 }
 
 func (c *Client) SetLabels(ctx context.Context, id string, labels []string) (SetLabelsResult, error) {
@@ -243,8 +501,14 @@ func (c *Client) SetLabels(ctx context.Context, id string, labels []string) (Set
 		return SetLabelsResult{}, err
 	}
 
-	bookmark := mapBookmark(obj)
-	result := SetLabelsResult{ID: bookmark.ID, Labels: normalized}
+	result := SetLabelsResult{ID: id, Labels: normalized}
+	if obj == nil {
+		return result, nil
+	}
+	bookmark := mapBookmark(obj, c.fields)
+	if bookmark.ID != "" {
+		result.ID = bookmark.ID
+	}
 	if len(bookmark.Labels) > 0 {
 		result.Labels = labelNames(bookmark.Labels)
 	}
@@ -253,7 +517,7 @@ func (c *Client) SetLabels(ctx context.Context, id string, labels []string) (Set
 
 func (c *Client) ListHighlights(ctx context.Context, bookmarkID string, limit, offset int) (HighlightListResult, error) {
 	if limit <= 0 {
-		limit = defaultListLimit
+		limit = c.listLimit
 	}
 	if limit > maxLabelsLimit {
 		limit = maxLabelsLimit
@@ -278,10 +542,10 @@ func (c *Client) listHighlights(ctx context.Context, endpoint string, params url
 		return HighlightListResult{}, err
 	}
 
-	rawItems, next := extractItemsAndCursor(respMap)
+	rawItems, next, prev := extractItemsAndCursor(respMap)
 	highlights := make([]Highlight, 0, len(rawItems))
 	for _, raw := range rawItems {
-		h := mapHighlight(raw)
+		h := mapHighlight(raw, c.fields)
 		if h.ID == "" {
 			continue
 		}
@@ -299,44 +563,142 @@ func (c *Client) listHighlights(ctx context.Context, endpoint string, params url
 		}
 	}
 
-	return HighlightListResult{Highlights: highlights, NextCursor: next}, nil
+	return HighlightListResult{Highlights: highlights, NextCursor: next, PrevCursor: prev}, nil
 }
 
-func (c *Client) fetchContent(ctx context.Context, id string) (string, string, error) {
-	candidates := []string{
-		"/bookmarks/" + url.PathEscape(id) + "/content",
-		"/bookmarks/" + url.PathEscape(id) + "/article",
-		"/bookmarks/" + url.PathEscape(id) + "/text",
-	}
-	for _, endpoint := range candidates {
-		obj, err := c.getObject(ctx, endpoint, nil)
-		if err != nil {
-			if httpErr := new(HTTPError); errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
-				continue
+type articleContent struct {
+	text        string
+	html        string
+	unavailable string
+}
+
+// fetchContent tries each content endpoint in turn. Binary responses such as
+// PDFs are skipped so a later extracted-text endpoint can still win; if none
+// does, the binary type is reported as the reason content is unavailable.
+// Misses and server errors (after do's own retries) also move on to the next
+// candidate; a server error is only returned when no candidate yields anything.
+// Plain text and HTML bodies are taken as the article itself.
+func (c *Client) fetchContent(ctx context.Context, id string) (articleContent, error) {
+	var out articleContent
+	var serverErr error
+	for _, suffix := range c.contentEndpoints {
+		endpoint := "/bookmarks/" + url.PathEscape(id) + suffix
+		shared := c.getShared(ctx, endpoint, nil)
+		if shared.err != nil {
+			if httpErr := new(HTTPError); errors.As(shared.err, &httpErr) {
+				if httpErr.StatusCode == http.StatusNotFound {
+					continue
+				}
+				if httpErr.StatusCode >= 500 {
+					serverErr = shared.err
+					continue
+				}
 			}
-			return "", "", err
+			return articleContent{}, shared.err
 		}
-		text := firstNonEmptyString(obj, "content_text", "text", "content", "article")
-		html := firstNonEmptyString(obj, "content_html", "html")
+		mediaType := contentMediaType(shared.contentType)
+		if isBinaryMediaType(mediaType) {
+			out.unavailable = "binary content: " + mediaType
+			continue
+		}
+		if mediaType == "text/plain" {
+			if text := strings.TrimSpace(string(shared.body)); text != "" {
+				return articleContent{text: text}, nil
+			}
+			continue
+		}
+		if isHTMLContentType(mediaType) {
+			if html := strings.TrimSpace(string(shared.body)); html != "" {
+				return articleContent{html: html}, nil
+			}
+			continue
+		}
+		obj, err := decodeObject(endpoint, shared)
+		if err != nil {
+			return articleContent{}, err
+		}
+		text := firstNonEmptyString(obj, c.fields.keys("bookmark.content_text", "content_text", "text", "content", "article")...)
+		html := firstNonEmptyString(obj, c.fields.keys("bookmark.content_html", "content_html", "html")...)
 		if text != "" || html != "" {
-			return text, html, nil
+			return articleContent{text: text, html: html}, nil
 		}
 	}
-	return "", "", nil
+	if serverErr != nil && out.unavailable == "" {
+		return articleContent{}, serverErr
+	}
+	return out, nil
+}
+
+func (c *Client) fetchIcon(ctx context.Context, iconURL string) (string, error) {
+	u, err := url.Parse(iconURL)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(c.apiBase)
+	if err != nil {
+		return "", err
+	}
+	u = base.ResolveReference(u)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("unsupported icon url scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if u.Host == base.Host {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	if err := c.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer c.release()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("icon returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxIconBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxIconBytes {
+		return "", errors.New("icon exceeds size limit")
+	}
+
+	mimeType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("icon has non-image content type %q", mimeType)
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
 }
 
-func normalizeSearchOptions(opts SearchOptions, maxPageSize int) SearchOptions {
+func normalizeSearchOptions(opts SearchOptions, defaultLimit, maxPageSize int) SearchOptions {
 	opts.Archived = normalizeArchivedMode(opts.Archived)
 	if opts.Sort == "" {
 		opts.Sort = SortUpdatedDesc
 	}
 	if opts.Limit <= 0 {
-		opts.Limit = defaultSearchLimit
+		opts.Limit = defaultLimit
 	}
 	if opts.Limit > maxPageSize {
 		opts.Limit = maxPageSize
 	}
 	opts.Labels = normalizeLabels(opts.Labels)
+	if opts.LabelMode != LabelMatchAny {
+		opts.LabelMode = LabelMatchAll
+	}
 	return opts
 }
 
@@ -349,7 +711,7 @@ func normalizeArchivedMode(mode ArchivedMode) ArchivedMode {
 	}
 }
 
-func buildSearchQuery(opts SearchOptions) url.Values {
+func (c *Client) buildSearchQuery(opts SearchOptions) url.Values {
 	params := url.Values{}
 	search := strings.TrimSpace(opts.Query)
 	if text := strings.TrimSpace(opts.Text); text != "" {
@@ -365,14 +727,18 @@ func buildSearchQuery(opts SearchOptions) url.Values {
 	if title := strings.TrimSpace(opts.Title); title != "" {
 		params.Set("title", title)
 	}
-	if len(opts.Labels) > 0 {
+	// Upstream matches all labels, so any-mode filtering happens locally.
+	if len(opts.Labels) > 0 && opts.LabelMode != LabelMatchAny {
 		params.Set("labels", strings.Join(opts.Labels, ","))
 	}
+	if collection := strings.TrimSpace(opts.Collection); collection != "" {
+		params.Set("collection", collection)
+	}
 	if opts.Favorites != nil {
-		params.Set("favorite", strconv.FormatBool(*opts.Favorites))
+		params.Set("favorite", c.formatBool(*opts.Favorites))
 	}
-	if opts.Sort != "" {
-		params.Set("sort", string(opts.Sort))
+	if token := c.sorts.token(opts.Sort); token != "" {
+		params.Set("sort", token)
 	}
 	if opts.Limit > 0 {
 		params.Set("limit", strconv.Itoa(opts.Limit))
@@ -383,22 +749,66 @@ func buildSearchQuery(opts SearchOptions) url.Values {
 	return params
 }
 
+// formatBool encodes a boolean query parameter in the configured style.
+func (c *Client) formatBool(v bool) string {
+	switch c.boolFormat {
+	case "onezero":
+		if v {
+			return "1"
+		}
+		return "0"
+	case "yesno":
+		if v {
+			return "yes"
+		}
+		return "no"
+	default:
+		return strconv.FormatBool(v)
+	}
+}
+
 func (c *Client) getObject(ctx context.Context, endpoint string, query url.Values) (map[string]any, error) {
-	respBytes, statusCode, reqID, err := c.do(ctx, http.MethodGet, endpoint, query, nil)
-	if err != nil {
-		return nil, err
+	shared := c.getShared(ctx, endpoint, query)
+	if shared.err != nil {
+		return nil, shared.err
+	}
+	return decodeObject(endpoint, shared)
+}
+
+// getShared issues a GET through the flight group. The upstream call runs on
+// a context detached from the first caller, bounded by the client's own
+// timeout for every attempt, so one caller cancelling does not fail the
+// others; it is cancelled once every caller has given up. It carries the
+// first caller's request id, and callers that join it log theirs against
+// the endpoint.
+func (c *Client) getShared(ctx context.Context, endpoint string, query url.Values) flightResult {
+	key := http.MethodGet + " " + endpoint + "?" + query.Encode()
+	result, shared := c.flights.do(ctx, key, func(callCtx context.Context) flightResult {
+		fetchCtx, cancel := context.WithTimeout(callCtx, maxAttempts*c.timeout)
+		defer cancel()
+		body, statusCode, reqID, contentType, err := c.do(fetchCtx, http.MethodGet, endpoint, query, nil)
+		return flightResult{body: body, statusCode: statusCode, requestID: reqID, contentType: contentType, err: err}
+	})
+	if shared {
+		requestID, _ := ctx.Value(requestIDKey).(string)
+		c.logger.Printf("request_id=%s method=GET endpoint=%s coalesced=true", requestID, endpoint)
 	}
+	return result
+}
+
+func decodeObject(endpoint string, shared flightResult) (map[string]any, error) {
+	respBytes, statusCode, reqID, contentType := shared.body, shared.statusCode, shared.requestID, shared.contentType
 	if len(respBytes) == 0 {
 		return map[string]any{}, nil
 	}
 
 	var obj map[string]any
-	if err := json.Unmarshal(respBytes, &obj); err == nil {
+	if err := unmarshalJSON(respBytes, &obj); err == nil {
 		return obj, nil
 	}
 
 	var arr []map[string]any
-	if err := json.Unmarshal(respBytes, &arr); err == nil {
+	if err := unmarshalJSON(respBytes, &arr); err == nil {
 		return map[string]any{"items": arr}, nil
 	}
 
@@ -406,31 +816,50 @@ func (c *Client) getObject(ctx context.Context, endpoint string, query url.Value
 		StatusCode: statusCode,
 		Endpoint:   endpoint,
 		RequestID:  reqID,
-		Message:    "decode response: unsupported JSON shape",
+		Message:    decodeErrorMessage("unsupported JSON shape", contentType, respBytes),
 	}
 }
 
+// unmarshalJSON decodes like json.Unmarshal but keeps numbers as
+// json.Number, so numeric IDs beyond 2^53 survive intact.
+func unmarshalJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
+// requestObject returns a nil map for 204 No Content: the write succeeded and
+// there is nothing to map.
 func (c *Client) requestObject(ctx context.Context, method, endpoint string, query url.Values, body any) (map[string]any, error) {
-	respBytes, statusCode, reqID, err := c.do(ctx, method, endpoint, query, body)
+	respBytes, statusCode, reqID, contentType, err := c.do(ctx, method, endpoint, query, body)
 	if err != nil {
 		return nil, err
 	}
+	if statusCode == http.StatusNoContent {
+		return nil, nil
+	}
 	if len(respBytes) == 0 {
 		return map[string]any{}, nil
 	}
 
 	var obj map[string]any
-	if unmarshalErr := json.Unmarshal(respBytes, &obj); unmarshalErr != nil {
-		return nil, &HTTPError{StatusCode: statusCode, Endpoint: endpoint, RequestID: reqID, Message: fmt.Sprintf("decode response: %v", unmarshalErr)}
+	if unmarshalErr := unmarshalJSON(respBytes, &obj); unmarshalErr != nil {
+		return nil, &HTTPError{StatusCode: statusCode, Endpoint: endpoint, RequestID: reqID, Message: decodeErrorMessage(unmarshalErr.Error(), contentType, respBytes)}
 	}
 	return obj, nil
 }
 
-func (c *Client) do(ctx context.Context, method, endpoint string, query url.Values, body any) ([]byte, int, string, error) {
+func (c *Client) do(ctx context.Context, method, endpoint string, query url.Values, body any) ([]byte, int, string, string, error) {
 	endpoint = "/" + strings.TrimLeft(endpoint, "/")
 	u, err := url.Parse(c.apiBase)
 	if err != nil {
-		return nil, 0, "", err
+		return nil, 0, "", "", err
 	}
 	u.Path = strings.TrimRight(u.Path, "/") + endpoint
 	if query != nil {
@@ -441,46 +870,97 @@ func (c *Client) do(ctx context.Context, method, endpoint string, query url.Valu
 	if body != nil {
 		payload, err = json.Marshal(body)
 		if err != nil {
-			return nil, 0, "", err
+			return nil, 0, "", "", err
 		}
 	}
 
+	// Writes go through the queue one at a time and are retried on 429 only;
+	// a throttled write was not applied, so retrying it is safe.
+	write := method != http.MethodGet
+	if write {
+		if err := c.writes.acquire(ctx); err != nil {
+			return nil, 0, "", "", err
+		}
+		defer c.writes.release()
+	}
+
 	attempt := 0
 	for {
 		attempt++
-		statusCode, requestID, respBytes, reqErr := c.doOnce(ctx, method, endpoint, u.String(), payload, attempt-1)
+		if write {
+			if err := c.writes.pace(ctx); err != nil {
+				return nil, 0, "", "", err
+			}
+		}
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, 0, "", "", err
+		}
+		if err := c.acquire(ctx); err != nil {
+			return nil, 0, "", "", err
+		}
+		statusCode, requestID, contentType, respBytes, reqErr := c.doOnce(ctx, method, endpoint, u.String(), payload, attempt-1)
+		c.release()
 		if reqErr != nil {
-			return nil, statusCode, requestID, reqErr
+			return nil, statusCode, requestID, contentType, reqErr
 		}
 
-		if method == http.MethodGet && (statusCode == http.StatusTooManyRequests || statusCode >= 500) && attempt < 4 {
+		if method == http.MethodGet && (statusCode == http.StatusTooManyRequests || statusCode >= 500) && attempt < maxAttempts {
 			backoff := retryBackoff(attempt)
 			if err := waitForRetry(ctx, backoff); err != nil {
-				return nil, statusCode, requestID, err
+				return nil, statusCode, requestID, contentType, err
 			}
 			continue
 		}
+		if write && statusCode == http.StatusTooManyRequests {
+			c.writes.throttled()
+			if attempt < maxAttempts {
+				continue
+			}
+		} else if write && statusCode < 400 {
+			c.writes.succeeded()
+		}
+
+		if isHTMLContentType(contentType) && !c.isContentEndpoint(endpoint) {
+			return nil, statusCode, requestID, contentType, &HTTPError{
+				StatusCode: statusCode,
+				Endpoint:   endpoint,
+				RequestID:  requestID,
+				Message:    fmt.Sprintf("upstream returned an HTML page instead of JSON (status %d) for %s; check that READECK_BASE_URL points at the Readeck root, not a UI page", statusCode, u.Path),
+			}
+		}
 
 		if statusCode >= 400 {
-			return nil, statusCode, requestID, &HTTPError{
+			return nil, statusCode, requestID, contentType, &HTTPError{
 				StatusCode: statusCode,
 				Endpoint:   endpoint,
 				RequestID:  requestID,
 				Message:    fmt.Sprintf("upstream returned status %d", statusCode),
 			}
 		}
-		return respBytes, statusCode, requestID, nil
+		return respBytes, statusCode, requestID, contentType, nil
 	}
 }
 
-func (c *Client) doOnce(ctx context.Context, method, endpoint, fullURL string, payload []byte, retries int) (int, string, []byte, error) {
+// isContentEndpoint reports whether endpoint is one of the article content
+// candidates, which may legitimately answer with HTML. Every other endpoint
+// is JSON, so an HTML page there means the base URL points at the UI.
+func (c *Client) isContentEndpoint(endpoint string) bool {
+	rest, ok := strings.CutPrefix(endpoint, "/bookmarks/")
+	if !ok {
+		return false
+	}
+	_, suffix, ok := strings.Cut(rest, "/")
+	return ok && slices.Contains(c.contentEndpoints, "/"+suffix)
+}
+
+func (c *Client) doOnce(ctx context.Context, method, endpoint, fullURL string, payload []byte, retries int) (int, string, string, []byte, error) {
 	var body io.Reader
 	if len(payload) > 0 {
 		body = bytes.NewReader(payload)
 	}
 	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
 	if err != nil {
-		return 0, "", nil, err
+		return 0, "", "", nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.token)
@@ -489,24 +969,40 @@ func (c *Client) doOnce(ctx context.Context, method, endpoint, fullURL string, p
 	if len(payload) > 0 {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	if requestID, _ := ctx.Value(requestIDKey).(string); requestID != "" && req.Header.Get("X-Request-Id") == "" {
+		req.Header.Set("X-Request-Id", requestID)
+	}
 
 	start := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		c.logRequest(ctx, method, endpoint, 0, time.Since(start), 0, retries)
-		return 0, "", nil, err
+		return 0, "", "", nil, err
 	}
 	defer resp.Body.Close()
 
 	respBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return resp.StatusCode, "", nil, err
+		return resp.StatusCode, "", "", nil, err
 	}
 
 	requestID := firstNonEmpty(resp.Header.Get("X-Request-Id"), resp.Header.Get("X-Request-ID"))
 	c.logRequest(ctx, method, endpoint, resp.StatusCode, time.Since(start), len(respBytes), retries)
 
-	return resp.StatusCode, requestID, respBytes, nil
+	return resp.StatusCode, requestID, resp.Header.Get("Content-Type"), respBytes, nil
+}
+
+func (c *Client) acquire(ctx context.Context) error {
+	select {
+	case c.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) release() {
+	<-c.sem
 }
 
 func (c *Client) logRequest(ctx context.Context, method, endpoint string, status int, latency time.Duration, size int, retries int) {
@@ -514,12 +1010,13 @@ func (c *Client) logRequest(ctx context.Context, method, endpoint string, status
 	c.logger.Printf("request_id=%s method=%s endpoint=%s status=%d latency_ms=%d retries=%d bytes=%d", requestID, method, endpoint, status, latency.Milliseconds(), retries, size)
 }
 
-func extractItemsAndCursor(obj map[string]any) ([]map[string]any, string) {
+func extractItemsAndCursor(obj map[string]any) ([]map[string]any, string, string) {
 	if obj == nil {
-		return nil, ""
+		return nil, "", ""
 	}
 
 	next := firstNonEmptyString(obj, "next_cursor", "next", "cursor")
+	prev := firstNonEmptyString(obj, "prev_cursor", "previous_cursor", "prev", "previous")
 	items := extractArrayMap(obj, "items")
 	if len(items) == 0 {
 		items = extractArrayMap(obj, "results")
@@ -543,7 +1040,16 @@ func extractItemsAndCursor(obj map[string]any) ([]map[string]any, string) {
 		}
 	}
 
-	return items, next
+	return items, next, prev
+}
+
+func extractTotal(obj map[string]any) (int, bool) {
+	for _, key := range []string{"total", "total_count", "count"} {
+		if _, ok := obj[key]; ok {
+			return firstInt(obj, key), true
+		}
+	}
+	return 0, false
 }
 
 func matchesFilters(b Bookmark, opts SearchOptions) bool {
@@ -559,31 +1065,72 @@ func matchesFilters(b Bookmark, opts SearchOptions) bool {
 	if opts.Title != "" && !containsFold(b.Title, opts.Title) {
 		return false
 	}
+	if opts.Collection != "" && !strings.EqualFold(b.Collection, opts.Collection) {
+		return false
+	}
 	if len(opts.Labels) > 0 {
-		have := map[string]struct{}{}
-		for _, l := range b.Labels {
-			have[strings.ToLower(strings.TrimSpace(l.Name))] = struct{}{}
-		}
-		for _, want := range opts.Labels {
-			if _, ok := have[strings.ToLower(want)]; !ok {
-				return false
-			}
+		matched := countLabelMatches(labelNames(b.Labels), opts.Labels)
+		if opts.LabelMode == LabelMatchAny {
+			return matched > 0
 		}
+		return matched == len(opts.Labels)
 	}
 	return true
 }
 
-func sortSummaries(items []BookmarkSummary, mode SortMode) {
+// labelsFor returns the requested labels when their match count should
+// influence ordering: any-mode searches sorted by relevance or label_match.
+func labelsFor(opts SearchOptions) []string {
+	if opts.LabelMode != LabelMatchAny {
+		return nil
+	}
+	if opts.Sort != SortRelevance && opts.Sort != SortLabelMatch {
+		return nil
+	}
+	return opts.Labels
+}
+
+func countLabelMatches(have, want []string) int {
+	set := make(map[string]struct{}, len(have))
+	for _, l := range have {
+		set[strings.ToLower(strings.TrimSpace(l))] = struct{}{}
+	}
+	count := 0
+	for _, w := range want {
+		if _, ok := set[strings.ToLower(w)]; ok {
+			count++
+		}
+	}
+	return count
+}
+
+// RankByLabels orders items by how many of labels they carry, most first,
+// then by update time, as the label_match sort does.
+func RankByLabels(items []BookmarkSummary, labels []string) {
+	sortSummaries(items, SortLabelMatch, normalizeLabels(labels))
+}
+
+func sortSummaries(items []BookmarkSummary, mode SortMode, labels []string) {
 	if mode == "" {
 		mode = SortUpdatedDesc
 	}
-	if mode == SortRelevance {
+	if mode == SortRelevance && len(labels) == 0 {
 		return
 	}
 
 	sort.SliceStable(items, func(i, j int) bool {
 		left := items[i]
 		right := items[j]
+		if len(labels) > 0 {
+			leftMatches := countLabelMatches(left.Labels, labels)
+			rightMatches := countLabelMatches(right.Labels, labels)
+			if leftMatches != rightMatches {
+				return leftMatches > rightMatches
+			}
+			if mode == SortRelevance {
+				return false
+			}
+		}
 		switch mode {
 		case SortPublishedDesc:
 			return left.PublishedAt > right.PublishedAt
@@ -629,6 +1176,9 @@ func containsFold(s, substr string) bool {
 
 func snippetFromMap(obj map[string]any) string {
 	s := firstNonEmptyString(obj, "snippet", "excerpt", "summary", "description", "content_text")
+	if htmlTagRe.MatchString(s) {
+		s = stripHTML(s)
+	}
 	s = strings.TrimSpace(strings.ReplaceAll(s, "\n", " "))
 	if len(s) > 280 {
 		s = s[:280] + "..."
@@ -636,6 +1186,16 @@ func snippetFromMap(obj map[string]any) string {
 	return s
 }
 
+var htmlTagRe = regexp.MustCompile(`(?s)<(?:[a-zA-Z][a-zA-Z0-9-]*|/[a-zA-Z][a-zA-Z0-9-]*|!--)[^>]*>`)
+
+// stripHTML turns markup that upstream sometimes stores in text fields into
+// plain text: tags become spaces, entities are decoded and whitespace runs
+// collapse.
+func stripHTML(s string) string {
+	s = htmlTagRe.ReplaceAllString(s, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if strings.TrimSpace(v) != "" {
@@ -645,9 +1205,53 @@ func firstNonEmpty(values ...string) string {
 	return ""
 }
 
+const maxDecodeSnippet = 200
+
+func decodeErrorMessage(reason, contentType string, body []byte) string {
+	if isJSONContentType(contentType) {
+		return "decode response: " + reason
+	}
+	snippet := strings.TrimSpace(string(body))
+	if len(snippet) > maxDecodeSnippet {
+		snippet = snippet[:maxDecodeSnippet] + "..."
+	}
+	if contentType == "" {
+		contentType = "none"
+	}
+	return fmt.Sprintf("decode response: expected JSON but got content type %s: %s", contentType, snippet)
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "application/json" {
+		return true
+	}
+	return strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")
+}
+
+func isHTMLContentType(contentType string) bool {
+	mediaType := contentMediaType(contentType)
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+func contentMediaType(contentType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
+
+func isBinaryMediaType(mediaType string) bool {
+	switch {
+	case mediaType == "application/pdf", mediaType == "application/octet-stream":
+		return true
+	case strings.HasPrefix(mediaType, "image/"), strings.HasPrefix(mediaType, "audio/"), strings.HasPrefix(mediaType, "video/"):
+		return true
+	default:
+		return false
+	}
+}
+
 func retryBackoff(attempt int) time.Duration {
 	// attempt is 1-based in caller; retries start after first attempt.
-	return 200 * time.Millisecond * time.Duration(1<<(attempt-1))
+	return retryBaseDelay * time.Duration(1<<(attempt-1))
 }
 
 func waitForRetry(ctx context.Context, d time.Duration) error {
@@ -0,0 +1,803 @@
+package readeck
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/akrisanov/readeck-mcp/internal/config"
+)
+
+// newTestClient points a client at a stub upstream. env holds extra
+// name/value pairs applied before config.Load.
+func newTestClient(t *testing.T, handler http.HandlerFunc, env ...string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	t.Setenv("READECK_BASE_URL", srv.URL)
+	t.Setenv("READECK_API_TOKEN", "test-token")
+	for i := 0; i+1 < len(env); i += 2 {
+		t.Setenv(env[i], env[i+1])
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	return NewClient(cfg, nil)
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Errorf("encode response: %v", err)
+	}
+}
+
+func labeled(id, updated string, labels ...string) map[string]any {
+	return map[string]any{"id": id, "title": id, "url": "https://example.com/" + id, "updated": updated, "labels": labels}
+}
+
+func ids(items []BookmarkSummary) []string {
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		out = append(out, item.ID)
+	}
+	return out
+}
+
+func TestSearchAnyModeFillsPageAcrossUpstreamPages(t *testing.T) {
+	pages := map[string]map[string]any{
+		"": {"items": []any{
+			labeled("none", "2024-01-09T00:00:00Z", "other"),
+			labeled("one", "2024-01-08T00:00:00Z", "a"),
+		}, "next_cursor": "p2"},
+		"p2": {"items": []any{
+			labeled("two", "2024-01-07T00:00:00Z", "a", "b"),
+			labeled("none2", "2024-01-06T00:00:00Z"),
+		}, "next_cursor": "p3"},
+		"p3": {"items": []any{
+			labeled("three", "2024-01-05T00:00:00Z", "a", "b", "c"),
+		}, "next_cursor": "p4"},
+	}
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("labels") {
+			t.Errorf("any-mode search sent labels upstream: %s", r.URL.RawQuery)
+		}
+		cursor := r.URL.Query().Get("cursor")
+		requests = append(requests, cursor)
+		writeJSON(t, w, pages[cursor])
+	})
+
+	result, err := client.Search(context.Background(), SearchOptions{
+		Labels:    []string{"a", "b", "c"},
+		LabelMode: LabelMatchAny,
+		Sort:      SortLabelMatch,
+		Limit:     3,
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if got, want := ids(result.Items), []string{"three", "two", "one"}; !slices.Equal(got, want) {
+		t.Fatalf("items = %v, want %v", got, want)
+	}
+	if len(requests) != 3 {
+		t.Fatalf("upstream pages fetched = %v, want 3", requests)
+	}
+	if result.NextCursor != "p4" || result.Partial {
+		t.Fatalf("next_cursor = %q partial = %v, want p4 and not partial", result.NextCursor, result.Partial)
+	}
+}
+
+func TestSearchAnyModeResumesInsideAFullPage(t *testing.T) {
+	pages := map[string]map[string]any{
+		"": {"items": []any{
+			labeled("m1", "2024-01-09T00:00:00Z", "a"),
+			labeled("none", "2024-01-08T00:00:00Z", "other"),
+		}, "next_cursor": "p2"},
+		"p2": {"items": []any{
+			labeled("m2", "2024-01-07T00:00:00Z", "a"),
+			labeled("m3", "2024-01-06T00:00:00Z", "a", "b"),
+			labeled("m4", "2024-01-05T00:00:00Z", "b"),
+		}, "next_cursor": "p3"},
+		"p3": {"items": []any{}},
+	}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		if _, ok := pages[cursor]; !ok {
+			t.Errorf("upstream got cursor %q", cursor)
+		}
+		writeJSON(t, w, pages[cursor])
+	})
+
+	var seen []string
+	var cursors []string
+	opts := SearchOptions{Labels: []string{"a", "b"}, LabelMode: LabelMatchAny, Sort: SortLabelMatch, Limit: 2}
+	for range 5 {
+		result, err := client.Search(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		seen = append(seen, ids(result.Items)...)
+		cursors = append(cursors, result.NextCursor)
+		if result.NextCursor == "" {
+			break
+		}
+		opts.Cursor = result.NextCursor
+	}
+	slices.Sort(seen)
+	if want := []string{"m1", "m2", "m3", "m4"}; !slices.Equal(seen, want) {
+		t.Fatalf("items across pages = %v, want %v (cursors %q)", seen, want, cursors)
+	}
+}
+
+func TestSearchAnyModeStopsAtScanCap(t *testing.T) {
+	calls := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		items := make([]any, 0, 100)
+		for range 100 {
+			items = append(items, labeled("x", "2024-01-01T00:00:00Z", "unrelated"))
+		}
+		writeJSON(t, w, map[string]any{"items": items, "next_cursor": r.URL.Query().Get("cursor") + "n"})
+	})
+
+	result, err := client.Search(context.Background(), SearchOptions{
+		Labels:    []string{"a"},
+		LabelMode: LabelMatchAny,
+		Limit:     100,
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if !result.Partial || len(result.Items) != 0 {
+		t.Fatalf("partial = %v items = %d, want partial and no items", result.Partial, len(result.Items))
+	}
+	if calls != maxCountScan/100 {
+		t.Fatalf("upstream calls = %d, want %d", calls, maxCountScan/100)
+	}
+}
+
+func TestSortSummariesRanksByLabelMatches(t *testing.T) {
+	items := []BookmarkSummary{
+		{ID: "one-new", Labels: []string{"a"}, UpdatedAt: "2024-03-01"},
+		{ID: "two", Labels: []string{"A", "b"}, UpdatedAt: "2024-01-01"},
+		{ID: "one-old", Labels: []string{"b"}, UpdatedAt: "2024-02-01"},
+		{ID: "three", Labels: []string{"a", "b", "c"}, UpdatedAt: "2023-01-01"},
+	}
+	sortSummaries(items, SortLabelMatch, []string{"a", "b", "c"})
+	if got, want := ids(items), []string{"three", "two", "one-new", "one-old"}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
+
+func TestAllModeSendsLabelsUpstream(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("labels"); got != "a,b" {
+			t.Errorf("labels = %q, want a,b", got)
+		}
+		writeJSON(t, w, map[string]any{"items": []any{labeled("both", "2024-01-01T00:00:00Z", "a", "b")}})
+	})
+	result, err := client.Search(context.Background(), SearchOptions{Labels: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(result.Items) != 1 {
+		t.Fatalf("items = %v, want one", ids(result.Items))
+	}
+}
+
+func endlessPages(t *testing.T, labels ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, map[string]any{
+			"items":       []any{labeled("x", "2024-01-01T00:00:00Z", labels...)},
+			"next_cursor": r.URL.Query().Get("cursor") + "n",
+		})
+	}
+}
+
+func TestCountStopsNearDeadline(t *testing.T) {
+	client := newTestClient(t, endlessPages(t, "a"), "MCP_DEADLINE_SLACK_MS", "5000")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result, err := client.Count(ctx, SearchOptions{Labels: []string{"a"}, LabelMode: LabelMatchAny})
+	if err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	if !result.Partial || result.Count != 1 {
+		t.Fatalf("count = %d partial = %v, want 1 and partial", result.Count, result.Partial)
+	}
+}
+
+func TestSearchStopsNearDeadline(t *testing.T) {
+	client := newTestClient(t, endlessPages(t, "other"), "MCP_DEADLINE_SLACK_MS", "5000")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	result, err := client.Search(ctx, SearchOptions{Labels: []string{"a"}, LabelMode: LabelMatchAny})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if !result.Partial || result.NextCursor != "n" {
+		t.Fatalf("partial = %v next_cursor = %q, want partial after the first page", result.Partial, result.NextCursor)
+	}
+}
+
+func TestNearDeadline(t *testing.T) {
+	if NearDeadline(context.Background(), time.Hour) {
+		t.Fatal("context without deadline reported near")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if NearDeadline(ctx, time.Second) {
+		t.Fatal("a minute away reported near with 1s slack")
+	}
+	if !NearDeadline(ctx, 2*time.Minute) {
+		t.Fatal("a minute away not near with 2m slack")
+	}
+}
+
+func writeHTML(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(body))
+}
+
+func TestHTMLFromJSONEndpointPointsAtBaseURL(t *testing.T) {
+	for _, status := range []int{http.StatusOK, http.StatusNotFound} {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			writeHTML(w, status, "<!doctype html><title>Readeck</title>")
+		})
+
+		_, err := client.Search(context.Background(), SearchOptions{Limit: 10})
+		httpErr := new(HTTPError)
+		if !errors.As(err, &httpErr) {
+			t.Fatalf("status %d: err = %v, want *HTTPError", status, err)
+		}
+		if httpErr.Endpoint != "/bookmarks" || !strings.Contains(httpErr.Message, "READECK_BASE_URL") {
+			t.Fatalf("status %d: error = %+v", status, httpErr)
+		}
+	}
+}
+
+func TestHTMLFromContentEndpointIsArticle(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bookmarks/b1/article":
+			writeHTML(w, http.StatusOK, "<p>Article body</p>")
+		default:
+			writeHTML(w, http.StatusNotFound, "<p>Not found</p>")
+		}
+	})
+
+	text, html, err := client.GetContent(context.Background(), "b1")
+	if err != nil {
+		t.Fatalf("GetContent: %v", err)
+	}
+	if text != "" || html != "<p>Article body</p>" {
+		t.Fatalf("content = %q, %q", text, html)
+	}
+}
+
+func TestIncludeIconInlinesImageData(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bookmarks/b1":
+			writeJSON(t, w, map[string]any{"id": "b1", "icon_url": "/icons/b1.png"})
+		case "/api/bookmarks/b2":
+			writeJSON(t, w, map[string]any{"id": "b2", "icon_url": "/icons/b2.txt"})
+		case "/api/bookmarks/b3":
+			writeJSON(t, w, map[string]any{"id": "b3", "icon_url": "/icons/big.png"})
+		case "/icons/b1.png":
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				t.Errorf("same-host icon fetched without the token")
+			}
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(png)
+		case "/icons/b2.txt":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("not an image"))
+		case "/icons/big.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(make([]byte, maxIconBytes+1))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+
+	bm, err := client.GetBookmark(ctx, "b1", IncludeOptions{Icon: true})
+	if err != nil {
+		t.Fatalf("GetBookmark: %v", err)
+	}
+	if want := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png); bm.IconData != want || len(bm.Warnings) != 0 {
+		t.Fatalf("icon_data = %q warnings = %v", bm.IconData, bm.Warnings)
+	}
+	if bm, _ := client.GetBookmark(ctx, "b1", IncludeOptions{}); bm.IconData != "" {
+		t.Fatalf("icon fetched without include.icon")
+	}
+	for _, id := range []string{"b2", "b3"} {
+		bm, err := client.GetBookmark(ctx, id, IncludeOptions{Icon: true})
+		if err != nil || bm.IconData != "" || len(bm.Warnings) != 1 {
+			t.Fatalf("%s: icon_data = %q warnings = %v err = %v", id, bm.IconData, bm.Warnings, err)
+		}
+	}
+}
+
+func TestContentEndpointsAreTriedInConfiguredOrder(t *testing.T) {
+	var tried []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		tried = append(tried, r.URL.Path)
+		switch r.URL.Path {
+		case "/api/bookmarks/b1/text", "/api/bookmarks/b1/content":
+			writeJSON(t, w, map[string]any{"content_text": "from " + r.URL.Path})
+		default:
+			http.NotFound(w, r)
+		}
+	}, "READECK_CONTENT_ENDPOINTS", "/article/, text,content")
+
+	text, _, err := client.GetContent(context.Background(), "b1")
+	if err != nil || text != "from /api/bookmarks/b1/text" {
+		t.Fatalf("GetContent = %q, %v", text, err)
+	}
+	if want := []string{"/api/bookmarks/b1/article", "/api/bookmarks/b1/text"}; !slices.Equal(tried, want) {
+		t.Fatalf("tried %v, want %v", tried, want)
+	}
+}
+
+func TestDecodeErrorsNameTheContentType(t *testing.T) {
+	tests := []struct {
+		contentType, body string
+		want              []string
+	}{
+		{"text/plain; charset=utf-8", "upstream proxy says hello", []string{"content type text/plain", "upstream proxy says hello"}},
+		{"", "oops", []string{"content type none", "oops"}},
+		{"application/json", `{"items": [`, []string{"decode response: unsupported JSON shape"}},
+	}
+	for _, tt := range tests {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header()["Content-Type"] = []string{tt.contentType}
+			_, _ = w.Write([]byte(tt.body))
+		})
+		_, err := client.Search(context.Background(), SearchOptions{})
+		if err == nil {
+			t.Fatalf("%q: Search succeeded", tt.contentType)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%q: error %q does not mention %q", tt.contentType, err, want)
+			}
+		}
+	}
+}
+
+func TestConcurrencyNeverExceedsLimit(t *testing.T) {
+	var inflight, peak atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		writeJSON(t, w, map[string]any{"id": strings.TrimPrefix(r.URL.Path, "/api/bookmarks/")})
+	}, "READECK_MAX_CONCURRENCY", "2")
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.GetBookmark(context.Background(), fmt.Sprint("b", i), IncludeOptions{}); err != nil {
+				t.Errorf("GetBookmark: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if p := peak.Load(); p != 2 {
+		t.Fatalf("peak concurrency = %d, want 2", p)
+	}
+}
+
+func TestPDFContentIsReportedUnavailable(t *testing.T) {
+	withText := false
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bookmarks/b1":
+			writeJSON(t, w, map[string]any{"id": "b1", "title": "Paper.pdf"})
+		case "/api/bookmarks/b1/content":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write([]byte("%PDF-1.7 binary"))
+		case "/api/bookmarks/b1/text":
+			if withText {
+				w.Header().Set("Content-Type", "text/plain")
+				_, _ = w.Write([]byte("Extracted paper text"))
+				return
+			}
+			http.NotFound(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	bm, err := client.GetBookmark(context.Background(), "b1", IncludeOptions{Content: true})
+	if err != nil {
+		t.Fatalf("GetBookmark: %v", err)
+	}
+	if bm.ContentText != "" || bm.ContentHTML != "" || bm.ContentUnavailableReason != "binary content: application/pdf" {
+		t.Fatalf("content = %q/%q reason = %q", bm.ContentText, bm.ContentHTML, bm.ContentUnavailableReason)
+	}
+
+	withText = true
+	bm, err = client.GetBookmark(context.Background(), "b1", IncludeOptions{Content: true})
+	if err != nil || bm.ContentText != "Extracted paper text" || bm.ContentUnavailableReason != "" {
+		t.Fatalf("content = %q reason = %q err = %v", bm.ContentText, bm.ContentUnavailableReason, err)
+	}
+}
+
+func TestCountUsesUpstreamTotal(t *testing.T) {
+	var queries []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		writeJSON(t, w, map[string]any{"items": []any{labeled("x", "2024-01-01T00:00:00Z")}, "total": 42})
+	})
+
+	result, err := client.Count(context.Background(), SearchOptions{Archived: ArchivedInclude})
+	if err != nil || result.Count != 42 || result.Capped || result.Partial {
+		t.Fatalf("Count = %+v, %v", result, err)
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], "limit=1") {
+		t.Fatalf("queries = %q, want one limit=1 probe", queries)
+	}
+}
+
+func TestCountPaginatesWhenFilteringLocally(t *testing.T) {
+	pages := map[string]map[string]any{
+		"": {"items": []any{
+			map[string]any{"id": "a", "is_archived": false},
+			map[string]any{"id": "b", "is_archived": true},
+		}, "next_cursor": "p2", "total": 99},
+		"p2": {"items": []any{
+			map[string]any{"id": "c", "is_archived": false},
+		}},
+	}
+	var requested []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		cursor := r.URL.Query().Get("cursor")
+		requested = append(requested, cursor)
+		writeJSON(t, w, pages[cursor])
+	})
+
+	result, err := client.Count(context.Background(), SearchOptions{Archived: ArchivedExclude})
+	if err != nil || result.Count != 2 || result.Capped {
+		t.Fatalf("Count = %+v, %v; want 2 unarchived", result, err)
+	}
+	if !slices.Equal(requested, []string{"", "p2"}) {
+		t.Fatalf("pages requested = %q", requested)
+	}
+}
+
+func TestCountCapsTheScan(t *testing.T) {
+	client := newTestClient(t, endlessPages(t, "a"))
+	result, err := client.Count(context.Background(), SearchOptions{Labels: []string{"a"}, LabelMode: LabelMatchAny})
+	if err != nil || !result.Capped || result.Count == 0 {
+		t.Fatalf("Count = %+v, %v", result, err)
+	}
+}
+
+func TestBothCursorsAreCaptured(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bookmarks":
+			writeJSON(t, w, map[string]any{"items": []any{map[string]any{"id": "b1"}}, "next": "n1", "previous_cursor": "p1"})
+		case "/api/labels":
+			writeJSON(t, w, map[string]any{"items": []any{map[string]any{"name": "go"}}, "next_cursor": "n2", "prev": "p2"})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	search, err := client.Search(context.Background(), SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if search.NextCursor != "n1" || search.PrevCursor != "p1" {
+		t.Fatalf("search cursors = %q, %q", search.NextCursor, search.PrevCursor)
+	}
+	labels, err := client.ListLabels(context.Background(), 0, "")
+	if err != nil {
+		t.Fatalf("ListLabels: %v", err)
+	}
+	if labels.NextCursor != "n2" || labels.PrevCursor != "p2" {
+		t.Fatalf("label cursors = %q, %q", labels.NextCursor, labels.PrevCursor)
+	}
+}
+
+func TestNoContentWritesSucceed(t *testing.T) {
+	var requests []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodPatch {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(t, w, map[string]any{"id": "b1", "is_archived": true, "updated": "2024-05-01T00:00:00Z"})
+	})
+
+	archived, err := client.SetArchived(context.Background(), "b1", true)
+	if err != nil {
+		t.Fatalf("SetArchived: %v", err)
+	}
+	if archived.ID != "b1" || !archived.IsArchived || archived.UpdatedAt != "2024-05-01T00:00:00Z" {
+		t.Fatalf("archive result = %+v", archived)
+	}
+	if want := []string{"PATCH /api/bookmarks/b1", "GET /api/bookmarks/b1"}; !slices.Equal(requests, want) {
+		t.Fatalf("requests = %q, want %q", requests, want)
+	}
+
+	requests = nil
+	labels, err := client.SetLabels(context.Background(), "b1", []string{"go", " Go ", "rust"})
+	if err != nil {
+		t.Fatalf("SetLabels: %v", err)
+	}
+	if labels.ID != "b1" || !slices.Equal(labels.Labels, []string{"go", "rust"}) {
+		t.Fatalf("labels result = %+v", labels)
+	}
+	if want := []string{"PATCH /api/bookmarks/b1"}; !slices.Equal(requests, want) {
+		t.Fatalf("requests = %q, want %q", requests, want)
+	}
+}
+
+func TestRequestIDIsForwarded(t *testing.T) {
+	var got []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Request-Id"))
+		writeJSON(t, w, map[string]any{"id": "b1"})
+	})
+
+	if _, err := client.getObject(WithRequestID(context.Background(), "req-42"), "/bookmarks/b1", nil); err != nil {
+		t.Fatalf("getObject: %v", err)
+	}
+	if _, err := client.getObject(context.Background(), "/bookmarks/b2", nil); err != nil {
+		t.Fatalf("getObject: %v", err)
+	}
+	if !slices.Equal(got, []string{"req-42", ""}) {
+		t.Fatalf("X-Request-Id headers = %q", got)
+	}
+}
+
+func TestListCollectionsFallsBackAndDegrades(t *testing.T) {
+	var paths []string
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/api/bookmarks/collections" {
+			writeJSON(t, w, map[string]any{"items": []any{
+				map[string]any{"id": "c1", "name": "Reading"},
+				map[string]any{"title": "Later"},
+				map[string]any{},
+			}})
+			return
+		}
+		http.NotFound(w, r)
+	})
+
+	result, err := client.ListCollections(context.Background(), 0, "")
+	if err != nil {
+		t.Fatalf("ListCollections: %v", err)
+	}
+	if want := []Collection{{ID: "c1", Name: "Reading"}, {Name: "Later"}}; !slices.Equal(result.Collections, want) {
+		t.Fatalf("collections = %+v, want %+v", result.Collections, want)
+	}
+	if want := []string{"/api/collections", "/api/bookmarks/collections"}; !slices.Equal(paths, want) {
+		t.Fatalf("paths = %q, want %q", paths, want)
+	}
+
+	absent := newTestClient(t, http.NotFound)
+	result, err = absent.ListCollections(context.Background(), 0, "")
+	if err != nil || result.Collections == nil || len(result.Collections) != 0 {
+		t.Fatalf("without collections: %+v, %v", result, err)
+	}
+}
+
+func TestSearchFiltersByCollection(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("collection"); got != "reading" {
+			t.Errorf("collection param = %q", got)
+		}
+		writeJSON(t, w, map[string]any{"items": []any{
+			map[string]any{"id": "in", "collection": "Reading"},
+			map[string]any{"id": "out", "collection": "other"},
+		}})
+	})
+
+	result, err := client.Search(context.Background(), SearchOptions{Collection: "reading"})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].ID != "in" {
+		t.Fatalf("items = %+v", result.Items)
+	}
+}
+
+func TestContentServerErrorMovesToNextCandidate(t *testing.T) {
+	hits := map[string]int{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch r.URL.Path {
+		case "/api/bookmarks/b1/article":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "/api/bookmarks/b1/text":
+			writeJSON(t, w, map[string]any{"content_text": "recovered"})
+		default:
+			http.NotFound(w, r)
+		}
+	}, "READECK_CONTENT_ENDPOINTS", "article,text")
+
+	text, _, err := client.GetContent(context.Background(), "b1")
+	if err != nil || text != "recovered" {
+		t.Fatalf("GetContent = %q, %v", text, err)
+	}
+	if hits["/api/bookmarks/b1/article"] != maxAttempts || hits["/api/bookmarks/b1/text"] != 1 {
+		t.Fatalf("hits = %v, want the failing candidate retried %d times then the next one tried", hits, maxAttempts)
+	}
+}
+
+func TestContentServerErrorReturnedWhenAllCandidatesFail(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bookmarks/b1/article" {
+			http.Error(w, "boom", http.StatusBadGateway)
+			return
+		}
+		http.NotFound(w, r)
+	}, "READECK_CONTENT_ENDPOINTS", "article,text")
+
+	_, _, err := client.GetContent(context.Background(), "b1")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("err = %v, want the 502", err)
+	}
+}
+
+func TestFailedIncludesSurfaceWarnings(t *testing.T) {
+	status := http.StatusForbidden
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/bookmarks/b1" {
+			writeJSON(t, w, map[string]any{"id": "b1", "title": "Kept"})
+			return
+		}
+		http.Error(w, "nope", status)
+	}, "READECK_CONTENT_ENDPOINTS", "content")
+
+	bm, err := client.GetBookmark(context.Background(), "b1", IncludeOptions{Content: true, Highlights: true})
+	if err != nil {
+		t.Fatalf("GetBookmark: %v", err)
+	}
+	if bm.Title != "Kept" || len(bm.Warnings) != 2 {
+		t.Fatalf("bookmark = %q warnings = %q", bm.Title, bm.Warnings)
+	}
+	if !strings.HasPrefix(bm.Warnings[0], "content could not be loaded: ") || !strings.HasPrefix(bm.Warnings[1], "highlights could not be loaded: ") {
+		t.Fatalf("warnings = %q", bm.Warnings)
+	}
+
+	status = http.StatusNotFound
+	bm, err = client.GetBookmark(context.Background(), "b1", IncludeOptions{Content: true, Highlights: true})
+	if err != nil || len(bm.Warnings) != 0 {
+		t.Fatalf("missing includes: warnings = %q, err = %v", bm.Warnings, err)
+	}
+}
+
+func TestBoolQueryFormats(t *testing.T) {
+	tests := []struct{ format, yes, no string }{
+		{"", "true", "false"},
+		{"truefalse", "true", "false"},
+		{"onezero", "1", "0"},
+		{"yesno", "yes", "no"},
+	}
+	for _, tt := range tests {
+		client := newTestClient(t, http.NotFound, "READECK_BOOL_FORMAT", tt.format)
+		for _, v := range []bool{true, false} {
+			want := tt.no
+			if v {
+				want = tt.yes
+			}
+			if got := client.buildSearchQuery(SearchOptions{Favorites: &v}).Get("favorite"); got != want {
+				t.Errorf("%q: favorite=%v encodes as %q, want %q", tt.format, v, got, want)
+			}
+		}
+	}
+}
+
+func TestSnippetStripsHTML(t *testing.T) {
+	tests := []struct {
+		obj  map[string]any
+		want string
+	}{
+		{map[string]any{"content_text": "<p>First <b>bold</b> line</p>\n<p>Tom &amp; Jerry</p><!-- note -->"}, "First bold line Tom & Jerry"},
+		{map[string]any{"excerpt": "1 < 2 and 3 > 2"}, "1 < 2 and 3 > 2"},
+		{map[string]any{"snippet": "Plain &amp; simple"}, "Plain &amp; simple"},
+	}
+	for _, tt := range tests {
+		if got := snippetFromMap(tt.obj); got != tt.want {
+			t.Errorf("snippetFromMap(%v) = %q, want %q", tt.obj, got, tt.want)
+		}
+	}
+
+	long := "<div>" + strings.Repeat("word ", 100) + "</div>"
+	if got := snippetFromMap(map[string]any{"content_text": long}); strings.Contains(got, "<") || len(got) != 283 {
+		t.Fatalf("long snippet = %q (%d bytes)", got, len(got))
+	}
+}
+
+func TestLargeNumericIDsRoundTrip(t *testing.T) {
+	const id = "9007199254740993"
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/bookmarks":
+			io.WriteString(w, `{"items":[{"id":`+id+`,"title":"Big"}]}`)
+		case "/api/bookmarks/" + id:
+			io.WriteString(w, `{"id":`+id+`,"title":"Big"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	result, err := client.Search(context.Background(), SearchOptions{})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(result.Items) != 1 || result.Items[0].ID != id {
+		t.Fatalf("search items = %+v", result.Items)
+	}
+	bm, err := client.GetBookmark(context.Background(), result.Items[0].ID, IncludeOptions{})
+	if err != nil {
+		t.Fatalf("GetBookmark: %v", err)
+	}
+	if bm.ID != id || bm.Title != "Big" {
+		t.Fatalf("bookmark = %+v", bm)
+	}
+}
+
+func TestConfiguredDefaultLimitsApply(t *testing.T) {
+	limits := map[string]string{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		limits[r.URL.Path] = r.URL.Query().Get("limit")
+		writeJSON(t, w, map[string]any{"items": []any{}})
+	}, "READECK_DEFAULT_SEARCH_LIMIT", "7", "READECK_DEFAULT_LIST_LIMIT", "33")
+
+	if _, err := client.Search(context.Background(), SearchOptions{}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if limits["/api/bookmarks"] != "7" {
+		t.Fatalf("default search limit = %v", limits)
+	}
+	if _, err := client.ListLabels(context.Background(), 0, ""); err != nil {
+		t.Fatalf("ListLabels: %v", err)
+	}
+	if _, err := client.Search(context.Background(), SearchOptions{Limit: 3}); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if limits["/api/labels"] != "33" {
+		t.Fatalf("label limits = %v", limits)
+	}
+	if limits["/api/bookmarks"] != "3" {
+		t.Fatalf("explicit search limit = %v", limits)
+	}
+}
@@ -0,0 +1,76 @@
+package readeck
+
+import (
+	"sort"
+	"strings"
+)
+
+type FieldChange struct {
+	Field    string `json:"field"`
+	Previous any    `json:"previous"`
+	Current  any    `json:"current"`
+}
+
+// DiffBookmarks lists the fields that differ between two snapshots of a
+// bookmark. Content is compared only when previous carries it.
+func DiffBookmarks(previous, current Bookmark) []FieldChange {
+	changes := make([]FieldChange, 0)
+	addString := func(field, prev, cur string) {
+		if prev != cur {
+			changes = append(changes, FieldChange{Field: field, Previous: prev, Current: cur})
+		}
+	}
+	addBool := func(field string, prev, cur bool) {
+		if prev != cur {
+			changes = append(changes, FieldChange{Field: field, Previous: prev, Current: cur})
+		}
+	}
+	addInt := func(field string, prev, cur int) {
+		if prev != cur {
+			changes = append(changes, FieldChange{Field: field, Previous: prev, Current: cur})
+		}
+	}
+	addList := func(field string, prev, cur []string) {
+		if strings.Join(prev, "\x00") != strings.Join(cur, "\x00") {
+			changes = append(changes, FieldChange{Field: field, Previous: prev, Current: cur})
+		}
+	}
+
+	addString("url", previous.URL, current.URL)
+	addString("title", previous.Title, current.Title)
+	addString("site_name", previous.SiteName, current.SiteName)
+	addString("author", previous.Author, current.Author)
+	addString("author_raw", previous.AuthorRaw, current.AuthorRaw)
+	addString("published_at", previous.PublishedAt, current.PublishedAt)
+	addString("created_at", previous.CreatedAt, current.CreatedAt)
+	addString("updated_at", previous.UpdatedAt, current.UpdatedAt)
+	addBool("is_archived", previous.IsArchived, current.IsArchived)
+	addBool("is_favorite", previous.IsFavorite, current.IsFavorite)
+	addString("collection", previous.Collection, current.Collection)
+	addInt("word_count", previous.WordCount, current.WordCount)
+	addInt("reading_minutes", previous.ReadingMinutes, current.ReadingMinutes)
+	addInt("read_progress", previous.ReadProgress, current.ReadProgress)
+	addString("icon_url", previous.IconURL, current.IconURL)
+	addList("labels", sortedLabelNames(previous.Labels), sortedLabelNames(current.Labels))
+	// highlights is omitted from JSON when empty, so a missing list means none.
+	addList("highlights", sortedHighlightIDs(previous.Highlights), sortedHighlightIDs(current.Highlights))
+	if previous.ContentText != "" {
+		addString("content_text", previous.ContentText, current.ContentText)
+	}
+	return changes
+}
+
+func sortedLabelNames(labels []Label) []string {
+	names := labelNames(labels)
+	sort.Strings(names)
+	return names
+}
+
+func sortedHighlightIDs(highlights []Highlight) []string {
+	ids := make([]string, 0, len(highlights))
+	for _, h := range highlights {
+		ids = append(ids, h.ID)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,66 @@
+package readeck
+
+import (
+	"slices"
+	"testing"
+)
+
+func changedFields(changes []FieldChange) []string {
+	fields := make([]string, 0, len(changes))
+	for _, c := range changes {
+		fields = append(fields, c.Field)
+	}
+	return fields
+}
+
+func TestDiffBookmarks(t *testing.T) {
+	previous := Bookmark{
+		ID: "b1", Title: "Old", URL: "https://example.com/a", UpdatedAt: "t1",
+		Labels: []Label{{Name: "go"}, {Name: "web"}},
+	}
+
+	current := previous
+	current.Labels = []Label{{Name: "web"}, {Name: "go"}}
+	if changes := DiffBookmarks(previous, current); len(changes) != 0 {
+		t.Fatalf("label order alone reported as changes: %v", changes)
+	}
+
+	current.Title, current.UpdatedAt, current.IsArchived = "New", "t2", true
+	current.Labels = []Label{{Name: "go"}}
+	changes := DiffBookmarks(previous, current)
+	if got, want := changedFields(changes), []string{"title", "updated_at", "is_archived", "labels"}; !slices.Equal(got, want) {
+		t.Fatalf("changed fields = %v, want %v", got, want)
+	}
+	if changes[0].Previous != "Old" || changes[0].Current != "New" {
+		t.Fatalf("title change = %+v", changes[0])
+	}
+}
+
+func TestDiffBookmarksComparesContentOnlyWhenProvided(t *testing.T) {
+	previous := Bookmark{ID: "b1"}
+	current := Bookmark{ID: "b1", ContentText: "body", Highlights: []Highlight{{ID: "h1"}}}
+	if got := changedFields(DiffBookmarks(previous, current)); !slices.Equal(got, []string{"highlights"}) {
+		t.Fatalf("changed fields = %v, want a missing highlights list treated as empty", got)
+	}
+
+	previous = Bookmark{ID: "b1", ContentText: "old body", Highlights: []Highlight{}}
+	if got := changedFields(DiffBookmarks(previous, current)); !slices.Equal(got, []string{"highlights", "content_text"}) {
+		t.Fatalf("changed fields = %v", got)
+	}
+}
+
+func TestDiffBookmarksCoversLaterFields(t *testing.T) {
+	previous := Bookmark{ID: "b1", Collection: "inbox", WordCount: 100, ReadingMinutes: 1, ReadProgress: 0, AuthorRaw: "By Ann", IconURL: "https://example.com/a.ico"}
+	current := Bookmark{ID: "b1", Collection: "reading", WordCount: 1200, ReadingMinutes: 6, ReadProgress: 40, AuthorRaw: "By Ann Lee", IconURL: "https://example.com/b.ico"}
+	changes := DiffBookmarks(previous, current)
+	want := []string{"author_raw", "collection", "word_count", "reading_minutes", "read_progress", "icon_url"}
+	if got := changedFields(changes); !slices.Equal(got, want) {
+		t.Fatalf("changed fields = %v, want %v", got, want)
+	}
+	if changes[2].Previous != 100 || changes[2].Current != 1200 {
+		t.Fatalf("word_count change = %+v", changes[2])
+	}
+	if changes := DiffBookmarks(current, current); len(changes) != 0 {
+		t.Fatalf("identical bookmarks differ: %v", changes)
+	}
+}
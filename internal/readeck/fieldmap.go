@@ -0,0 +1,15 @@
+package readeck
+
+// fieldMap holds operator-configured JSON keys, keyed by "entity.field" (for
+// example "bookmark.content_text"), that are tried before the built-in ones.
+type fieldMap map[string][]string
+
+func (fm fieldMap) keys(field string, defaults ...string) []string {
+	extra := fm[field]
+	if len(extra) == 0 {
+		return defaults
+	}
+	out := make([]string, 0, len(extra)+len(defaults))
+	out = append(out, extra...)
+	return append(out, defaults...)
+}
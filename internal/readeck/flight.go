@@ -0,0 +1,79 @@
+package readeck
+
+import (
+	"context"
+	"sync"
+)
+
+type flightResult struct {
+	body        []byte
+	statusCode  int
+	requestID   string
+	contentType string
+	err         error
+}
+
+type flightCall struct {
+	done    chan struct{}
+	result  flightResult
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// flightGroup coalesces concurrent identical calls so only one reaches the
+// upstream; every waiter receives the same result. The call runs on its own
+// goroutine and context, so each waiter can give up on its own context
+// without cutting the call short for the others. Once every waiter has
+// given up, the call is cancelled and forgotten.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// do returns fn's result, or ctx's error if ctx ends first. fn runs on a
+// context detached from ctx that is cancelled when no waiter is left.
+// shared reports that the call was already in flight for another caller.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) flightResult) (result flightResult, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*flightCall{}
+	}
+	call, shared := g.calls[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+		go func() {
+			defer cancel()
+			call.result = fn(callCtx)
+			g.forget(key, call)
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.result, shared
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return flightResult{err: ctx.Err()}, shared
+	}
+}
+
+func (g *flightGroup) forget(key string, call *flightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+}
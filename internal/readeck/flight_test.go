@@ -0,0 +1,126 @@
+package readeck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingUpstream holds every request until release is closed and counts
+// how many reached it.
+func blockingUpstream(t *testing.T, hits *atomic.Int32, arrived chan<- struct{}, release <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		arrived <- struct{}{}
+		<-release
+		writeJSON(t, w, map[string]any{"id": "b1", "title": "Shared"})
+	}
+}
+
+type getOutcome struct {
+	obj map[string]any
+	err error
+}
+
+func TestConcurrentIdenticalGetsShareOneUpstreamCall(t *testing.T) {
+	var hits atomic.Int32
+	arrived := make(chan struct{}, 2)
+	release := make(chan struct{})
+	client := newTestClient(t, blockingUpstream(t, &hits, arrived, release))
+
+	results := make(chan getOutcome, 2)
+	get := func() {
+		obj, err := client.getObject(context.Background(), "/bookmarks/b1", nil)
+		results <- getOutcome{obj, err}
+	}
+	go get()
+	<-arrived
+	go get()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for range 2 {
+		out := <-results
+		if out.err != nil || out.obj["title"] != "Shared" {
+			t.Fatalf("result = %v, %v", out.obj, out.err)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("upstream hits = %d, want 1", n)
+	}
+}
+
+func TestCancelledLeaderDoesNotFailWaiters(t *testing.T) {
+	var hits atomic.Int32
+	arrived := make(chan struct{}, 2)
+	release := make(chan struct{})
+	client := newTestClient(t, blockingUpstream(t, &hits, arrived, release))
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leader := make(chan getOutcome, 1)
+	go func() {
+		obj, err := client.getObject(leaderCtx, "/bookmarks/b1", nil)
+		leader <- getOutcome{obj, err}
+	}()
+	<-arrived
+
+	waiter := make(chan getOutcome, 1)
+	go func() {
+		obj, err := client.getObject(context.Background(), "/bookmarks/b1", nil)
+		waiter <- getOutcome{obj, err}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancelLeader()
+	if out := <-leader; !errors.Is(out.err, context.Canceled) {
+		t.Fatalf("leader err = %v, want context.Canceled", out.err)
+	}
+	close(release)
+	if out := <-waiter; out.err != nil || out.obj["title"] != "Shared" {
+		t.Fatalf("waiter = %v, %v", out.obj, out.err)
+	}
+	if n := hits.Load(); n != 1 {
+		t.Fatalf("upstream hits = %d, want 1", n)
+	}
+}
+
+func TestCallIsCancelledOnceEveryWaiterLeaves(t *testing.T) {
+	arrived := make(chan struct{}, 1)
+	cancelled := make(chan struct{})
+	var hits atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) > 1 {
+			writeJSON(t, w, map[string]any{"id": "b1", "title": "Fresh"})
+			return
+		}
+		arrived <- struct{}{}
+		<-r.Context().Done()
+		close(cancelled)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := client.getObject(ctx, "/bookmarks/b1", nil)
+		errCh <- err
+	}()
+	<-arrived
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request was not cancelled after its only caller left")
+	}
+
+	// The abandoned call is forgotten, so the next caller starts afresh.
+	obj, err := client.getObject(context.Background(), "/bookmarks/b1", nil)
+	if err != nil || obj["title"] != "Fresh" {
+		t.Fatalf("next call = %v, %v", obj, err)
+	}
+}
@@ -2,51 +2,93 @@ package readeck
 
 import (
 	"encoding/json"
-	"fmt"
+	"math"
+	"strconv"
 	"strings"
 )
 
-func mapBookmark(obj map[string]any) Bookmark {
-	labels := extractLabels(obj, "labels", "tags")
-	highlights := extractHighlights(obj, "highlights")
+func mapBookmark(obj map[string]any, fm fieldMap) Bookmark {
+	labels := extractLabels(obj, fm, fm.keys("bookmark.labels", "labels", "tags")...)
+	highlights := extractHighlights(obj, fm, "highlights")
 
 	bm := Bookmark{
-		ID:          firstNonEmptyString(obj, "id", "uid"),
-		URL:         firstNonEmptyString(obj, "url", "link"),
-		Title:       firstNonEmptyString(obj, "title"),
-		SiteName:    firstNonEmptyString(obj, "site_name", "site", "domain"),
-		Author:      firstNonEmptyString(obj, "author", "byline"),
-		PublishedAt: normalizeTimeField(obj, "published_at", "published"),
-		CreatedAt:   normalizeTimeField(obj, "created_at", "created"),
-		UpdatedAt:   normalizeTimeField(obj, "updated_at", "updated"),
-		IsArchived:  firstBool(obj, "is_archived", "archived"),
-		IsFavorite:  firstBool(obj, "is_favorite", "favorite"),
-		Labels:      labels,
-		ContentText: firstNonEmptyString(obj, "content_text", "text", "content"),
-		ContentHTML: firstNonEmptyString(obj, "content_html", "html"),
-		Highlights:  highlights,
+		ID:             firstNonEmptyString(obj, fm.keys("bookmark.id", "id", "uid")...),
+		URL:            firstNonEmptyString(obj, fm.keys("bookmark.url", "url", "link")...),
+		Title:          firstNonEmptyString(obj, fm.keys("bookmark.title", "title")...),
+		SiteName:       firstNonEmptyString(obj, fm.keys("bookmark.site_name", "site_name", "site", "domain")...),
+		Author:         firstNonEmptyString(obj, fm.keys("bookmark.author", "author", "byline")...),
+		PublishedAt:    normalizeTimeField(obj, fm.keys("bookmark.published_at", "published_at", "published")...),
+		CreatedAt:      normalizeTimeField(obj, fm.keys("bookmark.created_at", "created_at", "created")...),
+		UpdatedAt:      normalizeTimeField(obj, fm.keys("bookmark.updated_at", "updated_at", "updated")...),
+		IsArchived:     firstBool(obj, fm.keys("bookmark.is_archived", "is_archived", "archived")...),
+		IsFavorite:     firstBool(obj, fm.keys("bookmark.is_favorite", "is_favorite", "favorite")...),
+		WordCount:      firstInt(obj, fm.keys("bookmark.word_count", "word_count", "words")...),
+		ReadingMinutes: firstInt(obj, fm.keys("bookmark.reading_minutes", "reading_time", "reading_minutes")...),
+		ReadProgress:   firstInt(obj, fm.keys("bookmark.read_progress", "read_progress", "progress")...),
+		Labels:         labels,
+		Collection:     firstNonEmptyString(obj, fm.keys("bookmark.collection", "collection", "collection_id", "folder")...),
+		ContentText:    firstNonEmptyString(obj, fm.keys("bookmark.content_text", "content_text", "text", "content")...),
+		ContentHTML:    firstNonEmptyString(obj, fm.keys("bookmark.content_html", "content_html", "html")...),
+		Highlights:     highlights,
+		IconURL:        firstNonEmptyString(obj, fm.keys("bookmark.icon_url", "icon_url", "icon", "favicon")...),
+	}
+	if bm.IconURL == "" {
+		bm.IconURL = nestedString(obj, "resources", "icon", "src")
 	}
 	if bm.Title == "" && bm.URL != "" {
 		bm.Title = bm.URL
 	}
+	if bm.Collection == "" {
+		bm.Collection = firstNonEmpty(nestedString(obj, "collection", "id"), nestedString(obj, "collection", "name"))
+	}
+	bm.AuthorRaw = bm.Author
+	bm.Author = cleanAuthor(bm.Author)
 	return bm
 }
 
-func mapLabel(obj map[string]any) Label {
-	name := firstNonEmptyString(obj, "name", "label")
+var authorSiteSeparators = []string{" — ", " – ", " | ", " - "}
+
+// cleanAuthor strips a leading "By " and a trailing "— Site" fragment from a
+// byline, falling back to the raw value if nothing would be left.
+func cleanAuthor(raw string) string {
+	author := strings.TrimSpace(raw)
+	if len(author) > 3 && strings.EqualFold(author[:3], "by ") {
+		author = strings.TrimSpace(author[3:])
+	}
+	for _, sep := range authorSiteSeparators {
+		if i := strings.Index(author, sep); i > 0 {
+			author = strings.TrimSpace(author[:i])
+		}
+	}
+	author = strings.TrimRight(author, ",;")
+	if author == "" {
+		return strings.TrimSpace(raw)
+	}
+	return author
+}
+
+func mapLabel(obj map[string]any, fm fieldMap) Label {
+	name := firstNonEmptyString(obj, fm.keys("label.name", "name", "label")...)
 	if name == "" {
 		if s, ok := obj["title"].(string); ok {
 			name = s
 		}
 	}
 	return Label{
-		ID:    firstNonEmptyString(obj, "id", "uid"),
+		ID:    firstNonEmptyString(obj, fm.keys("label.id", "id", "uid")...),
 		Name:  name,
-		Color: firstNonEmptyString(obj, "color", "hex"),
+		Color: firstNonEmptyString(obj, fm.keys("label.color", "color", "hex")...),
+	}
+}
+
+func mapCollection(obj map[string]any, fm fieldMap) Collection {
+	return Collection{
+		ID:   firstNonEmptyString(obj, fm.keys("collection.id", "id", "uid")...),
+		Name: firstNonEmptyString(obj, fm.keys("collection.name", "name", "title")...),
 	}
 }
 
-func mapHighlight(obj map[string]any) Highlight {
+func mapHighlight(obj map[string]any, fm fieldMap) Highlight {
 	loc := json.RawMessage(nil)
 	if raw, ok := obj["location"].(map[string]any); ok {
 		if b, err := json.Marshal(raw); err == nil {
@@ -58,18 +100,32 @@ func mapHighlight(obj map[string]any) Highlight {
 			loc = b
 		}
 	}
+	if loc == nil {
+		selector := map[string]any{}
+		for _, key := range []string{"start_selector", "start_offset", "end_selector", "end_offset"} {
+			if v, ok := obj[key]; ok && v != nil {
+				selector[key] = v
+			}
+		}
+		if len(selector) > 0 {
+			if b, err := json.Marshal(selector); err == nil {
+				loc = b
+			}
+		}
+	}
 	return Highlight{
-		ID:         firstNonEmptyString(obj, "id", "uid"),
-		BookmarkID: firstNonEmptyString(obj, "bookmark_id", "article_id"),
-		Text:       firstNonEmptyString(obj, "text", "quote"),
-		Note:       firstNonEmptyString(obj, "note", "comment"),
-		Color:      firstNonEmptyString(obj, "color"),
-		CreatedAt:  normalizeTimeField(obj, "created_at", "created"),
+		ID:         firstNonEmptyString(obj, fm.keys("highlight.id", "id", "uid")...),
+		BookmarkID: firstNonEmptyString(obj, fm.keys("highlight.bookmark_id", "bookmark_id", "article_id")...),
+		Text:       firstNonEmptyString(obj, fm.keys("highlight.text", "text", "quote")...),
+		Note:       firstNonEmptyString(obj, fm.keys("highlight.note", "note", "comment")...),
+		Color:      firstNonEmptyString(obj, fm.keys("highlight.color", "color")...),
+		CreatedAt:  normalizeTimeField(obj, fm.keys("highlight.created_at", "created_at", "created")...),
+		UpdatedAt:  normalizeTimeField(obj, fm.keys("highlight.updated_at", "updated_at", "updated", "modified")...),
 		Location:   loc,
 	}
 }
 
-func extractLabels(obj map[string]any, keys ...string) []Label {
+func extractLabels(obj map[string]any, fm fieldMap, keys ...string) []Label {
 	for _, key := range keys {
 		arr := extractArray(obj, key)
 		if len(arr) == 0 {
@@ -79,7 +135,7 @@ func extractLabels(obj map[string]any, keys ...string) []Label {
 		for _, item := range arr {
 			switch v := item.(type) {
 			case map[string]any:
-				label := mapLabel(v)
+				label := mapLabel(v, fm)
 				if strings.TrimSpace(label.Name) == "" {
 					continue
 				}
@@ -99,14 +155,14 @@ func extractLabels(obj map[string]any, keys ...string) []Label {
 	return nil
 }
 
-func extractHighlights(obj map[string]any, key string) []Highlight {
+func extractHighlights(obj map[string]any, fm fieldMap, key string) []Highlight {
 	arr := extractArrayMap(obj, key)
 	if len(arr) == 0 {
 		return nil
 	}
 	out := make([]Highlight, 0, len(arr))
 	for _, item := range arr {
-		h := mapHighlight(item)
+		h := mapHighlight(item, fm)
 		if h.ID == "" {
 			continue
 		}
@@ -162,15 +218,45 @@ func firstNonEmptyString(obj map[string]any, keys ...string) string {
 			if strings.TrimSpace(v) != "" {
 				return v
 			}
+		case json.Number:
+			return v.String()
 		case float64:
-			return fmt.Sprintf("%.0f", v)
+			return formatNumber(v)
 		case int:
-			return fmt.Sprintf("%d", v)
+			return strconv.Itoa(v)
 		}
 	}
 	return ""
 }
 
+// maxExactFloat is the largest integer a float64 holds without rounding.
+const maxExactFloat = 1 << 53
+
+// formatNumber renders integral values without an exponent or decimal
+// point and everything else in the shortest exact form, so IDs are not
+// rounded by %.0f and fractional values are not cut off.
+func formatNumber(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) <= maxExactFloat {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func nestedString(obj map[string]any, path ...string) string {
+	current := obj
+	for i, key := range path {
+		if i == len(path)-1 {
+			return firstNonEmptyString(current, key)
+		}
+		next, ok := current[key].(map[string]any)
+		if !ok {
+			return ""
+		}
+		current = next
+	}
+	return ""
+}
+
 func normalizeTimeField(obj map[string]any, keys ...string) string {
 	for _, key := range keys {
 		if raw, ok := obj[key]; ok && raw != nil {
@@ -182,6 +268,33 @@ func normalizeTimeField(obj map[string]any, keys ...string) string {
 	return ""
 }
 
+func firstInt(obj map[string]any, keys ...string) int {
+	for _, key := range keys {
+		raw, ok := obj[key]
+		if !ok || raw == nil {
+			continue
+		}
+		switch v := raw.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				return int(n)
+			}
+			if f, err := v.Float64(); err == nil {
+				return int(f)
+			}
+		case float64:
+			return int(v)
+		case int:
+			return v
+		case string:
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
 func firstBool(obj map[string]any, keys ...string) bool {
 	for _, key := range keys {
 		raw, ok := obj[key]
@@ -199,6 +312,10 @@ func firstBool(obj map[string]any, keys ...string) bool {
 			if lv == "false" || lv == "0" || lv == "no" {
 				return false
 			}
+		case json.Number:
+			if f, err := v.Float64(); err == nil {
+				return f != 0
+			}
 		case float64:
 			return v != 0
 		}
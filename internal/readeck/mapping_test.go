@@ -0,0 +1,123 @@
+package readeck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFieldMapKeysAreTriedFirst(t *testing.T) {
+	fm := fieldMap{
+		"bookmark.content_text": {"summary_text"},
+		"highlight.note":        {"comment"},
+		"label.name":            {"slug"},
+	}
+
+	bm := mapBookmark(map[string]any{"id": "b1", "summary_text": "Summary", "content_text": "Body"}, fm)
+	if bm.ContentText != "Summary" {
+		t.Fatalf("ContentText = %q, want the mapped key", bm.ContentText)
+	}
+	if bm := mapBookmark(map[string]any{"id": "b1", "content_text": "Body"}, fm); bm.ContentText != "Body" {
+		t.Fatalf("ContentText = %q, want the built-in key as fallback", bm.ContentText)
+	}
+	if h := mapHighlight(map[string]any{"id": "h1", "text": "quote", "comment": "mine"}, fm); h.Note != "mine" {
+		t.Fatalf("Note = %q", h.Note)
+	}
+	if l := mapLabel(map[string]any{"slug": "go", "name": "Go"}, fm); l.Name != "go" {
+		t.Fatalf("Name = %q", l.Name)
+	}
+}
+
+func TestClientUsesConfiguredFieldMap(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/bookmarks/b1/content":
+			writeJSON(t, w, map[string]any{"summary_text": "From a custom key"})
+		default:
+			http.NotFound(w, r)
+		}
+	}, "READECK_FIELD_MAP", "bookmark.content_text=summary_text")
+
+	text, _, err := client.GetContent(context.Background(), "b1")
+	if err != nil || text != "From a custom key" {
+		t.Fatalf("GetContent = %q, %v", text, err)
+	}
+}
+
+func TestHighlightUpdatedAtAliases(t *testing.T) {
+	for _, key := range []string{"updated_at", "updated", "modified"} {
+		h := mapHighlight(map[string]any{"id": "h1", key: "2024-03-05T10:00:00Z"}, nil)
+		if h.UpdatedAt != "2024-03-05T10:00:00Z" {
+			t.Errorf("%s: UpdatedAt = %q", key, h.UpdatedAt)
+		}
+	}
+
+	b, err := json.Marshal(mapHighlight(map[string]any{"id": "h1"}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "updated_at") {
+		t.Fatalf("empty updated_at not omitted: %s", b)
+	}
+}
+
+func TestCleanAuthorHandlesMessyBylines(t *testing.T) {
+	tests := []struct{ raw, want string }{
+		{"By Jane Doe", "Jane Doe"},
+		{"  by jane doe  ", "jane doe"},
+		{"BY Jane Doe — The Site", "Jane Doe"},
+		{"Jane Doe | Example News", "Jane Doe"},
+		{"Jane Doe - Example", "Jane Doe"},
+		{"Jane Doe, ", "Jane Doe"},
+		{"Jean-Luc Picard", "Jean-Luc Picard"},
+		{"Byron Katie", "Byron Katie"},
+		{"By", "By"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanAuthor(tt.raw); got != tt.want {
+			t.Errorf("cleanAuthor(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+
+	bm := mapBookmark(map[string]any{"id": "b1", "byline": "By Jane Doe – Blog"}, nil)
+	if bm.Author != "Jane Doe" || bm.AuthorRaw != "By Jane Doe – Blog" {
+		t.Fatalf("Author = %q AuthorRaw = %q", bm.Author, bm.AuthorRaw)
+	}
+}
+
+func TestCollectionMapping(t *testing.T) {
+	tests := []map[string]any{
+		{"id": "b1", "collection": "reading"},
+		{"id": "b1", "collection_id": "reading"},
+		{"id": "b1", "folder": "reading"},
+		{"id": "b1", "collection": map[string]any{"id": "reading", "name": "Reading"}},
+	}
+	for _, obj := range tests {
+		if bm := mapBookmark(obj, nil); bm.Collection != "reading" {
+			t.Errorf("%v: Collection = %q", obj, bm.Collection)
+		}
+	}
+}
+
+func TestFirstNonEmptyStringFormatsNumbers(t *testing.T) {
+	tests := []struct {
+		raw  any
+		want string
+	}{
+		{float64(42), "42"},
+		{float64(1 << 53), "9007199254740992"},
+		{float64(-17), "-17"},
+		{1.5, "1.5"},
+		{1e21, "1000000000000000000000"},
+		{json.Number("9007199254740993"), "9007199254740993"},
+		{7, "7"},
+	}
+	for _, tt := range tests {
+		if got := firstNonEmptyString(map[string]any{"id": tt.raw}, "id"); got != tt.want {
+			t.Errorf("firstNonEmptyString(%v) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
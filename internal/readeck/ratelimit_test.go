@@ -0,0 +1,51 @@
+package readeck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitBoundsSustainedRate(t *testing.T) {
+	const rps, requests = 20, 6
+	hits := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		writeJSON(t, w, map[string]any{"id": "b1"})
+	}, "READECK_RATE_LIMIT_RPS", strconv.Itoa(rps))
+
+	start := time.Now()
+	for i := range requests {
+		if _, err := client.getObject(context.Background(), "/bookmarks/b"+strconv.Itoa(i), nil); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+	elapsed := time.Since(start).Seconds()
+	if hits != requests {
+		t.Fatalf("upstream saw %d requests, want %d", hits, requests)
+	}
+	if rate := float64(requests-1) / elapsed; rate > rps {
+		t.Fatalf("effective rate %.1f rps exceeds the configured %d", rate, rps)
+	}
+}
+
+func TestRateLimitWaitRespectsContext(t *testing.T) {
+	l := newRateLimiter(1)
+	if err := l.wait(context.Background()); err != nil {
+		t.Fatalf("first wait: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second wait = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRateLimitDisabledByDefault(t *testing.T) {
+	if l := newRateLimiter(0); l != nil {
+		t.Fatalf("newRateLimiter(0) = %+v, want nil", l)
+	}
+}
@@ -0,0 +1,39 @@
+package readeck
+
+// defaultSortTokens translates sort modes to Readeck's sort query tokens.
+// Modes missing from the table are forwarded under their own name, as they
+// always were; an empty token keeps a mode local. label_match only exists
+// here, so it is never sent. sortSummaries decides the final order either
+// way.
+var defaultSortTokens = map[SortMode]string{
+	SortCreatedDesc:   "-created",
+	SortPublishedDesc: "-published",
+	SortLabelMatch:    "",
+}
+
+type sortTokens map[SortMode]string
+
+// token returns the upstream sort value for mode, or "" when it is not sent.
+func (t sortTokens) token(mode SortMode) string {
+	if token, ok := t[mode]; ok {
+		return token
+	}
+	return string(mode)
+}
+
+// SortsUpstream reports whether mode is forwarded to Readeck, so callers can
+// rely on the page order across pages.
+func (c *Client) SortsUpstream(mode SortMode) bool {
+	return c.sorts.token(mode) != ""
+}
+
+func newSortTokens(overrides map[string]string) sortTokens {
+	out := sortTokens{}
+	for mode, token := range defaultSortTokens {
+		out[mode] = token
+	}
+	for mode, token := range overrides {
+		out[SortMode(mode)] = token
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+package readeck
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSortTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    string
+		tokens map[SortMode]string
+	}{
+		{"default", "", map[SortMode]string{
+			SortCreatedDesc:   "-created",
+			SortPublishedDesc: "-published",
+			SortUpdatedDesc:   "updated_desc",
+			SortRelevance:     "relevance",
+			SortLabelMatch:    "",
+		}},
+		{"overridden", "updated_desc=-updated,created_desc=created_at:desc,published_desc=,relevance=", map[SortMode]string{
+			SortCreatedDesc:   "created_at:desc",
+			SortPublishedDesc: "",
+			SortUpdatedDesc:   "-updated",
+			SortRelevance:     "",
+			SortLabelMatch:    "",
+		}},
+	}
+	for _, tt := range tests {
+		client := newTestClient(t, http.NotFound, "READECK_SORT_MAP", tt.env)
+		for mode, want := range tt.tokens {
+			got := client.buildSearchQuery(SearchOptions{Sort: mode}).Get("sort")
+			if got != want {
+				t.Errorf("%s: %s sends sort=%q, want %q", tt.name, mode, got, want)
+			}
+			if client.SortsUpstream(mode) != (want != "") {
+				t.Errorf("%s: SortsUpstream(%s) = %v", tt.name, mode, client.SortsUpstream(mode))
+			}
+		}
+	}
+}
@@ -17,6 +17,14 @@ const (
 	SortUpdatedDesc   SortMode = "updated_desc"
 	SortCreatedDesc   SortMode = "created_desc"
 	SortPublishedDesc SortMode = "published_desc"
+	SortLabelMatch    SortMode = "label_match"
+)
+
+type LabelMatchMode string
+
+const (
+	LabelMatchAll LabelMatchMode = "all"
+	LabelMatchAny LabelMatchMode = "any"
 )
 
 type CitationStyle string
@@ -28,14 +36,27 @@ const (
 	StyleBibTeX   CitationStyle = "bibtex"
 	StyleCSLJSON  CitationStyle = "csl-json"
 	StyleMarkdown CitationStyle = "markdown"
+	StyleIEEE     CitationStyle = "ieee"
 )
 
+var CitationStyles = []CitationStyle{StyleAPA, StyleMLA, StyleChicago, StyleBibTeX, StyleCSLJSON, StyleMarkdown, StyleIEEE}
+
 type Label struct {
 	ID    string `json:"id,omitempty"`
 	Name  string `json:"name"`
 	Color string `json:"color,omitempty"`
 }
 
+type Collection struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name"`
+}
+
+type Image struct {
+	Src string `json:"src"`
+	Alt string `json:"alt,omitempty"`
+}
+
 type Highlight struct {
 	ID         string          `json:"id"`
 	BookmarkID string          `json:"bookmark_id"`
@@ -43,24 +64,36 @@ type Highlight struct {
 	Note       string          `json:"note,omitempty"`
 	Color      string          `json:"color,omitempty"`
 	CreatedAt  string          `json:"created_at,omitempty"`
+	UpdatedAt  string          `json:"updated_at,omitempty"`
 	Location   json.RawMessage `json:"location,omitempty"`
 }
 
 type Bookmark struct {
-	ID          string      `json:"id"`
-	URL         string      `json:"url"`
-	Title       string      `json:"title"`
-	SiteName    string      `json:"site_name,omitempty"`
-	Author      string      `json:"author,omitempty"`
-	PublishedAt string      `json:"published_at,omitempty"`
-	CreatedAt   string      `json:"created_at,omitempty"`
-	UpdatedAt   string      `json:"updated_at,omitempty"`
-	IsArchived  bool        `json:"is_archived"`
-	IsFavorite  bool        `json:"is_favorite,omitempty"`
-	Labels      []Label     `json:"labels,omitempty"`
-	ContentText string      `json:"content_text,omitempty"`
-	ContentHTML string      `json:"content_html,omitempty"`
-	Highlights  []Highlight `json:"highlights,omitempty"`
+	ID                       string      `json:"id"`
+	URL                      string      `json:"url"`
+	Title                    string      `json:"title"`
+	SiteName                 string      `json:"site_name,omitempty"`
+	Author                   string      `json:"author,omitempty"`
+	AuthorRaw                string      `json:"author_raw,omitempty"`
+	PublishedAt              string      `json:"published_at,omitempty"`
+	CreatedAt                string      `json:"created_at,omitempty"`
+	UpdatedAt                string      `json:"updated_at,omitempty"`
+	IsArchived               bool        `json:"is_archived"`
+	IsFavorite               bool        `json:"is_favorite,omitempty"`
+	WordCount                int         `json:"word_count,omitempty"`
+	ReadingMinutes           int         `json:"reading_minutes,omitempty"`
+	ReadProgress             int         `json:"read_progress,omitempty"`
+	Labels                   []Label     `json:"labels,omitempty"`
+	Collection               string      `json:"collection,omitempty"`
+	ContentText              string      `json:"content_text,omitempty"`
+	ContentHTML              string      `json:"content_html,omitempty"`
+	ContentUnavailableReason string      `json:"content_unavailable_reason,omitempty"`
+	Images                   []Image     `json:"images,omitempty"`
+	Highlights               []Highlight `json:"highlights,omitempty"`
+	Snippet                  string      `json:"snippet,omitempty"`
+	IconURL                  string      `json:"icon_url,omitempty"`
+	IconData                 string      `json:"icon_data,omitempty"`
+	Warnings                 []string    `json:"warnings,omitempty"`
 }
 
 type BookmarkSummary struct {
@@ -68,7 +101,9 @@ type BookmarkSummary struct {
 	Title       string   `json:"title"`
 	URL         string   `json:"url"`
 	IsArchived  bool     `json:"is_archived"`
+	IsFavorite  bool     `json:"is_favorite,omitempty"`
 	Labels      []string `json:"labels,omitempty"`
+	Collection  string   `json:"collection,omitempty"`
 	CreatedAt   string   `json:"created_at,omitempty"`
 	UpdatedAt   string   `json:"updated_at,omitempty"`
 	PublishedAt string   `json:"published_at,omitempty"`
@@ -76,21 +111,25 @@ type BookmarkSummary struct {
 }
 
 type SearchOptions struct {
-	Query     string       `json:"query,omitempty"`
-	Title     string       `json:"title,omitempty"`
-	Text      string       `json:"text,omitempty"`
-	Labels    []string     `json:"labels,omitempty"`
-	Archived  ArchivedMode `json:"archived,omitempty"`
-	Favorites *bool        `json:"favorites,omitempty"`
-	Sort      SortMode     `json:"sort,omitempty"`
-	Limit     int          `json:"limit,omitempty"`
-	Cursor    string       `json:"cursor,omitempty"`
+	Query      string         `json:"query,omitempty"`
+	Title      string         `json:"title,omitempty"`
+	Text       string         `json:"text,omitempty"`
+	Labels     []string       `json:"labels,omitempty"`
+	LabelMode  LabelMatchMode `json:"label_mode,omitempty"`
+	Collection string         `json:"collection,omitempty"`
+	Archived   ArchivedMode   `json:"archived,omitempty"`
+	Favorites  *bool          `json:"favorites,omitempty"`
+	Sort       SortMode       `json:"sort,omitempty"`
+	Limit      int            `json:"limit,omitempty"`
+	Cursor     string         `json:"cursor,omitempty"`
+	NoSnippets bool           `json:"no_snippets,omitempty"`
 }
 
 type IncludeOptions struct {
 	Content    bool `json:"content,omitempty"`
 	Highlights bool `json:"highlights,omitempty"`
 	Labels     bool `json:"labels,omitempty"`
+	Icon       bool `json:"icon,omitempty"`
 }
 
 type CitationMetadata struct {
@@ -113,16 +152,33 @@ type Citation struct {
 type SearchResult struct {
 	Items      []BookmarkSummary `json:"items"`
 	NextCursor string            `json:"next_cursor,omitempty"`
+	PrevCursor string            `json:"prev_cursor,omitempty"`
+	Partial    bool              `json:"partial,omitempty"`
+}
+
+type CountResult struct {
+	Count   int  `json:"count"`
+	Capped  bool `json:"capped,omitempty"`
+	Partial bool `json:"partial,omitempty"`
 }
 
 type LabelListResult struct {
 	Labels     []Label `json:"labels"`
 	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+type CollectionListResult struct {
+	Collections []Collection `json:"collections"`
+	NextCursor  string       `json:"next_cursor,omitempty"`
+	PrevCursor  string       `json:"prev_cursor,omitempty"`
 }
 
 type HighlightListResult struct {
 	Highlights []Highlight `json:"highlights"`
 	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+	Partial    bool        `json:"partial,omitempty"`
 }
 
 type ArchiveResult struct {
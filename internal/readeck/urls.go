@@ -0,0 +1,41 @@
+package readeck
+
+import (
+	"net/url"
+	"strings"
+)
+
+// NormalizeURL reduces a URL to a comparison key: scheme and host are
+// lowercased, default ports, fragments, a trailing slash and utm_* tracking
+// parameters are dropped, and the remaining query is sorted. Unparseable
+// input is returned trimmed.
+func NormalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path != "/" {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = ""
+	} else {
+		u.Path = ""
+	}
+
+	query := u.Query()
+	for key := range query {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,62 @@
+package readeck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const maxWriteDelay = 5 * time.Second
+
+// writeQueue serializes mutating requests so a burst of writes against a
+// throttling instance retries one at a time instead of all at once. The
+// delay between writes is shared: a 429 doubles it for every queued write,
+// each success halves it again.
+type writeQueue struct {
+	slot  chan struct{}
+	mu    sync.Mutex
+	delay time.Duration
+}
+
+func newWriteQueue() *writeQueue {
+	return &writeQueue{slot: make(chan struct{}, 1)}
+}
+
+func (q *writeQueue) acquire(ctx context.Context) error {
+	select {
+	case q.slot <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *writeQueue) release() {
+	<-q.slot
+}
+
+// pace waits out the current shared delay before the next write attempt.
+func (q *writeQueue) pace(ctx context.Context) error {
+	q.mu.Lock()
+	delay := q.delay
+	q.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	return waitForRetry(ctx, delay)
+}
+
+func (q *writeQueue) throttled() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.delay = min(max(q.delay*2, retryBaseDelay), maxWriteDelay)
+}
+
+func (q *writeQueue) succeeded() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.delay /= 2
+	if q.delay < retryBaseDelay {
+		q.delay = 0
+	}
+}
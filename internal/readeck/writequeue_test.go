@@ -0,0 +1,75 @@
+package readeck
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestThrottledWritesSerializeAndEventuallySucceed(t *testing.T) {
+	const writes, throttledResponses = 4, 2
+	var patches, inFlight, peak atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			return
+		}
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		if n > peak.Load() {
+			peak.Store(n)
+		}
+		if patches.Add(1) <= throttledResponses {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writes)
+	for i := range writes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.SetLabels(context.Background(), "b"+strconv.Itoa(i), []string{"go"})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("SetLabels: %v", err)
+		}
+	}
+	if n := patches.Load(); n != writes+throttledResponses {
+		t.Fatalf("PATCH requests = %d, want %d", n, writes+throttledResponses)
+	}
+	if p := peak.Load(); p != 1 {
+		t.Fatalf("peak concurrent writes = %d, want 1", p)
+	}
+}
+
+func TestWriteQueueDelayBackoffIsShared(t *testing.T) {
+	q := newWriteQueue()
+	q.throttled()
+	if q.delay != retryBaseDelay {
+		t.Fatalf("delay after one 429 = %v, want %v", q.delay, retryBaseDelay)
+	}
+	for range 10 {
+		q.throttled()
+	}
+	if q.delay != maxWriteDelay {
+		t.Fatalf("delay = %v, want it capped at %v", q.delay, maxWriteDelay)
+	}
+	for range 10 {
+		q.succeeded()
+	}
+	if q.delay != 0 {
+		t.Fatalf("delay after successes = %v, want 0", q.delay)
+	}
+}
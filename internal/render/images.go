@@ -0,0 +1,83 @@
+package render
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+var imgTagRe = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+var attrRe = regexp.MustCompile(`(?s)([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+
+// Images lists the images referenced by the bookmark's HTML content in
+// document order, with sources resolved against the bookmark URL. Lazy-loaded
+// images that only carry data-src are included; inline data: URIs are not.
+func Images(bookmark readeck.Bookmark) []readeck.Image {
+	base, _ := url.Parse(bookmark.URL)
+	seen := map[string]struct{}{}
+	var out []readeck.Image
+	for _, tag := range imgTagRe.FindAllString(bookmark.ContentHTML, -1) {
+		attrs := map[string]string{}
+		for _, m := range attrRe.FindAllStringSubmatch(tag, -1) {
+			name := strings.ToLower(m[1])
+			if _, ok := attrs[name]; !ok {
+				attrs[name] = html.UnescapeString(m[2] + m[3] + m[4])
+			}
+		}
+		src := resolveImageSrc(base, firstNonEmpty(attrs["src"], attrs["data-src"]))
+		if src == "" {
+			continue
+		}
+		if _, ok := seen[src]; ok {
+			continue
+		}
+		seen[src] = struct{}{}
+		out = append(out, readeck.Image{Src: src, Alt: strings.TrimSpace(attrs["alt"])})
+	}
+	return out
+}
+
+func resolveImageSrc(base *url.URL, src string) string {
+	src = strings.TrimSpace(src)
+	if src == "" || strings.HasPrefix(strings.ToLower(src), "data:") {
+		return ""
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+	if base != nil && base.IsAbs() {
+		u = base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+// ImagesMarkdown renders images as a list of Markdown image references.
+func ImagesMarkdown(images []readeck.Image) string {
+	var b strings.Builder
+	for _, img := range images {
+		alt := strings.NewReplacer("[", `\[`, "]", `\]`).Replace(img.Alt)
+		src := img.Src
+		if strings.ContainsAny(src, " ()") {
+			src = "<" + src + ">"
+		}
+		b.WriteString("![")
+		b.WriteString(alt)
+		b.WriteString("](")
+		b.WriteString(src)
+		b.WriteString(")\n")
+	}
+	return b.String()
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
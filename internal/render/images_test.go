@@ -0,0 +1,45 @@
+package render
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+func TestImagesResolveRelativeSources(t *testing.T) {
+	bm := readeck.Bookmark{
+		URL: "https://example.com/posts/article.html",
+		ContentHTML: `<p><img src="figure.png" alt=" Figure 1 "></p>
+<img data-src="/static/lazy.jpg">
+<img src='https://cdn.example.net/abs.gif' alt="Absolute">
+<img src="//cdn.example.net/proto.png">
+<img src="data:image/png;base64,AAAA" alt="inline">
+<img src="figure.png" alt="duplicate">`,
+	}
+	want := []readeck.Image{
+		{Src: "https://example.com/posts/figure.png", Alt: "Figure 1"},
+		{Src: "https://example.com/static/lazy.jpg"},
+		{Src: "https://cdn.example.net/abs.gif", Alt: "Absolute"},
+		{Src: "https://cdn.example.net/proto.png"},
+	}
+	if got := Images(bm); !slices.Equal(got, want) {
+		t.Fatalf("Images = %+v, want %+v", got, want)
+	}
+
+	bm.URL = ""
+	if got := Images(bm); got[0].Src != "figure.png" {
+		t.Fatalf("without a bookmark URL, src = %q, want it left relative", got[0].Src)
+	}
+}
+
+func TestImagesMarkdown(t *testing.T) {
+	got := ImagesMarkdown([]readeck.Image{
+		{Src: "https://example.com/a.png", Alt: "a [b]"},
+		{Src: "https://example.com/with space.png"},
+	})
+	want := "![a \\[b\\]](https://example.com/a.png)\n![](<https://example.com/with space.png>)\n"
+	if got != want {
+		t.Fatalf("ImagesMarkdown = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+var anchorTagRe = regexp.MustCompile(`(?is)<a\b([^>]*)>(.*?)</a\s*>`)
+
+type Link struct {
+	URL  string `json:"url"`
+	Text string `json:"text,omitempty"`
+}
+
+// Links lists the outbound http(s) links in the bookmark's HTML content in
+// document order. Hrefs are resolved against the bookmark URL and fragments
+// dropped, so in-page anchors and repeated links to the same page collapse;
+// mailto:, javascript: and other schemes are skipped.
+func Links(bookmark readeck.Bookmark) []Link {
+	base, _ := url.Parse(bookmark.URL)
+	self := ""
+	if base != nil && base.IsAbs() {
+		self = withoutFragment(*base)
+	}
+	index := map[string]int{}
+	var out []Link
+	for _, m := range anchorTagRe.FindAllStringSubmatch(bookmark.ContentHTML, -1) {
+		href := ""
+		for _, attr := range attrRe.FindAllStringSubmatch(m[1], -1) {
+			if strings.EqualFold(attr[1], "href") {
+				href = attr[2] + attr[3] + attr[4]
+				break
+			}
+		}
+		link := resolveLink(base, html.UnescapeString(href))
+		if link == "" || link == self {
+			continue
+		}
+		text := htmlToText(m[2])
+		if i, ok := index[link]; ok {
+			if out[i].Text == "" {
+				out[i].Text = text
+			}
+			continue
+		}
+		index[link] = len(out)
+		out = append(out, Link{URL: link, Text: text})
+	}
+	return out
+}
+
+func resolveLink(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base != nil && base.IsAbs() {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return ""
+	}
+	return withoutFragment(*u)
+}
+
+func withoutFragment(u url.URL) string {
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
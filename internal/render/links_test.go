@@ -0,0 +1,40 @@
+package render
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+func TestLinksResolveFilterAndDedupe(t *testing.T) {
+	bm := readeck.Bookmark{
+		URL: "https://example.com/posts/article.html",
+		ContentHTML: `<p>See <a href="related.html">the <em>related</em> post</a>,
+<a href="/about">about</a> and <a href='https://other.net/paper'>a paper</a>.</p>
+<a href="#section-2">jump</a>
+<a href="article.html#intro">this page</a>
+<a href="mailto:me@example.com">mail</a>
+<a href="javascript:void(0)">js</a>
+<a href="ftp://files.example.com/x">ftp</a>
+<a href="https://other.net/paper#results"></a>
+<a href="//cdn.example.net/lib?a=1&amp;b=2">cdn</a>
+<a name="anchor">no href</a>`,
+	}
+	want := []Link{
+		{URL: "https://example.com/posts/related.html", Text: "the related post"},
+		{URL: "https://example.com/about", Text: "about"},
+		{URL: "https://other.net/paper", Text: "a paper"},
+		{URL: "https://cdn.example.net/lib?a=1&b=2", Text: "cdn"},
+	}
+	if got := Links(bm); !slices.Equal(got, want) {
+		t.Fatalf("Links =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestLinksWithoutBookmarkURLKeepOnlyAbsolute(t *testing.T) {
+	bm := readeck.Bookmark{ContentHTML: `<a href="relative.html">rel</a><a href="https://example.com/abs">abs</a>`}
+	if got := Links(bm); !slices.Equal(got, []Link{{URL: "https://example.com/abs", Text: "abs"}}) {
+		t.Fatalf("Links = %+v", got)
+	}
+}
@@ -0,0 +1,162 @@
+package render
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+type highlightPosition struct {
+	selector []selectorStep
+	offset   int
+}
+
+type selectorStep struct {
+	name  string
+	index int
+}
+
+// SortHighlightsByPosition orders highlights in reading order using their
+// location selector and offset. Selectors naming different elements cannot
+// be ordered against each other (h2[1] may follow p[3]), so highlights are
+// grouped by the element names along their selector. Groups keep the slots
+// creation order gives them, and each group is sorted by position within its
+// own slots. Highlights without a usable location keep their creation order
+// and go last.
+func SortHighlightsByPosition(highlights []readeck.Highlight) []readeck.Highlight {
+	out := append([]readeck.Highlight(nil), highlights...)
+	positions := make(map[string]highlightPosition, len(out))
+	for _, h := range out {
+		if pos, ok := parseHighlightPosition(h.Location); ok {
+			positions[h.ID] = pos
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		_, leftOK := positions[out[i].ID]
+		_, rightOK := positions[out[j].ID]
+		if leftOK != rightOK {
+			return leftOK
+		}
+		return creationLess(out[i], out[j])
+	})
+
+	slots := map[string][]int{}
+	var shapes []string
+	for i, h := range out {
+		pos, ok := positions[h.ID]
+		if !ok {
+			continue
+		}
+		shape := pos.shape()
+		if _, seen := slots[shape]; !seen {
+			shapes = append(shapes, shape)
+		}
+		slots[shape] = append(slots[shape], i)
+	}
+	for _, shape := range shapes {
+		idx := slots[shape]
+		group := make([]readeck.Highlight, len(idx))
+		for k, i := range idx {
+			group[k] = out[i]
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			if cmp := comparePositions(positions[group[i].ID], positions[group[j].ID]); cmp != 0 {
+				return cmp < 0
+			}
+			return creationLess(group[i], group[j])
+		})
+		for k, i := range idx {
+			out[i] = group[k]
+		}
+	}
+	return out
+}
+
+func creationLess(a, b readeck.Highlight) bool {
+	if a.CreatedAt != b.CreatedAt {
+		return a.CreatedAt < b.CreatedAt
+	}
+	return a.ID < b.ID
+}
+
+func parseHighlightPosition(raw json.RawMessage) (highlightPosition, bool) {
+	if len(raw) == 0 {
+		return highlightPosition{}, false
+	}
+	var loc map[string]any
+	if err := json.Unmarshal(raw, &loc); err != nil {
+		return highlightPosition{}, false
+	}
+	selector, _ := firstValue(loc, "start_selector", "startSelector", "selector").(string)
+	offset, hasOffset := toInt(firstValue(loc, "start_offset", "startOffset", "offset", "start"))
+	if selector == "" && !hasOffset {
+		return highlightPosition{}, false
+	}
+	return highlightPosition{selector: parseSelector(selector), offset: offset}, true
+}
+
+func parseSelector(selector string) []selectorStep {
+	parts := strings.Split(strings.Trim(selector, "/"), "/")
+	steps := make([]selectorStep, 0, len(parts))
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		step := selectorStep{name: part, index: 1}
+		if open := strings.Index(part, "["); open > 0 && strings.HasSuffix(part, "]") {
+			if n, err := strconv.Atoi(part[open+1 : len(part)-1]); err == nil {
+				step = selectorStep{name: part[:open], index: n}
+			}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// shape is the selector with indices dropped; positions of the same shape
+// can always be ordered against each other.
+func (p highlightPosition) shape() string {
+	names := make([]string, len(p.selector))
+	for i, step := range p.selector {
+		names[i] = step.name
+	}
+	return strings.Join(names, "/")
+}
+
+// comparePositions orders two positions of the same shape by the element
+// indices along their selectors, then by offset.
+func comparePositions(a, b highlightPosition) int {
+	for i := 0; i < len(a.selector) && i < len(b.selector); i++ {
+		if a.selector[i].index != b.selector[i].index {
+			return a.selector[i].index - b.selector[i].index
+		}
+	}
+	return a.offset - b.offset
+}
+
+func firstValue(obj map[string]any, keys ...string) any {
+	for _, key := range keys {
+		if v, ok := obj[key]; ok && v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func toInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err == nil
+	case string:
+		i, err := strconv.Atoi(n)
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
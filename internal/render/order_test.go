@@ -0,0 +1,81 @@
+package render
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+func highlightAt(id, created, location string) readeck.Highlight {
+	h := readeck.Highlight{ID: id, CreatedAt: created}
+	if location != "" {
+		h.Location = json.RawMessage(location)
+	}
+	return h
+}
+
+func highlightIDs(highlights []readeck.Highlight) []string {
+	out := make([]string, 0, len(highlights))
+	for _, h := range highlights {
+		out = append(out, h.ID)
+	}
+	return out
+}
+
+func TestSortHighlightsByPosition(t *testing.T) {
+	highlights := []readeck.Highlight{
+		highlightAt("none", "2024-01-01", ""),
+		highlightAt("p3", "2024-01-02", `{"start_selector":"/article/p[3]","start_offset":5}`),
+		highlightAt("p1-late", "2024-01-03", `{"start_selector":"/article/p[1]","start_offset":40}`),
+		highlightAt("p1-early", "2024-01-04", `{"start_selector":"/article/p[1]","start_offset":2}`),
+		highlightAt("offset-only", "2023-12-31", `{"start_offset":"7"}`),
+	}
+	got := highlightIDs(SortHighlightsByPosition(highlights))
+	want := []string{"offset-only", "p1-early", "p1-late", "p3", "none"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+}
+
+func TestSortHighlightsMixedElementsFallBackToCreation(t *testing.T) {
+	highlights := []readeck.Highlight{
+		highlightAt("p-newer", "2024-01-02", `{"start_selector":"/article/p[1]","start_offset":0}`),
+		highlightAt("h2-older", "2024-01-01", `{"start_selector":"/article/h2[1]","start_offset":0}`),
+	}
+	got := highlightIDs(SortHighlightsByPosition(highlights))
+	if want := []string{"h2-older", "p-newer"}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v", got, want)
+	}
+
+	highlights[0].CreatedAt, highlights[1].CreatedAt = "2024-01-01", "2024-01-02"
+	got = highlightIDs(SortHighlightsByPosition(highlights))
+	if want := []string{"p-newer", "h2-older"}; !slices.Equal(got, want) {
+		t.Fatalf("order = %v, want %v (alphabetical tag order must not win)", got, want)
+	}
+}
+
+func TestSortHighlightsMixedTagsIsIndependentOfInputOrder(t *testing.T) {
+	a := highlightAt("A", "2024-01-01", `{"start_selector":"/article/p[2]","start_offset":0}`)
+	b := highlightAt("B", "2024-01-02", `{"start_selector":"/article/h2[1]","start_offset":0}`)
+	c := highlightAt("C", "2024-01-03", `{"start_selector":"/article/p[1]","start_offset":0}`)
+	d := highlightAt("D", "2024-01-04", `{"start_selector":"/article/h2[1]","start_offset":9}`)
+	want := []string{"C", "B", "A", "D"}
+	for _, order := range [][]readeck.Highlight{
+		{a, b, c, d}, {d, c, b, a}, {b, a, d, c}, {c, a, d, b}, {d, b, a, c},
+	} {
+		if got := highlightIDs(SortHighlightsByPosition(order)); !slices.Equal(got, want) {
+			t.Fatalf("input %v: order = %v, want %v", highlightIDs(order), got, want)
+		}
+	}
+}
+
+func TestToIntAcceptsJSONNumber(t *testing.T) {
+	if n, ok := toInt(json.Number("42")); !ok || n != 42 {
+		t.Fatalf("toInt(json.Number) = %d, %v", n, ok)
+	}
+	if _, ok := toInt(json.Number("4.5")); ok {
+		t.Fatal("fractional json.Number accepted")
+	}
+}
@@ -4,65 +4,184 @@ import (
 	"fmt"
 	"html"
 	"regexp"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/akrisanov/readeck-mcp/internal/readeck"
 )
 
 var tagRe = regexp.MustCompile(`(?s)<[^>]*>`)
 var wsRe = regexp.MustCompile(`\s+`)
+var blockTagRe = regexp.MustCompile(`(?is)</?(?:p|div|h[1-6]|li|ul|ol|dl|dt|dd|blockquote|pre|section|article|header|footer|aside|table|tr|figure|figcaption|hr)\b[^>]*>`)
+var brTagRe = regexp.MustCompile(`(?i)<br\b[^>]*>`)
 
-func BookmarkContentMarkdown(bookmark readeck.Bookmark, includeHighlights bool) string {
-	var b strings.Builder
-	b.WriteString("---\n")
-	writeYAML(&b, "title", bookmark.Title)
-	writeYAML(&b, "url", bookmark.URL)
-	writeYAML(&b, "author", bookmark.Author)
-	writeYAML(&b, "site_name", bookmark.SiteName)
-	writeYAML(&b, "published_at", bookmark.PublishedAt)
-	writeYAML(&b, "created_at", bookmark.CreatedAt)
-	writeYAML(&b, "updated_at", bookmark.UpdatedAt)
-	writeYAML(&b, "readeck_id", bookmark.ID)
-	writeYAMLBool(&b, "archived", bookmark.IsArchived)
+// TextOptions controls how content is flattened to plain text. By default
+// whitespace is collapsed within each line and the source line breaks are
+// kept as they are; Paragraphs turns HTML block elements into paragraph
+// breaks and keeps paragraphs apart by exactly one blank line.
+type TextOptions struct {
+	Paragraphs bool
+}
 
-	labels := make([]string, 0, len(bookmark.Labels))
-	for _, l := range bookmark.Labels {
-		if strings.TrimSpace(l.Name) != "" {
-			labels = append(labels, l.Name)
-		}
+type MarkdownOptions struct {
+	IncludeHighlights bool
+	Hashtags          bool
+	OmitFrontmatter   bool
+	FrontmatterFields []string
+	Images            bool
+	Paragraphs        bool
+	// MaxChars truncates the rendered body, not the frontmatter; 0 keeps it
+	// whole.
+	MaxChars int
+}
+
+func BookmarkContentMarkdown(bookmark readeck.Bookmark, opts MarkdownOptions) string {
+	body := Truncate(markdownBody(bookmark, opts), opts.MaxChars)
+	if opts.OmitFrontmatter {
+		return body
 	}
-	b.WriteString("labels:\n")
-	if len(labels) == 0 {
-		b.WriteString("  []\n")
-	} else {
-		for _, label := range labels {
-			b.WriteString("  - ")
-			b.WriteString(quoteYAML(label))
-			b.WriteByte('\n')
+	return Frontmatter(bookmark, opts.FrontmatterFields) + "\n" + body
+}
+
+var defaultFrontmatterFields = []string{
+	"title", "url", "author", "site_name", "published_at", "created_at", "updated_at", "readeck_id",
+	"archived", "word_count", "reading_minutes", "read_progress", "highlight_count", "labels",
+}
+
+// Frontmatter renders the YAML metadata block that opens content.md with the
+// given fields in order, or the default set when fields is empty. Unknown
+// fields are skipped.
+func Frontmatter(bookmark readeck.Bookmark, fields []string) string {
+	if len(fields) == 0 {
+		fields = defaultFrontmatterFields
+	}
+	var b strings.Builder
+	b.WriteString("---\n")
+	for _, field := range fields {
+		switch field {
+		case "title":
+			writeYAML(&b, field, bookmark.Title)
+		case "url":
+			writeYAML(&b, field, bookmark.URL)
+		case "author":
+			writeYAML(&b, field, bookmark.Author)
+		case "site_name":
+			writeYAML(&b, field, bookmark.SiteName)
+		case "published_at":
+			writeYAML(&b, field, bookmark.PublishedAt)
+		case "created_at":
+			writeYAML(&b, field, bookmark.CreatedAt)
+		case "updated_at":
+			writeYAML(&b, field, bookmark.UpdatedAt)
+		case "readeck_id":
+			writeYAML(&b, field, bookmark.ID)
+		case "archived":
+			writeYAMLBool(&b, field, bookmark.IsArchived)
+		case "word_count":
+			writeYAMLInt(&b, field, bookmark.WordCount)
+		case "reading_minutes":
+			writeYAMLInt(&b, field, bookmark.ReadingMinutes)
+		case "read_progress":
+			writeYAMLInt(&b, field, bookmark.ReadProgress)
+		case "highlight_count":
+			writeYAMLInt(&b, field, len(bookmark.Highlights))
+		case "labels":
+			writeYAMLList(&b, field, labelNames(bookmark))
 		}
 	}
-	b.WriteString("---\n\n")
+	b.WriteString("---\n")
+	return b.String()
+}
 
-	text := BookmarkContentText(bookmark)
+func markdownBody(bookmark readeck.Bookmark, opts MarkdownOptions) string {
+	var b strings.Builder
+	text := ContentText(bookmark, TextOptions{Paragraphs: opts.Paragraphs})
 	if text == "" {
 		text = "(content unavailable)"
+		if reason := strings.TrimSpace(bookmark.ContentUnavailableReason); reason != "" {
+			text = "(content unavailable: " + reason + ")"
+		}
 	}
 	b.WriteString(text)
 	b.WriteByte('\n')
 
-	if includeHighlights && len(bookmark.Highlights) > 0 {
+	if opts.Images {
+		if images := Images(bookmark); len(images) > 0 {
+			b.WriteString("\n## Images\n\n")
+			b.WriteString(ImagesMarkdown(images))
+		}
+	}
+
+	if opts.IncludeHighlights && len(bookmark.Highlights) > 0 {
 		b.WriteString("\n## Highlights\n\n")
 		b.WriteString(HighlightsMarkdown(bookmark.Highlights))
 	}
 
+	if opts.Hashtags {
+		if tags := hashtags(labelNames(bookmark)); tags != "" {
+			b.WriteByte('\n')
+			b.WriteString(tags)
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.String()
+}
+
+func labelNames(bookmark readeck.Bookmark) []string {
+	labels := make([]string, 0, len(bookmark.Labels))
+	for _, l := range bookmark.Labels {
+		if strings.TrimSpace(l.Name) != "" {
+			labels = append(labels, l.Name)
+		}
+	}
+	return labels
+}
+
+func hashtags(labels []string) string {
+	tags := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if slug := hashtagSlug(label); slug != "" {
+			tags = append(tags, "#"+slug)
+		}
+	}
+	return strings.Join(tags, " ")
+}
+
+func hashtagSlug(label string) string {
+	var b strings.Builder
+	pendingSep := false
+	for _, r := range strings.TrimSpace(label) {
+		switch {
+		case unicode.IsSpace(r):
+			pendingSep = true
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '/':
+			if pendingSep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			pendingSep = false
+			b.WriteRune(r)
+		}
+	}
 	return b.String()
 }
 
 func BookmarkContentText(bookmark readeck.Bookmark) string {
+	return ContentText(bookmark, TextOptions{})
+}
+
+func ContentText(bookmark readeck.Bookmark, opts TextOptions) string {
 	if strings.TrimSpace(bookmark.ContentText) != "" {
+		if opts.Paragraphs {
+			return normalizeParagraphs(bookmark.ContentText)
+		}
 		return normalizeWhitespace(bookmark.ContentText)
 	}
 	if strings.TrimSpace(bookmark.ContentHTML) != "" {
+		if opts.Paragraphs {
+			return htmlParagraphs(bookmark.ContentHTML)
+		}
 		return htmlToText(bookmark.ContentHTML)
 	}
 	return ""
@@ -98,6 +217,103 @@ func HighlightsMarkdown(highlights []readeck.Highlight) string {
 	return b.String()
 }
 
+// HighlightNotesMarkdown aggregates only the notes, each followed by a short
+// one-line excerpt of the quote it belongs to.
+func HighlightNotesMarkdown(highlights []readeck.Highlight) string {
+	var b strings.Builder
+	for _, h := range highlights {
+		note := strings.TrimSpace(h.Note)
+		if note == "" {
+			continue
+		}
+		b.WriteString("- ")
+		b.WriteString(note)
+		if quote := Truncate(strings.Join(strings.Fields(h.Text), " "), digestQuoteChars); quote != "" {
+			b.WriteString(" (on \"")
+			b.WriteString(quote)
+			b.WriteString("\")")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func HighlightsDigest(bookmark readeck.Bookmark) string {
+	var b strings.Builder
+	title := strings.TrimSpace(bookmark.Title)
+	if title == "" {
+		title = bookmark.ID
+	}
+	b.WriteString("# Highlight digest: ")
+	b.WriteString(title)
+	b.WriteString("\n\n## Highlights\n\n")
+	if quotes := HighlightsMarkdown(bookmark.Highlights); quotes != "" {
+		b.WriteString(quotes)
+	} else {
+		b.WriteString("_No highlights._\n")
+	}
+	b.WriteString("\n## Notes\n\n")
+	if notes := HighlightNotesMarkdown(bookmark.Highlights); notes != "" {
+		b.WriteString(notes)
+	} else {
+		b.WriteString("_No notes._\n")
+	}
+	return b.String()
+}
+
+// Stats counts characters (runes) and whitespace-separated words in text.
+func Stats(text string) (chars, words int) {
+	return len([]rune(text)), len(strings.Fields(text))
+}
+
+func Summary(text string, maxSentences int) string {
+	text = wsRe.ReplaceAllString(strings.TrimSpace(text), " ")
+	if text == "" || maxSentences <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	count := 0
+	for i, r := range runes {
+		if r != '.' && r != '!' && r != '?' {
+			continue
+		}
+		if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			continue
+		}
+		count++
+		if count == maxSentences {
+			return string(runes[:i+1])
+		}
+	}
+	return text
+}
+
+const (
+	truncatedMarker  = "…[truncated]"
+	digestQuoteChars = 80
+)
+
+func Truncate(text string, maxChars int) string {
+	runes := []rune(text)
+	if maxChars <= 0 || len(runes) <= maxChars {
+		return text
+	}
+	cut := runes[:maxChars]
+	if idx := lastSpace(cut); idx > maxChars/2 {
+		cut = cut[:idx]
+	}
+	return strings.TrimRightFunc(string(cut), unicode.IsSpace) + truncatedMarker
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
 func writeYAML(b *strings.Builder, key, value string) {
 	b.WriteString(key)
 	b.WriteString(": ")
@@ -115,10 +331,60 @@ func writeYAMLBool(b *strings.Builder, key string, value bool) {
 	b.WriteString("false\n")
 }
 
+func writeYAMLList(b *strings.Builder, key string, values []string) {
+	b.WriteString(key)
+	b.WriteString(":\n")
+	if len(values) == 0 {
+		b.WriteString("  []\n")
+		return
+	}
+	for _, v := range values {
+		b.WriteString("  - ")
+		b.WriteString(quoteYAML(v))
+		b.WriteByte('\n')
+	}
+}
+
+func writeYAMLInt(b *strings.Builder, key string, value int) {
+	if value == 0 {
+		return
+	}
+	b.WriteString(key)
+	b.WriteString(": ")
+	b.WriteString(strconv.Itoa(value))
+	b.WriteByte('\n')
+}
+
 func quoteYAML(v string) string {
-	v = strings.ReplaceAll(v, "\n", " ")
+	v = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(v)
 	v = strings.TrimSpace(v)
-	return fmt.Sprintf("%q", v)
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range v {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\u0085':
+			b.WriteString(`\N`)
+		case '\u2028':
+			b.WriteString(`\L`)
+		case '\u2029':
+			b.WriteString(`\P`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\x%02x`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
 }
 
 func htmlToText(input string) string {
@@ -140,3 +406,41 @@ func normalizeWhitespace(s string) string {
 	}
 	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
+
+// htmlParagraphs strips markup like htmlToText, but block elements become
+// paragraph breaks and <br> a line break, so the structure survives
+// normalizeParagraphs.
+func htmlParagraphs(input string) string {
+	text := brTagRe.ReplaceAllString(input, "\n")
+	text = blockTagRe.ReplaceAllString(text, "\n\n")
+	text = tagRe.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	return normalizeParagraphs(text)
+}
+
+// normalizeParagraphs collapses whitespace within each line like
+// normalizeWhitespace, and turns each run of blank lines into a single one.
+func normalizeParagraphs(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = wsRe.ReplaceAllString(strings.TrimSpace(line), " ")
+		if line == "" {
+			blank = true
+			continue
+		}
+		if blank && len(out) > 0 {
+			out = append(out, "")
+		}
+		blank = false
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
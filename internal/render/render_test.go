@@ -0,0 +1,248 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/akrisanov/readeck-mcp/internal/readeck"
+)
+
+func TestTruncateBoundaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     string
+	}{
+		{"no limit", "hello world", 0, "hello world"},
+		{"exact fit", "hello world", 11, "hello world"},
+		{"word boundary", "hello wonderful world", 17, "hello wonderful…[truncated]"},
+		{"cuts a long word", "supercalifragilistic", 5, "super…[truncated]"},
+		{"early space ignored", "a bcdefghijkl", 8, "a bcdefg…[truncated]"},
+		{"counts runes", "héllo wörld", 9, "héllo…[truncated]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Truncate(tt.text, tt.maxChars); got != tt.want {
+				t.Fatalf("Truncate(%q, %d) = %q, want %q", tt.text, tt.maxChars, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentMarkdownMaxCharsTruncatesBodyOnly(t *testing.T) {
+	bookmark := readeck.Bookmark{
+		ID:          "b1",
+		Title:       "A title long enough to exceed the budget",
+		URL:         "https://example.com/a",
+		ContentHTML: `<p>First paragraph here.</p><img src="https://example.com/i.png" alt="pic">`,
+		Labels:      []readeck.Label{{Name: "go"}},
+	}
+
+	full := BookmarkContentMarkdown(bookmark, MarkdownOptions{Images: true, Hashtags: true})
+	if !strings.Contains(full, "## Images") || !strings.Contains(full, "#go") {
+		t.Fatalf("full render missing images or hashtags:\n%s", full)
+	}
+
+	got := BookmarkContentMarkdown(bookmark, MarkdownOptions{Images: true, Hashtags: true, MaxChars: 30})
+	frontmatter := Frontmatter(bookmark, nil)
+	if !strings.HasPrefix(got, frontmatter+"\n") {
+		t.Fatalf("frontmatter not kept intact:\n%s", got)
+	}
+	body := strings.TrimPrefix(got, frontmatter+"\n")
+	if !strings.HasSuffix(body, "…[truncated]") {
+		t.Fatalf("body not truncated: %q", body)
+	}
+	if n := len([]rune(strings.TrimSuffix(body, "…[truncated]"))); n > 30 {
+		t.Fatalf("body keeps %d characters, want at most 30", n)
+	}
+
+	images := BookmarkContentMarkdown(bookmark, MarkdownOptions{Images: true, OmitFrontmatter: true, MaxChars: 200})
+	if !strings.Contains(images, "https://example.com/i.png") {
+		t.Fatalf("images dropped under a generous budget:\n%s", images)
+	}
+}
+
+func TestFrontmatterReadingStatsOnlyWhenNonZero(t *testing.T) {
+	empty := Frontmatter(readeck.Bookmark{ID: "b1"}, nil)
+	for _, key := range []string{"word_count:", "reading_minutes:", "read_progress:", "highlight_count:"} {
+		if strings.Contains(empty, key) {
+			t.Fatalf("zero %s emitted:\n%s", key, empty)
+		}
+	}
+
+	full := Frontmatter(readeck.Bookmark{
+		ID:             "b1",
+		WordCount:      1200,
+		ReadingMinutes: 6,
+		ReadProgress:   40,
+		Highlights:     []readeck.Highlight{{ID: "h1"}, {ID: "h2"}},
+	}, nil)
+	for _, line := range []string{"word_count: 1200\n", "reading_minutes: 6\n", "read_progress: 40\n", "highlight_count: 2\n"} {
+		if !strings.Contains(full, line) {
+			t.Fatalf("missing %q in:\n%s", line, full)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		name, text string
+		sentences  int
+		want       string
+	}{
+		{"first sentences", "One. Two! Three? Four.", 3, "One. Two! Three?"},
+		{"fewer sentences than asked", "Only one sentence here", 3, "Only one sentence here"},
+		{"decimal is not a sentence end", "Pi is 3.14 or so. Next.", 1, "Pi is 3.14 or so."},
+		{"collapses whitespace", "  Line\n\tbreak.  Next. ", 1, "Line break."},
+		{"empty", "   ", 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summary(tt.text, tt.sentences); got != tt.want {
+				t.Fatalf("Summary(%q, %d) = %q, want %q", tt.text, tt.sentences, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashtagsFromLabels(t *testing.T) {
+	tests := []struct {
+		labels []string
+		want   string
+	}{
+		{[]string{"go"}, "#go"},
+		{[]string{"machine learning", "  to   read  "}, "#machine_learning #to_read"},
+		{[]string{"c++", "C#", "node.js"}, "#c #C #nodejs"},
+		{[]string{"ai/ml", "long-form", "snake_case"}, "#ai/ml #long-form #snake_case"},
+		{[]string{"日本語 ラベル"}, "#日本語_ラベル"},
+		{[]string{"!!!", ""}, ""},
+	}
+	for _, tt := range tests {
+		if got := hashtags(tt.labels); got != tt.want {
+			t.Errorf("hashtags(%q) = %q, want %q", tt.labels, got, tt.want)
+		}
+	}
+}
+
+func TestContentMarkdownHashtagsAreOptIn(t *testing.T) {
+	bookmark := readeck.Bookmark{ContentText: "Body.", Labels: []readeck.Label{{Name: "deep work"}}}
+	if got := BookmarkContentMarkdown(bookmark, MarkdownOptions{OmitFrontmatter: true}); strings.Contains(got, "#") {
+		t.Fatalf("hashtags without the option: %q", got)
+	}
+	if got := BookmarkContentMarkdown(bookmark, MarkdownOptions{OmitFrontmatter: true, Hashtags: true}); got != "Body.\n\n#deep_work\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestFrontmatterEscapesValues(t *testing.T) {
+	tests := []struct{ title, want string }{
+		{`He said "hi"`, `title: "He said \"hi\""`},
+		{"Go: a tour", `title: "Go: a tour"`},
+		{"- not a list # nor a comment", `title: "- not a list # nor a comment"`},
+		{"Launch 🚀 day", `title: "Launch 🚀 day"`},
+		{`back\slash`, `title: "back\\slash"`},
+		{"multi\nline\r\ntitle", `title: "multi line title"`},
+		{"tab\there\x07bell", `title: "tab\there\x07bell"`},
+		{"true", `title: "true"`},
+	}
+	for _, tt := range tests {
+		fm := Frontmatter(readeck.Bookmark{Title: tt.title, Labels: []readeck.Label{{Name: `a "quoted": label`}}}, []string{"title", "labels"})
+		want := "---\n" + tt.want + "\nlabels:\n  - \"a \\\"quoted\\\": label\"\n---\n"
+		if fm != want {
+			t.Errorf("title %q:\n%s\nwant:\n%s", tt.title, fm, want)
+		}
+	}
+}
+
+func TestFrontmatterReadingMetadataIsConditional(t *testing.T) {
+	bare := Frontmatter(readeck.Bookmark{Title: "T"}, nil)
+	for _, key := range []string{"word_count", "reading_minutes", "read_progress", "highlight_count"} {
+		if strings.Contains(bare, key+":") {
+			t.Errorf("%s emitted for a zero value:\n%s", key, bare)
+		}
+	}
+	if !strings.Contains(bare, "archived: false\n") {
+		t.Errorf("archived missing:\n%s", bare)
+	}
+
+	full := Frontmatter(readeck.Bookmark{
+		Title: "T", IsArchived: true, WordCount: 1200, ReadingMinutes: 6, ReadProgress: 40,
+		Highlights: []readeck.Highlight{{ID: "h1"}, {ID: "h2"}},
+	}, nil)
+	for _, line := range []string{"archived: true\n", "word_count: 1200\n", "reading_minutes: 6\n", "read_progress: 40\n", "highlight_count: 2\n"} {
+		if !strings.Contains(full, line) {
+			t.Errorf("missing %q:\n%s", line, full)
+		}
+	}
+}
+
+func TestHighlightsDigestWithMixedNotes(t *testing.T) {
+	bookmark := readeck.Bookmark{ID: "b1", Title: "Article", Highlights: []readeck.Highlight{
+		{ID: "h1", Text: "First quote", Note: "Why it matters"},
+		{ID: "h2", Text: "Second quote"},
+		{ID: "h3", Text: "Third\n  quote", Note: "  Follow up  "},
+	}}
+	want := "# Highlight digest: Article\n\n## Highlights\n\n" +
+		"> First quote\n- Note: Why it matters\n- Highlight ID: `h1`\n\n" +
+		"> Second quote\n- Highlight ID: `h2`\n\n" +
+		"> Third\n  quote\n- Note: Follow up\n- Highlight ID: `h3`\n" +
+		"\n## Notes\n\n" +
+		"- Why it matters (on \"First quote\")\n" +
+		"- Follow up (on \"Third quote\")\n"
+	if got := HighlightsDigest(bookmark); got != want {
+		t.Fatalf("digest:\n%s\nwant:\n%s", got, want)
+	}
+
+	bookmark.Highlights = bookmark.Highlights[1:2]
+	if got := HighlightsDigest(bookmark); !strings.HasSuffix(got, "## Notes\n\n_No notes._\n") {
+		t.Fatalf("digest without notes:\n%s", got)
+	}
+	bookmark.Highlights = nil
+	if got := HighlightsDigest(bookmark); !strings.Contains(got, "_No highlights._") {
+		t.Fatalf("digest without highlights:\n%s", got)
+	}
+}
+
+func TestContentMarkdownNamesWhyContentIsUnavailable(t *testing.T) {
+	bookmark := readeck.Bookmark{ContentUnavailableReason: "binary content: application/pdf"}
+	if got := BookmarkContentMarkdown(bookmark, MarkdownOptions{OmitFrontmatter: true}); got != "(content unavailable: binary content: application/pdf)\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestFrontmatterCustomFieldSubset(t *testing.T) {
+	bm := readeck.Bookmark{ID: "b1", Title: "T", URL: "https://example.com/t", Author: "Jane", IsArchived: true}
+	got := Frontmatter(bm, []string{"readeck_id", "archived", "title"})
+	want := "---\nreadeck_id: \"b1\"\narchived: true\ntitle: \"T\"\n---\n"
+	if got != want {
+		t.Fatalf("frontmatter:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestContentTextKeepsSourceLinesByDefault(t *testing.T) {
+	htmlBM := readeck.Bookmark{ContentHTML: "<h1>Title</h1><p>First  paragraph.</p>\n\n\n<p>Second<br>line two.</p><ul><li>one</li><li>two</li></ul>"}
+	if got, want := ContentText(htmlBM, TextOptions{}), "Title First paragraph.\n\n\nSecond line two. one two"; got != want {
+		t.Fatalf("HTML text = %q, want %q", got, want)
+	}
+	if got := BookmarkContentText(htmlBM); got != "Title First paragraph.\n\n\nSecond line two. one two" {
+		t.Fatalf("BookmarkContentText = %q", got)
+	}
+
+	textBM := readeck.Bookmark{ContentText: "  One\tline \n\n\n\nTwo\r\nThree  "}
+	if got, want := ContentText(textBM, TextOptions{}), "One line\n\n\n\nTwo\nThree"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+}
+
+func TestContentTextPreservesParagraphs(t *testing.T) {
+	htmlBM := readeck.Bookmark{ContentHTML: "<h1>Title</h1><p>First  paragraph.</p>\n\n\n<p>Second<br>line two.</p><ul><li>one</li><li>two</li></ul>"}
+	if got, want := ContentText(htmlBM, TextOptions{Paragraphs: true}), "Title\n\nFirst paragraph.\n\nSecond\nline two.\n\none\n\ntwo"; got != want {
+		t.Fatalf("HTML text = %q, want %q", got, want)
+	}
+
+	textBM := readeck.Bookmark{ContentText: "  One\tline \n\n\n\nTwo\r\nThree  "}
+	if got, want := ContentText(textBM, TextOptions{Paragraphs: true}), "One line\n\nTwo\nThree"; got != want {
+		t.Fatalf("text = %q, want %q", got, want)
+	}
+}